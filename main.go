@@ -2,49 +2,167 @@ package main
 
 import (
 	"context"
-	"flag"
+	"crypto/ed25519"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/cli"
 	"github.com/SunSince90/go-naivecoin/pkg/controllers"
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/finality"
+	"github.com/SunSince90/go-naivecoin/pkg/lightclient"
+	"github.com/SunSince90/go-naivecoin/pkg/metrics"
+	"github.com/SunSince90/go-naivecoin/pkg/mining"
 	"github.com/SunSince90/go-naivecoin/pkg/pb"
 	"github.com/SunSince90/go-naivecoin/pkg/peers"
 	"github.com/SunSince90/go-naivecoin/pkg/servers"
+	"github.com/SunSince90/go-naivecoin/pkg/wallet"
 	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
 	"gopkg.in/yaml.v3"
 )
 
 const (
 	defaultConsensusPath string = "/settings/consensus-settings.yaml"
+
+	modeFull  string = "full"
+	modeLight string = "light"
 )
 
-type ConsensusSettings struct {
-	ProofOfWork *block.ProofOfWorkSettings `yaml:"proofOfWork"`
+func main() {
+	os.Exit(execute())
 }
 
-func main() {
-	os.Exit(run())
+// execute runs the command provided on the command line and returns the
+// exit code.
+func execute() int {
+	exitCode := 0
+
+	root := cli.NewRootCommand()
+	root.AddCommand(newNodeCommand(&exitCode), newOperatorCommand(&exitCode))
+	if err := root.ExecuteContext(context.Background()); err != nil {
+		return 1
+	}
+
+	return exitCode
+}
+
+// nodeOptions contains the flags of the node command.
+type nodeOptions struct {
+	consensusPath string
+	network       string
+	genesisPath   string
+	mode          string
+	watch         string
+	watchAddrs    string
+	authPath      string
+	bootstrapPath string
+	mine          bool
+	designated    bool
+	validatorPath string
+	discoveryMode string
+	discovery     controllers.DiscoverySettings
+	readiness     servers.ReadinessSettings
+	writeSettings servers.WriteSettings
 }
 
-func run() int {
-	var consensusPath string
-	flag.StringVar(&consensusPath, "consensus-settings", defaultConsensusPath, "the path to where the consensus settings are stored.")
-	flag.Parse()
+func newNodeCommand(exitCode *int) *cobra.Command {
+	opts := &nodeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "node",
+		Short: "Run a node",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			*exitCode = run(opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.consensusPath, "consensus-settings", defaultConsensusPath, "the path to where the consensus settings are stored.")
+	flags.StringVar(&opts.network, "network", block.NetworkMainnet, "the network to join: mainnet, testnet or regtest. regtest mines blocks instantly at the minimum difficulty and ignores the consensus settings.")
+	flags.StringVar(&opts.genesisPath, "genesis", "", "the path to where the genesis settings are stored. If not empty, they replace the genesis block of the network.")
+	flags.StringVar(&opts.mode, "mode", modeFull, "the mode of the node: full stores and validates the whole chain, light only syncs headers.")
+	flags.StringVar(&opts.watch, "watch", "", "comma separated hex hashes of entries to watch in light mode.")
+	flags.StringVar(&opts.watchAddrs, "watch-addresses", "", "comma separated addresses whose balance is watched in light mode.")
+	flags.IntVar(&opts.readiness.MinPeers, "ready-min-peers", 0, "the minimum number of peers needed to be ready.")
+	flags.Int64Var(&opts.readiness.MaxBlocksBehind, "ready-max-blocks-behind", 2, "how many blocks the node can be behind the best peer and still be ready.")
+	flags.DurationVar(&opts.readiness.InitialSyncGracePeriod, "initial-sync-grace-period", 30*time.Second, "how long to wait for a peer to sync from before being ready anyways.")
+	flags.StringVar(&opts.authPath, "auth-settings", "", "the path to where the credentials accepted on write endpoints are stored. If empty, authentication is disabled.")
+	flags.IntVar(&opts.writeSettings.RateLimit, "rate-limit", 10, "the maximum number of writes a client can make in the rate limit window. 0 disables rate limiting.")
+	flags.DurationVar(&opts.writeSettings.RateLimitWindow, "rate-limit-window", time.Minute, "the window in which writes are counted for rate limiting.")
+	flags.StringVar(&opts.bootstrapPath, "bootstrap-file", "", "the path to a chain exported with \"naivecoin chain export\" to load at startup, before syncing with peers.")
+	flags.BoolVar(&opts.mine, "mine", true, "whether to mine the entries submitted to the node. If false, they are refused.")
+	flags.BoolVar(&opts.designated, "designated-miner", false, "elect a single node among the ones that mine to mine all entries: the other ones forward the entries they receive to it.")
+	flags.StringVar(&opts.validatorPath, "validator-wallet", "", "the path to the wallet used to seal blocks with proof of authority and to vote on checkpoints with finality. Its address must be one of the validators, and the node does not seal blocks or vote without it.")
+	flags.IntVar(&opts.writeSettings.MaxPayloadSize, "max-payload-size", 4096, "the maximum size in bytes of the body of write requests to the public server, i.e. POST /blocks and /rpc, and of RPC WebSocket messages. It must be positive.")
+	flags.StringVar(&opts.discoveryMode, "discovery", string(controllers.DiscoveryPods), "how peers are discovered: pods uses the running pods matching the peer selector, endpointslices uses the ready endpoints of the peer service.")
+	flags.StringVar(&opts.discovery.Selector, "peer-selector", controllers.DefaultPeerSelector, "the label selector of the pods of the peers.")
+	flags.StringSliceVar(&opts.discovery.Namespaces, "peer-namespaces", nil, "the namespaces where peers are discovered. If empty, only the namespace of the node is.")
+	flags.StringVar(&opts.discovery.Service, "peer-service", "", "the name of the headless service whose endpoints are peers, with endpointslices discovery.")
+	flags.StringVar(&opts.discovery.PortName, "peer-port-name", controllers.DefaultPortName, "the name of the port where peers serve peer communications.")
+
+	return cmd
+}
+
+func run(opts *nodeOptions) int {
+	readiness, writeSettings := opts.readiness, opts.writeSettings
+	writeSettings.DisableMining = !opts.mine
+	discovery := opts.discovery
+	discovery.Mode = controllers.DiscoveryMode(opts.discoveryMode)
 
 	log := zerolog.New(os.Stderr).With().Timestamp().Logger()
 	log.Info().Msg("starting...")
 
-	consensusSettings, err := getConsensusSettings(consensusPath)
+	network, err := block.LoadNetwork(opts.network, opts.genesisPath)
 	if err != nil {
-		log.Err(err).Msg("could not load consensus settings correctly")
-		return 4
+		log.Err(err).Msg("could not load network correctly")
+		return 9
+	}
+	log = log.With().Str("network", network.Name).Logger()
+
+	consensusSettings := network.Consensus
+	if consensusSettings == nil {
+		consensusSettings, err = block.LoadConsensusSettings(opts.consensusPath)
+		if err != nil {
+			log.Err(err).Msg("could not load consensus settings correctly")
+			return 4
+		}
+	}
+
+	if opts.mode == modeLight {
+		return runLight(log, network, consensusSettings, readiness, discovery, opts.watch, opts.watchAddrs)
+	}
+
+	if opts.mode != modeFull {
+		log.Error().Str("mode", opts.mode).Msg("unknown mode")
+		return 5
+	}
+
+	if writeSettings.MaxPayloadSize <= 0 {
+		log.Error().Int("max-payload-size", writeSettings.MaxPayloadSize).Msg("max payload size must be positive")
+		return 5
+	}
+
+	if opts.authPath != "" {
+		authSettings, err := getAuthSettings(opts.authPath)
+		if err != nil {
+			log.Err(err).Msg("could not load auth settings correctly")
+			return 7
+		}
+		writeSettings.Auth = *authSettings
+	} else {
+		log.Warn().Msg("no auth settings provided, authentication on write endpoints is disabled")
 	}
 
 	myip := os.Getenv("IP")
@@ -55,27 +173,94 @@ func run() int {
 
 	// create channels
 	peerEvents := make(chan *peers.PeerEvent, 100)
-	genBlock := make(chan *pb.Block, 10)
+	bus := events.NewBus()
+
+	var signer ed25519.PrivateKey
+	if opts.validatorPath != "" {
+		validator, err := wallet.LoadFile(opts.validatorPath)
+		if err != nil {
+			log.Err(err).Msg("could not load validator wallet correctly")
+			return 11
+		}
+		signer = validator.PrivateKey
+		log.Info().Str("validator", validator.Address()).Msg("sealing blocks as validator")
+	}
+
+	consensusOptions, err := consensusSettings.FactoryOptions(signer)
+	if err != nil {
+		log.Err(err).Msg("could not load consensus settings correctly")
+		return 4
+	}
+	if consensusSettings.ProofOfAuthority != nil && signer == nil && opts.mine {
+		log.Warn().Msg("no validator wallet provided, submitted entries will be refused")
+		opts.mine = false
+		writeSettings.DisableMining = true
+	}
+
+	checkpoints, err := block.ParseCheckpoints(consensusSettings.Checkpoints)
+	if err != nil {
+		log.Err(err).Msg("could not load checkpoints correctly")
+		return 4
+	}
 
 	// create structures
-	// TODO: this needs to be adapted with proof of stake in future
-	bf := block.NewBlockFactory(block.WithProofOfWork(consensusSettings.ProofOfWork))
+	bf := block.NewBlockFactory(append(consensusOptions, block.WithGenesis(network.Genesis), block.WithCheckpoints(checkpoints), block.WithEventBus(bus), block.WithMetrics(metrics.BlockMetrics{}))...)
 	blockchain := bf.NewBlockChain()
-	publicServer := servers.NewPublicServer(blockchain, genBlock, bf)
-	probesServer := servers.NewProbesServer(blockchain)
-	commServer := servers.NewPeerCommunicationServer(blockchain)
-	grpcServer := grpc.NewServer()
+	if opts.bootstrapPath != "" {
+		if err := bootstrapChain(blockchain, opts.bootstrapPath); err != nil {
+			log.Err(err).Str("path", opts.bootstrapPath).Msg("could not bootstrap chain")
+			return 8
+		}
+		log.Info().Int("height", blockchain.Length()-1).Msg("chain bootstrapped from file")
+	}
 	peerManager := peers.NewPeersManager(blockchain)
-	mgr, err := controllers.NewControllerManager()
+	designated := opts.mine && opts.designated
+	mgr, err := controllers.NewControllerManager(discovery.Namespaces, designated)
 	if err != nil {
 		log.Err(err).Msg("error while creating a controller manager")
 		return 2
 	}
-	_, err = controllers.NewPodReconciler(mgr, peerEvents)
-	if err != nil {
-		log.Err(err).Msg("error while creating the pod controller")
+	if err := controllers.NewPeerReconciler(mgr, discovery, peerEvents); err != nil {
+		log.Err(err).Msg("error while creating the peer discovery controller")
 		return 3
 	}
+	worker := mining.NewWorker(blockchain, bf, bus)
+	var miner mining.Miner = worker
+	if designated {
+		resolver, err := controllers.NewLeaderResolver(mgr, discovery)
+		if err != nil {
+			log.Err(err).Msg("error while creating the leader resolver")
+			return 10
+		}
+
+		// the manager only starts the leader miner when this node is
+		// elected.
+		leaderMiner := mining.NewLeaderMiner(worker, resolver, peerManager)
+		if err := mgr.Add(leaderMiner); err != nil {
+			log.Err(err).Msg("error while adding the leader miner")
+			return 10
+		}
+		miner = leaderMiner
+	}
+	var gadget *finality.Gadget
+	if consensusSettings.Finality != nil {
+		gadget, err = finality.NewGadget(consensusSettings.Finality, signer, blockchain, bus, peerManager)
+		if err != nil {
+			log.Err(err).Msg("error while creating the finality gadget")
+			return 12
+		}
+		log.Info().Bool("validating", gadget.Validating()).Msg("finality is enabled")
+	}
+	publicServer := servers.NewPublicServer(blockchain, bus, miner, writeSettings)
+	commServer := servers.NewPeerCommunicationServer(blockchain, worker, gadget)
+	grpcServer := grpc.NewServer()
+	probesServer := servers.NewProbesServer(peerManager, readiness)
+	var adminServer *servers.AdminServer
+	if adminToken := os.Getenv("ADMIN_TOKEN"); adminToken != "" {
+		adminServer = servers.NewAdminServer(adminToken, peerManager, commServer, bf.ProofOfAuthority())
+	} else {
+		log.Warn().Msg("no admin token provided, admin server is disabled")
+	}
 
 	// run the services
 	ctx, canc := context.WithCancel(context.Background())
@@ -95,8 +280,6 @@ func run() int {
 		if err := publicServer.FiberApp.Listen(":8080"); err != nil {
 			log.Err(err).Msg("error while serving public server")
 		}
-
-		close(genBlock)
 	}()
 
 	go func() {
@@ -106,6 +289,32 @@ func run() int {
 		}
 	}()
 
+	if adminServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := adminServer.FiberApp.Listen(":8083"); err != nil {
+				log.Err(err).Msg("error while serving admin server")
+			}
+		}()
+	}
+
+	if gadget != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gadget.Run(ctx)
+		}()
+	}
+
+	if opts.mine && !designated {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker.Run(ctx)
+		}()
+	}
+
 	go func() {
 		defer wg.Done()
 		lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", myip, 8082))
@@ -124,10 +333,20 @@ func run() int {
 
 	go func() {
 		defer wg.Done()
-		log.Info().Msg("starting pod controller...")
+		log.Info().Msg("starting peer discovery controller...")
 
 		if err := mgr.Start(ctx); err != nil {
 			log.Err(err).Msg("error while starting controller manager")
+
+			if designated && ctx.Err() == nil {
+				// leadership was lost, or could not be acquired: the
+				// node must stop, as the manager does not elect it
+				// again.
+				select {
+				case stopChan <- syscall.SIGTERM:
+				default:
+				}
+			}
 		}
 
 		close(peerEvents)
@@ -135,7 +354,7 @@ func run() int {
 
 	go func() {
 		defer wg.Done()
-		commServer.ServeSubscriptions(genBlock)
+		commServer.ServeSubscriptions(bus)
 	}()
 
 	<-stopChan
@@ -144,6 +363,10 @@ func run() int {
 	fmt.Println()
 	log.Info().Msg("exit requested")
 
+	// closing the bus first ends all event streams, which would otherwise
+	// keep the public server from shutting down.
+	bus.Close()
+
 	log.Info().Msg("shutting down public server...")
 
 	if err := publicServer.FiberApp.Shutdown(); err != nil {
@@ -155,6 +378,13 @@ func run() int {
 		log.Err(err).Msg("error while shutting down probes server")
 	}
 
+	if adminServer != nil {
+		log.Info().Msg("shutting down admin server...")
+		if err := adminServer.FiberApp.Shutdown(); err != nil {
+			log.Err(err).Msg("error while shutting down admin server")
+		}
+	}
+
 	log.Info().Msg("shutting down peers server...")
 	grpcServer.GracefulStop()
 
@@ -163,17 +393,199 @@ func run() int {
 	return 0
 }
 
-func getConsensusSettings(filePath string) (*ConsensusSettings, error) {
-	csBytes, err := ioutil.ReadFile(filePath)
+// runLight runs the node in light mode: only block headers are synced from
+// full peers, and inclusion of entries and balances are verified with merkle
+// proofs.
+func runLight(log zerolog.Logger, network *block.Network, consensusSettings *block.ConsensusSettings, readiness servers.ReadinessSettings, discovery controllers.DiscoverySettings, watch, watchAddrs string) int {
+	// create channels
+	peerEvents := make(chan *peers.PeerEvent, 100)
+
+	consensusOptions, err := consensusSettings.FactoryOptions(nil)
+	if err != nil {
+		log.Err(err).Msg("could not load consensus settings correctly")
+		return 4
+	}
+
+	checkpoints, err := block.ParseCheckpoints(consensusSettings.Checkpoints)
+	if err != nil {
+		log.Err(err).Msg("could not load checkpoints correctly")
+		return 4
+	}
+
+	// create structures
+	bf := block.NewBlockFactory(append(consensusOptions, block.WithGenesis(network.Genesis), block.WithCheckpoints(checkpoints))...)
+	lightClient := lightclient.NewLightClient(bf)
+	lightServer := servers.NewLightServer(lightClient)
+	probesServer := servers.NewProbesServer(lightClient, readiness)
+	mgr, err := controllers.NewControllerManager(discovery.Namespaces, false)
+	if err != nil {
+		log.Err(err).Msg("error while creating a controller manager")
+		return 2
+	}
+	if err := controllers.NewPeerReconciler(mgr, discovery, peerEvents); err != nil {
+		log.Err(err).Msg("error while creating the peer discovery controller")
+		return 3
+	}
+
+	for _, entryHash := range strings.Split(watch, ",") {
+		if entryHash == "" {
+			continue
+		}
+
+		decoded, err := hex.DecodeString(entryHash)
+		if err != nil {
+			log.Err(err).Str("entry-hash", entryHash).Msg("could not parse entry hash to watch")
+			return 6
+		}
+		lightClient.Watch(decoded)
+	}
+
+	for _, address := range strings.Split(watchAddrs, ",") {
+		if address == "" {
+			continue
+		}
+
+		if err := lightClient.WatchAddress(address); err != nil {
+			log.Err(err).Str("address", address).Msg("could not parse address to watch")
+			return 6
+		}
+	}
+
+	// run the services
+	ctx, canc := context.WithCancel(context.Background())
+	stopChan := make(chan os.Signal, 1)
+	signal.Notify(stopChan, syscall.SIGINT, syscall.SIGTERM)
+	wg := sync.WaitGroup{}
+	wg.Add(4)
+
+	go func() {
+		defer wg.Done()
+		log.Info().Msg("listening for peer events in light mode...")
+		lightClient.ListenPeerEvents(peerEvents)
+	}()
+
+	go func() {
+		defer wg.Done()
+		if err := lightServer.FiberApp.Listen(":8080"); err != nil {
+			log.Err(err).Msg("error while serving light server")
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		if err := probesServer.FiberApp.Listen(":8081"); err != nil {
+			log.Err(err).Msg("error while serving public server")
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		log.Info().Msg("starting peer discovery controller...")
+
+		if err := mgr.Start(ctx); err != nil {
+			log.Err(err).Msg("error while starting controller manager")
+		}
+
+		close(peerEvents)
+	}()
+
+	<-stopChan
+	canc()
+
+	fmt.Println()
+	log.Info().Msg("exit requested")
+
+	log.Info().Msg("shutting down light server...")
+	if err := lightServer.FiberApp.Shutdown(); err != nil {
+		log.Err(err).Msg("error while shutting down light server")
+	}
+
+	log.Info().Msg("shutting down probes server...")
+	if err := probesServer.FiberApp.Shutdown(); err != nil {
+		log.Err(err).Msg("error while shutting down probes server")
+	}
+
+	wg.Wait()
+	log.Info().Msg("clean up done, goodbye!")
+	return 0
+}
+
+func newOperatorCommand(exitCode *int) *cobra.Command {
+	var namespace string
+
+	cmd := &cobra.Command{
+		Use:   "operator",
+		Short: "Run the operator that deploys NaiveCoinNetwork resources",
+		Args:  cobra.NoArgs,
+		Run: func(*cobra.Command, []string) {
+			*exitCode = runOperator(namespace)
+		},
+	}
+
+	cmd.Flags().StringVar(&namespace, "namespace", "", "the namespace where networks are managed. If empty, all namespaces are.")
+	return cmd
+}
+
+// runOperator runs the operator until a termination signal is received.
+func runOperator(namespace string) int {
+	log := zerolog.New(os.Stderr).With().Timestamp().Logger()
+	log.Info().Msg("starting operator...")
+
+	mgr, err := controllers.NewOperatorManager(namespace)
+	if err != nil {
+		log.Err(err).Msg("error while creating the operator manager")
+		return 2
+	}
+
+	if _, err := controllers.NewNetworkReconciler(mgr); err != nil {
+		log.Err(err).Msg("error while creating the network controller")
+		return 3
+	}
+
+	ctx, canc := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer canc()
+
+	if err := mgr.Start(ctx); err != nil {
+		log.Err(err).Msg("error while running the operator")
+		return 1
+	}
+
+	log.Info().Msg("clean up done, goodbye!")
+	return 0
+}
+
+// bootstrapChain validates the chain exported in the provided file and
+// replaces the one of the blockchain with it.
+func bootstrapChain(blockchain *block.BlockChain, filePath string) error {
+	format, err := block.ExportFormatFromPath(filePath)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	chain, err := block.ReadChain(file, format)
+	if err != nil {
+		return err
+	}
+
+	return blockchain.ReplaceWith(chain)
+}
+
+func getAuthSettings(filePath string) (*servers.AuthSettings, error) {
+	asBytes, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: update this for proof of stake as well
-	var consesusSettings ConsensusSettings
-	if err := yaml.Unmarshal(csBytes, &consesusSettings); err != nil {
+	var authSettings servers.AuthSettings
+	if err := yaml.Unmarshal(asBytes, &authSettings); err != nil {
 		return nil, err
 	}
 
-	return &consesusSettings, nil
+	return &authSettings, nil
 }
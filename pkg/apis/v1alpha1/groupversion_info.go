@@ -0,0 +1,19 @@
+// Package v1alpha1 contains the v1alpha1 version of the naivecoin.io API
+// group, which describes naivecoin networks deployed on Kubernetes.
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group and version of the objects of this package.
+	GroupVersion = schema.GroupVersion{Group: "naivecoin.io", Version: "v1alpha1"}
+
+	// SchemeBuilder adds the objects of this package to a scheme.
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the objects of this package to the provided scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
@@ -0,0 +1,172 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NaiveCoinNetworkSpec defines the nodes of a network.
+type NaiveCoinNetworkSpec struct {
+	// Image is the container image of the nodes.
+	Image string `json:"image"`
+	// Replicas is the number of full nodes that do not mine blocks.
+	Replicas int32 `json:"replicas"`
+	// Miners is the number of full nodes that mine the entries they
+	// receive.
+	Miners int32 `json:"miners"`
+	// Network is the name of the built-in network the nodes join: mainnet,
+	// testnet or regtest. Defaults to mainnet.
+	// +optional
+	Network string `json:"network,omitempty"`
+	// Consensus contains the consensus settings of the nodes. It must be
+	// provided unless the network defines its own, as regtest does.
+	// +optional
+	Consensus *ConsensusSpec `json:"consensus,omitempty"`
+	// Genesis replaces the genesis block of the network, if provided.
+	// +optional
+	Genesis *GenesisSpec `json:"genesis,omitempty"`
+	// Storage defines a persistent volume for each node, mounted on /data.
+	// +optional
+	Storage *StorageSpec `json:"storage,omitempty"`
+	// BootstrapFile is the path of an exported chain that nodes load at
+	// startup, e.g. one stored on /data.
+	// +optional
+	BootstrapFile string `json:"bootstrapFile,omitempty"`
+	// DesignatedMiner makes the miners elect one of them to mine all the
+	// entries, so that they never mine competing blocks.
+	// +optional
+	DesignatedMiner bool `json:"designatedMiner,omitempty"`
+}
+
+// ConsensusSpec defines the consensus of the network.
+type ConsensusSpec struct {
+	// ProofOfWork contains the settings of the proof of work.
+	// +optional
+	ProofOfWork *ProofOfWorkSpec `json:"proofOfWork,omitempty"`
+	// ProofOfAuthority replaces the proof of work, if provided.
+	// +optional
+	ProofOfAuthority *ProofOfAuthoritySpec `json:"proofOfAuthority,omitempty"`
+	// Finality enables the finality gadget, if provided.
+	// +optional
+	Finality *FinalitySpec `json:"finality,omitempty"`
+	// Checkpoints maps the heights of known blocks to their hashes.
+	// +optional
+	Checkpoints map[int64]string `json:"checkpoints,omitempty"`
+}
+
+// ProofOfWorkSpec defines the settings of the proof of work.
+type ProofOfWorkSpec struct {
+	InitialDifficulty            int  `json:"initialDifficulty"`
+	BlockGenerationInterval      int  `json:"blockGenerationInterval"`
+	DifficultyAdjustmentInterval int  `json:"difficultyAdjustmentInterval"`
+	FixedDifficulty              bool `json:"fixedDifficulty,omitempty"`
+}
+
+// ProofOfAuthoritySpec defines the settings of the proof of authority.
+type ProofOfAuthoritySpec struct {
+	// Validators are the addresses of the validators that can seal blocks
+	// at the beginning of the chain.
+	Validators []string `json:"validators"`
+	// WalletsSecret is the name of a Secret with the wallet of each miner,
+	// stored under the name of its pod followed by ".json". Miners without
+	// a wallet do not seal blocks.
+	// +optional
+	WalletsSecret string `json:"walletsSecret,omitempty"`
+}
+
+// FinalitySpec defines the settings of the finality gadget.
+type FinalitySpec struct {
+	// Validators are the addresses of the validators that vote on
+	// checkpoints.
+	Validators []string `json:"validators"`
+	// CheckpointInterval is the number of blocks between two checkpoints.
+	// +optional
+	CheckpointInterval int `json:"checkpointInterval,omitempty"`
+	// WalletsSecret is the name of a Secret with the wallet of each miner,
+	// as in ProofOfAuthoritySpec. It is ignored if the proof of authority
+	// defines one.
+	// +optional
+	WalletsSecret string `json:"walletsSecret,omitempty"`
+}
+
+// GenesisSpec defines the genesis block of the network.
+type GenesisSpec struct {
+	// +optional
+	ChainID string `json:"chainID,omitempty"`
+	// +optional
+	Timestamp int64 `json:"timestamp,omitempty"`
+	// +optional
+	Data []string `json:"data,omitempty"`
+	// +optional
+	Allocations []AllocationSpec `json:"allocations,omitempty"`
+	// +optional
+	Difficulty int64 `json:"difficulty,omitempty"`
+}
+
+// AllocationSpec assigns an amount to an address in the genesis block.
+type AllocationSpec struct {
+	Address string `json:"address"`
+	Amount  uint64 `json:"amount"`
+}
+
+// StorageSpec defines the persistent volume of each node.
+type StorageSpec struct {
+	// Size of the volume.
+	Size resource.Quantity `json:"size"`
+	// StorageClassName is the storage class of the volume. The default one
+	// is used if not provided.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+// NodeStatus is the status of a node of the network.
+type NodeStatus struct {
+	// Name of the pod of the node.
+	Name string `json:"name"`
+	// Miner tells whether the node mines blocks.
+	Miner bool `json:"miner"`
+	// Ready tells whether the node is synced and ready to receive traffic.
+	Ready bool `json:"ready"`
+	// Height is the index of the last block of the node.
+	Height int64 `json:"height"`
+	// Peers is the number of peers of the node.
+	Peers int `json:"peers"`
+}
+
+// NaiveCoinNetworkStatus is the observed status of a network.
+type NaiveCoinNetworkStatus struct {
+	// ObservedGeneration is the generation of the spec that was last
+	// applied.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ReadyNodes is the number of nodes that are ready.
+	ReadyNodes int32 `json:"readyNodes"`
+	// Height is the highest index among the last blocks of the nodes.
+	Height int64 `json:"height"`
+	// Nodes contains the status of each node that could be reached.
+	// +optional
+	Nodes []NodeStatus `json:"nodes,omitempty"`
+}
+
+// NaiveCoinNetwork is a network of naivecoin nodes.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+type NaiveCoinNetwork struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NaiveCoinNetworkSpec   `json:"spec,omitempty"`
+	Status NaiveCoinNetworkStatus `json:"status,omitempty"`
+}
+
+// NaiveCoinNetworkList contains a list of NaiveCoinNetwork.
+// +kubebuilder:object:root=true
+type NaiveCoinNetworkList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NaiveCoinNetwork `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NaiveCoinNetwork{}, &NaiveCoinNetworkList{})
+}
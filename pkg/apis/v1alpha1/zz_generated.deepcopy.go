@@ -0,0 +1,285 @@
+// This file follows the layout of the output of controller-gen, so that it
+// can be regenerated with it once the project uses it.
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *AllocationSpec) DeepCopyInto(out *AllocationSpec) {
+	*out = *in
+}
+
+// DeepCopy returns a deep copy of the AllocationSpec.
+func (in *AllocationSpec) DeepCopy() *AllocationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AllocationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *ConsensusSpec) DeepCopyInto(out *ConsensusSpec) {
+	*out = *in
+	if in.ProofOfWork != nil {
+		in, out := &in.ProofOfWork, &out.ProofOfWork
+		*out = new(ProofOfWorkSpec)
+		**out = **in
+	}
+	if in.ProofOfAuthority != nil {
+		in, out := &in.ProofOfAuthority, &out.ProofOfAuthority
+		*out = new(ProofOfAuthoritySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Finality != nil {
+		in, out := &in.Finality, &out.Finality
+		*out = new(FinalitySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Checkpoints != nil {
+		in, out := &in.Checkpoints, &out.Checkpoints
+		*out = make(map[int64]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the ConsensusSpec.
+func (in *ConsensusSpec) DeepCopy() *ConsensusSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsensusSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *FinalitySpec) DeepCopyInto(out *FinalitySpec) {
+	*out = *in
+	if in.Validators != nil {
+		in, out := &in.Validators, &out.Validators
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy returns a deep copy of the FinalitySpec.
+func (in *FinalitySpec) DeepCopy() *FinalitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FinalitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *GenesisSpec) DeepCopyInto(out *GenesisSpec) {
+	*out = *in
+	if in.Data != nil {
+		in, out := &in.Data, &out.Data
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Allocations != nil {
+		in, out := &in.Allocations, &out.Allocations
+		*out = make([]AllocationSpec, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy returns a deep copy of the GenesisSpec.
+func (in *GenesisSpec) DeepCopy() *GenesisSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GenesisSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *NaiveCoinNetwork) DeepCopyInto(out *NaiveCoinNetwork) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy returns a deep copy of the NaiveCoinNetwork.
+func (in *NaiveCoinNetwork) DeepCopy() *NaiveCoinNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(NaiveCoinNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject returns a deep copy of the receiver as a runtime.Object.
+func (in *NaiveCoinNetwork) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *NaiveCoinNetworkList) DeepCopyInto(out *NaiveCoinNetworkList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NaiveCoinNetwork, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the NaiveCoinNetworkList.
+func (in *NaiveCoinNetworkList) DeepCopy() *NaiveCoinNetworkList {
+	if in == nil {
+		return nil
+	}
+	out := new(NaiveCoinNetworkList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject returns a deep copy of the receiver as a runtime.Object.
+func (in *NaiveCoinNetworkList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *NaiveCoinNetworkSpec) DeepCopyInto(out *NaiveCoinNetworkSpec) {
+	*out = *in
+	if in.Consensus != nil {
+		in, out := &in.Consensus, &out.Consensus
+		*out = new(ConsensusSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Genesis != nil {
+		in, out := &in.Genesis, &out.Genesis
+		*out = new(GenesisSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(StorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy returns a deep copy of the NaiveCoinNetworkSpec.
+func (in *NaiveCoinNetworkSpec) DeepCopy() *NaiveCoinNetworkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NaiveCoinNetworkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *NaiveCoinNetworkStatus) DeepCopyInto(out *NaiveCoinNetworkStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodeStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy returns a deep copy of the NaiveCoinNetworkStatus.
+func (in *NaiveCoinNetworkStatus) DeepCopy() *NaiveCoinNetworkStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NaiveCoinNetworkStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *NodeStatus) DeepCopyInto(out *NodeStatus) {
+	*out = *in
+}
+
+// DeepCopy returns a deep copy of the NodeStatus.
+func (in *NodeStatus) DeepCopy() *NodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *ProofOfAuthoritySpec) DeepCopyInto(out *ProofOfAuthoritySpec) {
+	*out = *in
+	if in.Validators != nil {
+		in, out := &in.Validators, &out.Validators
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy returns a deep copy of the ProofOfAuthoritySpec.
+func (in *ProofOfAuthoritySpec) DeepCopy() *ProofOfAuthoritySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProofOfAuthoritySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *ProofOfWorkSpec) DeepCopyInto(out *ProofOfWorkSpec) {
+	*out = *in
+}
+
+// DeepCopy returns a deep copy of the ProofOfWorkSpec.
+func (in *ProofOfWorkSpec) DeepCopy() *ProofOfWorkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProofOfWorkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out. in must be non-nil.
+func (in *StorageSpec) DeepCopyInto(out *StorageSpec) {
+	*out = *in
+	out.Size = in.Size.DeepCopy()
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy returns a deep copy of the StorageSpec.
+func (in *StorageSpec) DeepCopy() *StorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -13,48 +13,75 @@ const (
 	genesisBlockData string = "this is the genesis block!"
 )
 
-// calculateHash calculates and returns the sha256 of the provided block.
-func calculateHash(block *pb.Block) []byte {
-	header := bytes.Join([][]byte{
+// calculateHash calculates and returns the sha256 of the provided block
+// header.
+//
+// Only the header is hashed: the body is committed to through the merkle
+// root, so that headers can be verified without downloading the entries.
+func calculateHash(header *pb.BlockHeader) []byte {
+	data := bytes.Join([][]byte{
 		func() []byte {
 			bytesVal := make([]byte, 8)
-			binary.LittleEndian.PutUint64(bytesVal, uint64(block.Index))
+			binary.LittleEndian.PutUint64(bytesVal, uint64(header.Index))
 			return bytesVal
 		}(),
 		func() []byte {
 			bytesVal := make([]byte, 8)
-			binary.LittleEndian.PutUint64(bytesVal, uint64(block.Timestamp))
+			binary.LittleEndian.PutUint64(bytesVal, uint64(header.Timestamp))
 			return bytesVal
 		}(),
-		block.PreviousBlockHash,
-		[]byte(block.Data),
+		header.PreviousBlockHash,
+		header.MerkleRoot,
 	}, []byte{})
 
-	hash := sha256.Sum256(header)
+	hash := sha256.Sum256(data)
 	return hash[:]
 }
 
-func newGenesisBlock() *pb.Block {
-	genesis := &pb.Block{
-		Index:             0,
-		Timestamp:         0, // Setting this as 0 will prevent errors when we get blocks from other peers
-		PreviousBlockHash: []byte{},
-		Data:              genesisBlockData,
-		Hash:              []byte{},
+// validateGenesisBlock checks if the provided block is the expected genesis
+// block and returns an error if not.
+func validateGenesisBlock(block, genesis *pb.Block) error {
+	if block == nil || block.Header == nil ||
+		block.Header.Index != 0 ||
+		block.Header.Timestamp != genesis.Header.Timestamp ||
+		block.Header.Difficulty != genesis.Header.Difficulty ||
+		len(block.Header.PreviousBlockHash) > 0 ||
+		!bytes.Equal(genesis.Header.MerkleRoot, block.Header.MerkleRoot) ||
+		!bytes.Equal(genesis.Hash, block.Hash) {
+		return fmt.Errorf("genesis block is wrong")
 	}
-	genesis.Hash = calculateHash(genesis)
-	return genesis
+
+	return nil
 }
 
 // validateBlock checks if the block is valid and returns an error if not.
 func validateBlock(block, prevBlock *pb.Block) error {
-	if block.Index != prevBlock.Index+1 {
+	if block == nil || block.Header == nil {
+		return fmt.Errorf("block header is missing")
+	}
+
+	if block.Header.Index != prevBlock.Header.Index+1 {
 		return fmt.Errorf("index is not valid")
 	}
 
-	if !bytes.Equal(block.PreviousBlockHash, prevBlock.Hash) {
+	if !bytes.Equal(block.Header.PreviousBlockHash, prevBlock.Hash) {
 		return fmt.Errorf("previous block hash does not match")
 	}
 
+	if !bytes.Equal(block.Header.MerkleRoot, calculateMerkleRoot(block.Entries)) {
+		return fmt.Errorf("merkle root does not match the block entries")
+	}
+
+	return nil
+}
+
+// validateHash checks that the hash of a block of a chain without consensus
+// engine is the one of its header and returns an error if not. The engines
+// check the hash themselves, as they hash the header differently.
+func validateHash(block *pb.Block) error {
+	if !bytes.Equal(calculateHash(block.Header), block.Hash) {
+		return fmt.Errorf("hash does not match the block header")
+	}
+
 	return nil
 }
@@ -1,10 +1,14 @@
 package block
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"math/big"
 	"sync"
 	"time"
 
+	"github.com/SunSince90/go-naivecoin/pkg/events"
 	"github.com/SunSince90/go-naivecoin/pkg/pb"
 )
 
@@ -12,6 +16,14 @@ import (
 // to some settings, e.g. the consensus method.
 type BlockFactory struct {
 	pow *ProofOfWork
+	poa *ProofOfAuthority
+	// checkpoints are the hashes of known blocks of the chain.
+	checkpoints Checkpoints
+	events      *events.Bus
+	metrics     Metrics
+	genesis     *GenesisSettings
+	clock       Clock
+	instantSeal bool
 }
 
 // FactoryOptions defines options for the block factory.
@@ -25,6 +37,70 @@ func WithProofOfWork(settings *ProofOfWorkSettings) FactoryOptions {
 	}
 }
 
+// WithProofOfAuthority instructs the block factory to seal blocks and
+// validate them with the provided proof of authority, instead of the proof
+// of work.
+func WithProofOfAuthority(poa *ProofOfAuthority) FactoryOptions {
+	return func(bf *BlockFactory) {
+		bf.poa = poa
+	}
+}
+
+// WithCheckpoints instructs the block factory to create blockchains that
+// refuse blocks conflicting with the provided checkpoints.
+func WithCheckpoints(checkpoints Checkpoints) FactoryOptions {
+	return func(bf *BlockFactory) {
+		bf.checkpoints = checkpoints
+	}
+}
+
+// WithEventBus instructs the block factory to create blockchains that
+// publish their events on the provided bus.
+func WithEventBus(bus *events.Bus) FactoryOptions {
+	return func(bf *BlockFactory) {
+		bf.events = bus
+	}
+}
+
+// WithMetrics instructs the block factory to report measurements about the
+// blocks it mines and the blockchains it creates to the provided metrics.
+func WithMetrics(metrics Metrics) FactoryOptions {
+	return func(bf *BlockFactory) {
+		bf.metrics = metrics
+	}
+}
+
+// WithGenesis instructs the block factory to create blockchains that start
+// from the genesis block defined by the provided settings, instead of the
+// one of mainnet.
+func WithGenesis(settings *GenesisSettings) FactoryOptions {
+	return func(bf *BlockFactory) {
+		bf.genesis = settings
+	}
+}
+
+// WithClock instructs the block factory to timestamp blocks and validate
+// their timestamps with the provided clock, instead of the one of the
+// machine.
+func WithClock(clock Clock) FactoryOptions {
+	return func(bf *BlockFactory) {
+		bf.clock = clock
+	}
+}
+
+// WithInstantSeal instructs the block factory to seal blocks instantly with
+// proof of work: the difficulty is still recorded in the blocks and adjusted,
+// but their hashes don't need to meet it. Together with a FakeClock, this
+// makes mined blocks always the same.
+//
+// This is meant for tests only: chains sealed this way are refused by
+// factories that do not use it.
+func WithInstantSeal() FactoryOptions {
+	return func(bf *BlockFactory) {
+		bf.instantSeal = true
+	}
+}
+
 // NewBlockFactory initializes a new block factory with the provided settings
 // and returns it to the caller so it can be used to create new blocks and
 // blockchains.
@@ -34,47 +110,265 @@ func NewBlockFactory(options ...FactoryOptions) *BlockFactory {
 		o(factory)
 	}
 
+	if factory.genesis == nil {
+		factory.genesis = networks[NetworkMainnet].Genesis
+	}
+
+	if factory.clock == nil {
+		factory.clock = SystemClock
+	}
+
+	if factory.metrics == nil {
+		factory.metrics = nopMetrics{}
+	}
+
+	if factory.poa != nil {
+		// blocks are sealed, not mined.
+		factory.pow = nil
+		factory.poa.clock = factory.clock
+	}
+
+	if factory.pow != nil {
+		if factory.genesis.Difficulty > 0 {
+			// the chain starts from the difficulty of the genesis block, so
+			// the minimum one is computed from it too.
+			factory.pow.difficulty = int(factory.genesis.Difficulty)
+			factory.pow.initialDifficulty = int(factory.genesis.Difficulty)
+		}
+		factory.pow.clock = factory.clock
+		factory.pow.instantSeal = factory.instantSeal
+	}
+
 	return factory
 }
 
 // TODO: WithProofOfStake
 
-// NewBlock creates a new block with the provided data and returns it to the
-// caller.
-func (f *BlockFactory) NewBlock(data string, prevBlock *pb.Block) *pb.Block {
+// NewBlock creates a new block with the provided entries and returns it to
+// the caller.
+//
+// It returns nil if the block cannot be sealed, i.e. with proof of authority
+// and no signer: use MineBlock to know why.
+func (f *BlockFactory) NewBlock(entries []string, prevBlock *pb.Block) *pb.Block {
+	// with no cancellation, sealing is the only step that can fail.
+	b, _ := f.MineBlock(context.Background(), entries, prevBlock)
+	return b
+}
+
+// MineBlock creates a new block with the provided entries, like NewBlock, but
+// it stops mining and returns an error as soon as the context is cancelled.
+// It also returns an error if the block cannot be sealed.
+func (f *BlockFactory) MineBlock(ctx context.Context, entries []string, prevBlock *pb.Block) (*pb.Block, error) {
 	b := &pb.Block{
-		Index:             prevBlock.Index + 1,
-		Timestamp:         time.Now().Unix(),
-		PreviousBlockHash: prevBlock.Hash,
-		Data:              data,
+		Header: &pb.BlockHeader{
+			Index:             prevBlock.Header.Index + 1,
+			Timestamp:         f.clock.Now().Unix(),
+			PreviousBlockHash: prevBlock.Hash,
+			MerkleRoot:        calculateMerkleRoot(entries),
+		},
+		Entries: entries,
 	}
 
-	if f.pow != nil {
-		diff, nonce, hash := f.pow.calculateHash(b)
+	if f.poa != nil {
+		hash, err := f.poa.seal(b.Header)
+		if err != nil {
+			return nil, err
+		}
+		b.Hash = hash
+	} else if f.pow != nil {
+		start := time.Now()
+		diff, nonce, hash, err := f.pow.calculateHash(ctx, b.Header)
+		if err != nil {
+			return nil, fmt.Errorf("mining was stopped: %w", err)
+		}
+		f.metrics.BlockMined(time.Since(start), nonce+1)
 
-		b.Difficulty = diff
-		b.Nonce = nonce
+		b.Header.Difficulty = diff
+		b.Header.Nonce = nonce
 		b.Hash = hash
 	} else {
-		b.Hash = calculateHash(b)
+		b.Hash = calculateHash(b.Header)
 	}
 
-	return b
+	return b, nil
 }
 
 // NewBlockChain creates a new BlockChain and returns it to the caller.
 func (f *BlockFactory) NewBlockChain() *BlockChain {
-	genesis := newGenesisBlock()
+	genesis := newGenesisBlock(f.genesis)
 
 	bc := &BlockChain{
 		chain:                []*pb.Block{genesis},
+		genesis:              genesis,
+		chainID:              f.genesis.ChainID,
 		lock:                 sync.Mutex{},
 		cumulativeDifficulty: big.NewInt(0),
+		entries:              map[string]entryLocation{},
+		heights:              map[string]int{},
+		events:               f.events,
+		metrics:              f.metrics,
+		checkpoints:          f.checkpoints,
 	}
+	bc.indexBlock(genesis)
 
 	if f.pow != nil {
 		bc.pow = f.pow
 	}
 
+	if f.poa != nil {
+		bc.poa = f.poa
+		bc.authorities = f.poa.genesisAuthorities()
+	}
+	bc.updateMetrics()
+
 	return bc
 }
+
+// HashHeader calculates and returns the hash of the provided header according
+// to the consensus of the factory.
+func (f *BlockFactory) HashHeader(header *pb.BlockHeader) []byte {
+	if header.Index == 0 {
+		// the genesis block is never mined
+		return calculateHash(header)
+	}
+
+	if f.poa != nil {
+		return f.poa.hashHeader(header)
+	}
+
+	if f.pow != nil {
+		return f.pow.hashHeader(header)
+	}
+
+	return calculateHash(header)
+}
+
+// ValidateHeader checks that the provided hash belongs to the header and
+// that it satisfies the consensus of the factory. It returns an error if not.
+//
+// This only needs the header and not the whole block, so it can be used by
+// clients that do not store the block entries. With proof of authority, only
+// the signature is checked: whether the block was sealed by a validator
+// depends on the previous ones and is checked with the Authorities of the
+// chain.
+//
+// Headers that conflict with a checkpoint are refused with
+// ErrCheckpointMismatch.
+func (f *BlockFactory) ValidateHeader(header *pb.BlockHeader, hash []byte) error {
+	return f.validateHeader(header, hash, f.checkpoints.matches(header.GetIndex(), hash))
+}
+
+// ValidateCommittedHeader is like ValidateHeader, but does not verify the
+// signature of the header with proof of authority. It must only be used for
+// headers of a linked chain that are not after its anchor, as returned by
+// Checkpoints.Anchor.
+func (f *BlockFactory) ValidateCommittedHeader(header *pb.BlockHeader, hash []byte) error {
+	return f.validateHeader(header, hash, true)
+}
+
+func (f *BlockFactory) validateHeader(header *pb.BlockHeader, hash []byte, committed bool) error {
+	if header == nil {
+		return fmt.Errorf("block header is missing")
+	}
+
+	if !bytes.Equal(f.HashHeader(header), hash) {
+		return fmt.Errorf("hash does not match the block header")
+	}
+
+	if err := f.checkpoints.check(header.Index, hash); err != nil {
+		return err
+	}
+
+	if f.poa != nil && header.Index > 0 {
+		return f.poa.validateSeal(header, hash, committed)
+	}
+
+	if f.pow != nil && header.Index > 0 {
+		return f.pow.validateHeaderTarget(header, hash)
+	}
+
+	return nil
+}
+
+// Checkpoints returns the checkpoints of the chains created by the factory.
+func (f *BlockFactory) Checkpoints() Checkpoints {
+	return f.checkpoints
+}
+
+// ProofOfAuthority returns the proof of authority of the factory, or nil if
+// it does not use it.
+func (f *BlockFactory) ProofOfAuthority() *ProofOfAuthority {
+	return f.poa
+}
+
+// GenesisAuthorities returns the validator set at the beginning of the chains
+// created by the factory, or nil if they do not use proof of authority.
+func (f *BlockFactory) GenesisAuthorities() *Authorities {
+	if f.poa == nil {
+		return nil
+	}
+
+	return f.poa.genesisAuthorities()
+}
+
+// ValidateChain checks that the provided chain starts from the genesis block,
+// that each block follows the previous one and that all of them satisfy the
+// consensus of the factory, including the stored hash of each block, like
+// when replacing the chain. Differently from replacing it, the chain is not
+// compared with any other one.
+//
+// If a block is not valid, an *InvalidBlockError with its height is
+// returned.
+func (f *BlockFactory) ValidateChain(chain []*pb.Block) error {
+	if len(chain) == 0 {
+		return fmt.Errorf("chain is empty")
+	}
+
+	if err := validateGenesisBlock(chain[0], newGenesisBlock(f.genesis)); err != nil {
+		return &InvalidBlockError{Height: 0, Err: err}
+	}
+
+	anchor := f.checkpoints.chainAnchor(chain)
+	authorities := f.GenesisAuthorities()
+	for i := 1; i < len(chain); i++ {
+		if err := f.validateBlock(chain[i], chain[i-1], int64(i) <= anchor); err != nil {
+			return &InvalidBlockError{Height: i, Err: err}
+		}
+
+		if authorities != nil {
+			next, err := authorities.Apply(chain[i].Header)
+			if err != nil {
+				return &InvalidBlockError{Height: i, Err: err}
+			}
+			authorities = next
+		}
+	}
+
+	return nil
+}
+
+func (f *BlockFactory) validateBlock(block, prevBlock *pb.Block, committed bool) error {
+	if err := validateBlock(block, prevBlock); err != nil {
+		return err
+	}
+
+	if err := f.validateHeader(block.Header, block.Hash, committed); err != nil {
+		return err
+	}
+
+	if f.poa != nil {
+		return f.poa.validateBlockTimestamps(block, prevBlock)
+	}
+
+	if f.pow != nil {
+		return f.pow.validateBlockTimestamps(block, prevBlock)
+	}
+
+	return nil
+}
+
+// GenesisBlock returns the genesis block of the chains created by the
+// factory.
+func (f *BlockFactory) GenesisBlock() *pb.Block {
+	return newGenesisBlock(f.genesis)
+}
@@ -0,0 +1,247 @@
+package block
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// testStart is the time of the genesis block of the test chains.
+var testStart = time.Unix(1600000000, 0)
+
+// errAny is expected by test cases that fail with any error.
+var errAny = errors.New("any error")
+
+// checkError fails the test if err is not the expected one.
+func checkError(t *testing.T, err, want error) {
+	t.Helper()
+
+	switch {
+	case want == nil && err != nil:
+		t.Fatalf("unexpected error: %s", err)
+	case want != nil && err == nil:
+		t.Fatalf("expected an error, got nil")
+	case want != nil && want != errAny && !errors.Is(err, want):
+		t.Fatalf("error = %v, want %v", err, want)
+	}
+}
+
+// newTestFactory returns a factory of chains starting from testStart, whose
+// clock is only moved by the test.
+func newTestFactory(options ...FactoryOptions) (*BlockFactory, *FakeClock) {
+	clock := NewFakeClock(testStart)
+	options = append([]FactoryOptions{
+		WithGenesis(&GenesisSettings{ChainID: "test", Timestamp: testStart.Unix()}),
+		WithClock(clock),
+	}, options...)
+
+	return NewBlockFactory(options...), clock
+}
+
+// mineChain mines the provided number of blocks after the genesis one and
+// returns the whole chain. The clock is advanced by the interval before each
+// block.
+func mineChain(f *BlockFactory, clock *FakeClock, blocks int, interval time.Duration) []*pb.Block {
+	chain := []*pb.Block{f.GenesisBlock()}
+	for i := 0; i < blocks; i++ {
+		clock.Advance(interval)
+		chain = append(chain, f.NewBlock([]string{fmt.Sprintf("entry-%d", i)}, chain[len(chain)-1]))
+	}
+
+	return chain
+}
+
+// cloneBlock returns a deep copy of the block, so that it can be altered.
+func cloneBlock(block *pb.Block) *pb.Block {
+	return proto.Clone(block).(*pb.Block)
+}
+
+func TestValidateBlock(t *testing.T) {
+	f, clock := newTestFactory()
+	chain := mineChain(f, clock, 2, 10*time.Second)
+
+	cases := []struct {
+		name    string
+		block   func() *pb.Block
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			block: func() *pb.Block { return chain[2] },
+		},
+		{
+			name:    "nil",
+			block:   func() *pb.Block { return nil },
+			wantErr: true,
+		},
+		{
+			name: "missing header",
+			block: func() *pb.Block {
+				b := cloneBlock(chain[2])
+				b.Header = nil
+				return b
+			},
+			wantErr: true,
+		},
+		{
+			name: "same index",
+			block: func() *pb.Block {
+				b := cloneBlock(chain[2])
+				b.Header.Index = 1
+				return b
+			},
+			wantErr: true,
+		},
+		{
+			name: "index too high",
+			block: func() *pb.Block {
+				b := cloneBlock(chain[2])
+				b.Header.Index = 3
+				return b
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong previous hash",
+			block: func() *pb.Block {
+				b := cloneBlock(chain[2])
+				b.Header.PreviousBlockHash = chain[0].Hash
+				return b
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing previous hash",
+			block: func() *pb.Block {
+				b := cloneBlock(chain[2])
+				b.Header.PreviousBlockHash = nil
+				return b
+			},
+			wantErr: true,
+		},
+		{
+			name: "altered entries",
+			block: func() *pb.Block {
+				b := cloneBlock(chain[2])
+				b.Entries = []string{"altered"}
+				return b
+			},
+			wantErr: true,
+		},
+		{
+			name: "removed entries",
+			block: func() *pb.Block {
+				b := cloneBlock(chain[2])
+				b.Entries = nil
+				return b
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateBlock(c.block(), chain[1])
+			if (err != nil) != c.wantErr {
+				t.Fatalf("validateBlock() error = %v, wantErr %t", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGenesisBlock(t *testing.T) {
+	f, _ := newTestFactory()
+	genesis := f.GenesisBlock()
+
+	cases := []struct {
+		name    string
+		alter   func(b *pb.Block) *pb.Block
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			alter: func(b *pb.Block) *pb.Block { return b },
+		},
+		{
+			name:    "nil",
+			alter:   func(b *pb.Block) *pb.Block { return nil },
+			wantErr: true,
+		},
+		{
+			name:    "missing header",
+			alter:   func(b *pb.Block) *pb.Block { b.Header = nil; return b },
+			wantErr: true,
+		},
+		{
+			name:    "index",
+			alter:   func(b *pb.Block) *pb.Block { b.Header.Index = 1; return b },
+			wantErr: true,
+		},
+		{
+			name:    "timestamp",
+			alter:   func(b *pb.Block) *pb.Block { b.Header.Timestamp++; return b },
+			wantErr: true,
+		},
+		{
+			name:    "difficulty",
+			alter:   func(b *pb.Block) *pb.Block { b.Header.Difficulty++; return b },
+			wantErr: true,
+		},
+		{
+			name:    "previous hash",
+			alter:   func(b *pb.Block) *pb.Block { b.Header.PreviousBlockHash = []byte{1}; return b },
+			wantErr: true,
+		},
+		{
+			name:    "merkle root",
+			alter:   func(b *pb.Block) *pb.Block { b.Header.MerkleRoot = calculateMerkleRoot([]string{"other"}); return b },
+			wantErr: true,
+		},
+		{
+			name:    "hash",
+			alter:   func(b *pb.Block) *pb.Block { b.Hash = calculateHash(&pb.BlockHeader{}); return b },
+			wantErr: true,
+		},
+		{
+			name: "other chain",
+			alter: func(b *pb.Block) *pb.Block {
+				return newGenesisBlock(&GenesisSettings{ChainID: "other", Timestamp: testStart.Unix()})
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateGenesisBlock(c.alter(cloneBlock(genesis)), genesis)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("validateGenesisBlock() error = %v, wantErr %t", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewBlockWithoutSigner(t *testing.T) {
+	validator, _, _ := ed25519.GenerateKey(nil)
+	poa, err := NewProofOfAuthority(&ProofOfAuthoritySettings{
+		Validators: []string{hex.EncodeToString(validator)},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := newTestFactory(WithProofOfAuthority(poa))
+
+	if b := f.NewBlock([]string{"entry"}, f.GenesisBlock()); b != nil {
+		t.Fatal("block was sealed without a signer")
+	}
+	if _, err := f.MineBlock(context.Background(), []string{"entry"}, f.GenesisBlock()); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+}
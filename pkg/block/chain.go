@@ -1,23 +1,105 @@
 package block
 
 import (
-	"bytes"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
 	"sync"
 
+	"github.com/SunSince90/go-naivecoin/pkg/events"
 	"github.com/SunSince90/go-naivecoin/pkg/pb"
 	"github.com/rs/zerolog/log"
 )
 
+var (
+	// ErrEntryNotFound is returned when an entry is not included in any
+	// block of the chain.
+	ErrEntryNotFound = errors.New("entry not found")
+	// ErrBlockNotFound is returned when the requested block is not on the
+	// chain.
+	ErrBlockNotFound = errors.New("block not found")
+)
+
+// InvalidBlockError is returned when a block of a chain is not valid.
+type InvalidBlockError struct {
+	// Height of the first block that is not valid.
+	Height int
+	// Err is the reason why the block is not valid.
+	Err error
+}
+
+func (e *InvalidBlockError) Error() string {
+	return fmt.Sprintf("block at height %d is not valid: %s", e.Height, e.Err)
+}
+
+// Unwrap returns the reason why the block is not valid.
+func (e *InvalidBlockError) Unwrap() error {
+	return e.Err
+}
+
+const (
+	// statsBlockWindow is the number of latest blocks used to calculate the
+	// average block time.
+	statsBlockWindow int = 100
+)
+
+// ChainStats contains statistics about the chain.
+type ChainStats struct {
+	// ChainID identifies the chain, if it has an ID.
+	ChainID string `json:"chainID,omitempty"`
+	// Height is the index of the last block.
+	Height int64 `json:"height"`
+	// CumulativeDifficulty is the sum of the work of all blocks.
+	CumulativeDifficulty *big.Int `json:"cumulativeDifficulty"`
+	// CurrentDifficulty is the difficulty that the next block will be mined
+	// with.
+	CurrentDifficulty int `json:"currentDifficulty"`
+	// AverageBlockTime is the average number of seconds between the latest
+	// blocks.
+	AverageBlockTime float64 `json:"averageBlockTime"`
+	// Validators are the addresses of the validators that seal the next
+	// blocks, with proof of authority.
+	Validators []string `json:"validators,omitempty"`
+	// InTurnBlocks is the number of blocks that were sealed by the validator
+	// in turn, with proof of authority.
+	InTurnBlocks int `json:"inTurnBlocks,omitempty"`
+	// FinalizedHeight is the index of the last finalized block.
+	FinalizedHeight int64 `json:"finalizedHeight"`
+}
+
+// entryLocation tells where an entry is stored in the chain.
+type entryLocation struct {
+	height   int
+	position int
+}
+
 // BlockChain manages a slice of Blocks.
 //
 // TODO: explore persistency on future commits.
 type BlockChain struct {
 	chain                []*pb.Block
+	genesis              *pb.Block
+	chainID              string
 	pow                  *ProofOfWork
 	cumulativeDifficulty *big.Int
-	lock                 sync.Mutex
+	poa                  *ProofOfAuthority
+	// authorities is the validator set after the last block, and
+	// inTurnBlocks the number of blocks sealed in turn, with proof of
+	// authority.
+	authorities  *Authorities
+	inTurnBlocks int
+	// finalized is the index of the last finalized block.
+	finalized   int64
+	checkpoints Checkpoints
+	// entries maps the hex representation of each entry hash to its
+	// location in the chain.
+	entries map[string]entryLocation
+	// heights maps the hex representation of each block hash to its height.
+	heights map[string]int
+	events  *events.Bus
+	metrics Metrics
+	lock    sync.Mutex
 }
 
 // PushBlock validates the the provided block and -- if successful -- pushes it
@@ -35,78 +117,203 @@ func (b *BlockChain) PushBlock(block *pb.Block) error {
 
 	lastBlock := b.chain[len(b.chain)-1]
 	if err := validateBlock(block, lastBlock); err != nil {
+		b.metrics.BlockRejected(RejectedInvalidBlock)
+		return err
+	}
+
+	if err := b.checkpoints.check(block.Header.Index, block.Hash); err != nil {
+		b.metrics.BlockRejected(RejectedCheckpointMismatch)
 		return err
 	}
 
+	if b.pow == nil && b.poa == nil {
+		if err := validateHash(block); err != nil {
+			b.metrics.BlockRejected(RejectedInvalidHash)
+			return err
+		}
+	}
+
 	if b.pow != nil {
-		if err := b.pow.validateBlockHash(block); err != nil {
+		if err := b.pow.validateBlockHash(block, b.pow.difficulty); err != nil {
+			b.metrics.BlockRejected(RejectedInvalidHash)
 			return err
 		}
 		if err := b.pow.validateBlockTimestamps(block, lastBlock); err != nil {
+			b.metrics.BlockRejected(RejectedInvalidTimestamp)
+			return err
+		}
+	}
+
+	if b.poa != nil {
+		// the block can only be committed to by the checkpoint it matches,
+		// as the ones before it are already on the chain.
+		committed := b.checkpoints.matches(block.Header.Index, block.Hash)
+		if err := b.poa.validateSeal(block.Header, block.Hash, committed); err != nil {
+			b.metrics.BlockRejected(RejectedInvalidHash)
 			return err
 		}
+		if err := b.poa.validateBlockTimestamps(block, lastBlock); err != nil {
+			b.metrics.BlockRejected(RejectedInvalidTimestamp)
+			return err
+		}
+
+		authorities, err := b.authorities.Apply(block.Header)
+		if err != nil {
+			b.metrics.BlockRejected(RejectedInvalidSeal)
+			return err
+		}
+
+		if b.authorities.InTurn(block.Header) {
+			b.inTurnBlocks++
+		}
+		b.authorities = authorities
+		b.poa.proposalsDone(authorities)
 	}
 
 	b.chain = append(b.chain, block)
+	b.indexBlock(block)
+	b.events.Publish(events.EventBlockAccepted, block)
 
 	if b.pow != nil {
-		exp := big.NewInt(0).Exp(big.NewInt(2), big.NewInt(block.Difficulty), nil)
+		exp := big.NewInt(0).Exp(big.NewInt(2), big.NewInt(block.Header.Difficulty), nil)
 		b.cumulativeDifficulty = b.cumulativeDifficulty.Add(b.cumulativeDifficulty, exp)
 
-		if block.Index%int64(b.pow.blockGenInt) == 0 {
-			b.pow.adjustDifficulty(b.chain)
-		}
+		b.pow.adjustDifficulty(b.chain)
 	}
 
+	b.updateMetrics()
 	return nil
 }
 
 // ReplaceWith validates the given chain and replaces the one stored inside
-// the blockchain with the the one in the parameter.
+// the blockchain with the the one in the parameter. Chains that do not include
+// the last finalized block are always refused with ErrFinalizedReorg.
+//
+// A chain that includes all the checkpoints is always preferred over one that
+// does not, regardless of their weight.
 func (b *BlockChain) ReplaceWith(newChain []*pb.Block) error {
 
 	b.lock.Lock()
 	defer b.lock.Unlock()
 
+	if err := b.checkFinality(newChain); err != nil {
+		return err
+	}
+
+	reached := b.checkpoints.Reached(int64(len(newChain) - 1))
+	if !reached && b.checkpoints.Reached(int64(len(b.chain)-1)) {
+		return fmt.Errorf("peer's chain does not include all the checkpoints, stopping here")
+	}
+	// the current chain may have been built from a peer that stopped
+	// before the last checkpoint.
+	forced := reached && !b.checkpoints.Reached(int64(len(b.chain)-1))
+
 	if b.pow != nil {
-		cdiff, err := b.pow.validateChain(newChain)
+		cdiff, difficulty, err := b.pow.validateChain(newChain, b.genesis, b.checkpoints)
 		if err != nil {
 			return err
 		}
 
-		switch b.cumulativeDifficulty.Cmp(cdiff) {
-		case 0:
+		switch cmp := b.cumulativeDifficulty.Cmp(cdiff); {
+		case cmp == 0 && !forced:
 			log.Info().Msg("peer's chain is valid and has the same cumulative difficulty as mine: stopping here")
 			return nil
-		case 1:
+		case cmp == 1 && !forced:
 			// This should actually never happen, but let's cover this case anyways
 			return fmt.Errorf("peer's cumulative difficulty is lower than mine, stopping here")
-		default: // case -1
-			b.chain = newChain
+		default: // case -1, or the peer's chain includes all the checkpoints
+			b.cumulativeDifficulty = cdiff
+			b.pow.difficulty = difficulty
+			b.replaceChain(newChain)
 			log.Info().Msg("chain replaced with my peer's chain")
 			return nil
 		}
 	}
 
+	if b.poa != nil {
+		authorities, inTurn, err := b.poa.validateChain(newChain, b.genesis, b.checkpoints)
+		if err != nil {
+			return err
+		}
+
+		// the chain with more blocks sealed in turn is the one that most
+		// validators agree on, regardless of its length.
+		switch {
+		case forced:
+			// the peer's chain includes all the checkpoints.
+		case inTurn < b.inTurnBlocks:
+			return fmt.Errorf("peer's chain has less blocks sealed in turn than mine, stopping here")
+		case inTurn == b.inTurnBlocks && len(newChain) < len(b.chain):
+			return fmt.Errorf("peer's chain has as many blocks sealed in turn as mine but is shorter, stopping here")
+		case inTurn == b.inTurnBlocks && len(newChain) == len(b.chain):
+			log.Info().Msg("peer's chain is valid and has as many blocks sealed in turn as mine: stopping here")
+			return nil
+		}
+
+		b.authorities = authorities
+		b.inTurnBlocks = inTurn
+		b.poa.proposalsDone(authorities)
+		b.replaceChain(newChain)
+		log.Info().Msg("chain replaced with my peer's chain")
+		return nil
+	}
+
 	// For non proof of work
 	// ValidateChain may take a while, so we better check the len-s first
-	if len(newChain) < len(b.chain) {
+	if len(newChain) < len(b.chain) && !forced {
 		return fmt.Errorf("new chain is not longer than the current one")
 	}
 
-	if err := validateChain(newChain); err != nil {
+	if err := validateChain(newChain, b.genesis, b.checkpoints); err != nil {
 		return err
 	}
 
-	if len(newChain) == len(b.chain) {
+	if len(newChain) == len(b.chain) && !forced {
 		log.Info().Msg("peer chain is valid and same length as mine, stopping here...")
 		return nil
 	}
 
+	b.replaceChain(newChain)
 	log.Info().Msg("chain replaced with my peer's chain")
 	return nil
 }
 
+// replaceChain replaces the chain and rebuilds the indexes on it.
+// The caller must hold the lock.
+func (b *BlockChain) replaceChain(newChain []*pb.Block) {
+	b.chain = newChain
+	b.entries = map[string]entryLocation{}
+	b.heights = map[string]int{}
+	for _, block := range newChain {
+		b.indexBlock(block)
+	}
+
+	b.events.Publish(events.EventReorg, newChain[len(newChain)-1])
+	b.metrics.ChainReplaced()
+	b.updateMetrics()
+}
+
+// updateMetrics updates the metrics about the chain.
+// The caller must hold the lock.
+func (b *BlockChain) updateMetrics() {
+	b.metrics.ChainUpdated(b.chain[len(b.chain)-1].Header.Index, b.cumulativeDifficulty)
+	if b.pow != nil {
+		b.metrics.DifficultyUpdated(b.pow.difficulty)
+	}
+}
+
+// indexBlock adds the provided block and its entries to the indexes.
+// The caller must hold the lock.
+func (b *BlockChain) indexBlock(block *pb.Block) {
+	b.heights[hex.EncodeToString(block.Hash)] = int(block.Header.Index)
+	for i, entry := range block.Entries {
+		b.entries[hex.EncodeToString(HashEntry(entry))] = entryLocation{
+			height:   int(block.Header.Index),
+			position: i,
+		}
+	}
+}
+
 // Length returns the current length of the chain.
 // This is mostly used by Kubernetes probes to signal this pod as Ready, as
 // this is also guarded by locks.
@@ -133,22 +340,177 @@ func (b *BlockChain) GetChain() []*pb.Block {
 	return b.chain
 }
 
+// GetBlockByHeight returns the block with the provided height, or
+// ErrBlockNotFound if the chain is not that long.
+func (b *BlockChain) GetBlockByHeight(height int) (*pb.Block, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if height < 0 || height >= len(b.chain) {
+		return nil, ErrBlockNotFound
+	}
+
+	return b.chain[height], nil
+}
+
+// GetBlockByHash returns the block with the provided hash, or
+// ErrBlockNotFound if it is not on the chain.
+func (b *BlockChain) GetBlockByHash(hash []byte) (*pb.Block, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	height, exists := b.heights[hex.EncodeToString(hash)]
+	if !exists {
+		return nil, ErrBlockNotFound
+	}
+
+	return b.chain[height], nil
+}
+
+// GetBlocks returns at most limit blocks starting from the one with the
+// provided height.
+func (b *BlockChain) GetBlocks(from, limit int) []*pb.Block {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if from < 0 || from >= len(b.chain) || limit <= 0 {
+		return []*pb.Block{}
+	}
+
+	to := from + limit
+	if to > len(b.chain) {
+		to = len(b.chain)
+	}
+
+	return b.chain[from:to]
+}
+
+// GetStats returns statistics about the chain.
+func (b *BlockChain) GetStats() *ChainStats {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	lastBlock := b.chain[len(b.chain)-1]
+	stats := &ChainStats{
+		ChainID:              b.chainID,
+		Height:               lastBlock.Header.Index,
+		CumulativeDifficulty: big.NewInt(0).Set(b.cumulativeDifficulty),
+		FinalizedHeight:      b.finalized,
+	}
+
+	if b.pow != nil {
+		stats.CurrentDifficulty = b.pow.difficulty
+	}
+
+	if b.authorities != nil {
+		stats.Validators = b.authorities.Validators()
+		stats.InTurnBlocks = b.inTurnBlocks
+	}
+
+	// the genesis block has a fake timestamp, so it is left out
+	first := len(b.chain) - statsBlockWindow
+	if first < 1 {
+		first = 1
+	}
+	if blocks := len(b.chain) - first; blocks > 1 {
+		elapsed := lastBlock.Header.Timestamp - b.chain[first].Header.Timestamp
+		stats.AverageBlockTime = float64(elapsed) / float64(blocks-1)
+	}
+
+	return stats
+}
+
+// GetHeaders returns at most limit headers, along with their hashes,
+// starting from the block with the provided index.
+func (b *BlockChain) GetHeaders(fromIndex, limit int) []*pb.HashedHeader {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	headers := []*pb.HashedHeader{}
+	for i := fromIndex; i >= 0 && i < len(b.chain) && len(headers) < limit; i++ {
+		headers = append(headers, &pb.HashedHeader{
+			Header: b.chain[i].Header,
+			Hash:   b.chain[i].Hash,
+		})
+	}
+
+	return headers
+}
+
+// GetMerkleProof returns the proof that the entry with the provided hash is
+// included in the chain, or ErrEntryNotFound if it is not.
+func (b *BlockChain) GetMerkleProof(entryHash []byte) (*pb.MerkleProof, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	location, exists := b.entries[hex.EncodeToString(entryHash)]
+	if !exists {
+		return nil, ErrEntryNotFound
+	}
+
+	block := b.chain[location.height]
+	return &pb.MerkleProof{
+		Header:     block.Header,
+		BlockHash:  block.Hash,
+		EntryHash:  entryHash,
+		EntryIndex: int64(location.position),
+		EntryCount: int64(len(block.Entries)),
+		Branch:     calculateMerkleBranch(block.Entries, location.position),
+	}, nil
+}
+
+// FindEntries returns the entries of the chain that match, along with the
+// proofs of their inclusion. The height of the block of each entry is passed
+// to match.
+func (b *BlockChain) FindEntries(match func(entry string, height int64) bool) []*pb.ProvenEntry {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	found := []*pb.ProvenEntry{}
+	for _, block := range b.chain {
+		for i, entry := range block.Entries {
+			if !match(entry, block.Header.Index) {
+				continue
+			}
+
+			found = append(found, &pb.ProvenEntry{
+				Entry: entry,
+				Proof: &pb.MerkleProof{
+					Header:     block.Header,
+					BlockHash:  block.Hash,
+					EntryHash:  HashEntry(entry),
+					EntryIndex: int64(i),
+					EntryCount: int64(len(block.Entries)),
+					Branch:     calculateMerkleBranch(block.Entries, i),
+				},
+			})
+		}
+	}
+
+	return found
+}
+
 // validateChain checks if the provided chain is correct and returns an
 // error if not.
-func validateChain(chain []*pb.Block) error {
-	genesis := newGenesisBlock()
-	if chain[0].Index != 0 ||
-		chain[0].Timestamp != 0 ||
-		len(chain[0].PreviousBlockHash) > 0 ||
-		chain[0].Data != genesisBlockData ||
-		!bytes.Equal(genesis.Hash, chain[0].Hash) {
-		return fmt.Errorf("genesis block is wrong")
+func validateChain(chain []*pb.Block, genesis *pb.Block, checkpoints Checkpoints) error {
+	if len(chain) == 0 {
+		return fmt.Errorf("chain is empty")
+	}
+
+	if err := validateGenesisBlock(chain[0], genesis); err != nil {
+		return err
 	}
 
 	for i := 1; i < len(chain); i++ {
 		if err := validateBlock(chain[i], chain[i-1]); err != nil {
 			return err
 		}
+		if err := validateHash(chain[i]); err != nil {
+			return err
+		}
+		if err := checkpoints.check(chain[i].Header.Index, chain[i].Hash); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -0,0 +1,601 @@
+package block
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+)
+
+// sameChain returns true if the chains have the same blocks.
+func sameChain(a, b []*pb.Block) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !bytes.Equal(a[i].Hash, b[i].Hash) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestValidateChain(t *testing.T) {
+	f, clock := newTestFactory()
+	chain := mineChain(f, clock, 3, 10*time.Second)
+	other, otherClock := newTestFactory(WithGenesis(&GenesisSettings{ChainID: "other", Timestamp: testStart.Unix()}))
+
+	cases := []struct {
+		name        string
+		chain       func() []*pb.Block
+		checkpoints Checkpoints
+		wantErr     error
+	}{
+		{
+			name:  "valid",
+			chain: func() []*pb.Block { return chain },
+		},
+		{
+			name:  "only genesis",
+			chain: func() []*pb.Block { return chain[:1] },
+		},
+		{
+			name:        "matching checkpoint",
+			chain:       func() []*pb.Block { return chain },
+			checkpoints: Checkpoints{2: chain[2].Hash},
+		},
+		{
+			name:    "empty",
+			chain:   func() []*pb.Block { return nil },
+			wantErr: errAny,
+		},
+		{
+			name:    "other genesis",
+			chain:   func() []*pb.Block { return mineChain(other, otherClock, 3, 10*time.Second) },
+			wantErr: errAny,
+		},
+		{
+			name:    "missing genesis",
+			chain:   func() []*pb.Block { return chain[1:] },
+			wantErr: errAny,
+		},
+		{
+			name:    "missing block",
+			chain:   func() []*pb.Block { return []*pb.Block{chain[0], chain[1], chain[3]} },
+			wantErr: errAny,
+		},
+		{
+			name:    "nil block",
+			chain:   func() []*pb.Block { return []*pb.Block{chain[0], nil, chain[2]} },
+			wantErr: errAny,
+		},
+		{
+			name: "altered entries",
+			chain: func() []*pb.Block {
+				altered := append([]*pb.Block{}, chain...)
+				altered[2] = cloneBlock(chain[2])
+				altered[2].Entries = []string{"altered"}
+				return altered
+			},
+			wantErr: errAny,
+		},
+		{
+			name: "hash does not match the header",
+			chain: func() []*pb.Block {
+				altered := append([]*pb.Block{}, chain...)
+				altered[3] = cloneBlock(chain[3])
+				altered[3].Entries = []string{"altered"}
+				altered[3].Header.MerkleRoot = calculateMerkleRoot(altered[3].Entries)
+				return altered
+			},
+			wantErr: errAny,
+		},
+		{
+			name:        "conflicting checkpoint",
+			chain:       func() []*pb.Block { return chain },
+			checkpoints: Checkpoints{2: chain[1].Hash},
+			wantErr:     ErrCheckpointMismatch,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checkError(t, validateChain(c.chain(), chain[0], c.checkpoints), c.wantErr)
+		})
+	}
+}
+
+func TestPushBlock(t *testing.T) {
+	// blocks are mined with a low but real difficulty, so that the hashes
+	// are checked.
+	newFactory := func(checkpoints Checkpoints) (*BlockFactory, *FakeClock) {
+		return newTestFactory(
+			WithProofOfWork(&ProofOfWorkSettings{InitialDifficulty: 2, FixedDifficulty: true}),
+			WithCheckpoints(checkpoints),
+		)
+	}
+	next := func(f *BlockFactory, clock *FakeClock) *pb.Block {
+		clock.Advance(10 * time.Second)
+		return f.NewBlock([]string{"entry"}, f.GenesisBlock())
+	}
+	rehashed := func(f *BlockFactory, clock *FakeClock, difficulty int64) *pb.Block {
+		b := cloneBlock(next(f, clock))
+		b.Header.Difficulty = difficulty
+		b.Hash = f.HashHeader(b.Header)
+		return b
+	}
+
+	cases := []struct {
+		name        string
+		block       func(f *BlockFactory, clock *FakeClock) *pb.Block
+		checkpoints Checkpoints
+		wantErr     error
+	}{
+		{
+			name:  "valid",
+			block: next,
+		},
+		{
+			name:    "nil",
+			block:   func(*BlockFactory, *FakeClock) *pb.Block { return nil },
+			wantErr: errAny,
+		},
+		{
+			name: "missing header",
+			block: func(f *BlockFactory, clock *FakeClock) *pb.Block {
+				return &pb.Block{Hash: next(f, clock).Hash}
+			},
+			wantErr: errAny,
+		},
+		{
+			name: "invalid index",
+			block: func(f *BlockFactory, clock *FakeClock) *pb.Block {
+				b := cloneBlock(next(f, clock))
+				b.Header.Index = 2
+				return b
+			},
+			wantErr: errAny,
+		},
+		{
+			name:        "conflicting checkpoint",
+			block:       next,
+			checkpoints: Checkpoints{1: []byte("other")},
+			wantErr:     ErrCheckpointMismatch,
+		},
+		{
+			name: "hash does not match the header",
+			block: func(f *BlockFactory, clock *FakeClock) *pb.Block {
+				b := cloneBlock(next(f, clock))
+				b.Header.Nonce++
+				return b
+			},
+			wantErr: errAny,
+		},
+		{
+			name: "declared difficulty out of range",
+			block: func(f *BlockFactory, clock *FakeClock) *pb.Block {
+				return rehashed(f, clock, int64(maxDifficulty)+1)
+			},
+			wantErr: errAny,
+		},
+		{
+			name: "declared difficulty not met",
+			block: func(f *BlockFactory, clock *FakeClock) *pb.Block {
+				return rehashed(f, clock, 40)
+			},
+			wantErr: errAny,
+		},
+		{
+			name: "difficulty lower than the current one",
+			block: func(f *BlockFactory, clock *FakeClock) *pb.Block {
+				easy, easyClock := newTestFactory(WithProofOfWork(&ProofOfWorkSettings{InitialDifficulty: 0, FixedDifficulty: true}))
+				return next(easy, easyClock)
+			},
+			wantErr: errAny,
+		},
+		{
+			name: "timestamp in the future",
+			block: func(f *BlockFactory, clock *FakeClock) *pb.Block {
+				clock.Advance(2 * time.Minute)
+				b := next(f, clock)
+				clock.Set(testStart.Add(10 * time.Second))
+				return b
+			},
+			wantErr: errAny,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, clock := newFactory(c.checkpoints)
+			bc := f.NewBlockChain()
+			block := c.block(f, clock)
+
+			err := bc.PushBlock(block)
+			checkError(t, err, c.wantErr)
+
+			switch {
+			case err != nil && bc.Length() != 1:
+				t.Fatalf("chain has %d blocks after a rejected block, want 1", bc.Length())
+			case err == nil && bc.GetLastBlock() != block:
+				t.Fatal("accepted block is not the last one")
+			case err == nil && bc.GetStats().CumulativeDifficulty.Int64() != 4:
+				t.Fatalf("cumulative difficulty = %s, want 4", bc.GetStats().CumulativeDifficulty)
+			}
+		})
+	}
+}
+
+func TestPushBlockWithoutConsensus(t *testing.T) {
+	f, clock := newTestFactory()
+	bc := f.NewBlockChain()
+	clock.Advance(10 * time.Second)
+	valid := f.NewBlock([]string{"entry"}, bc.GetLastBlock())
+
+	// the body and the header are consistent, but the hash is the one of the
+	// original header.
+	altered := cloneBlock(valid)
+	altered.Entries = []string{"altered"}
+	altered.Header.MerkleRoot = calculateMerkleRoot(altered.Entries)
+
+	if err := bc.PushBlock(altered); err == nil {
+		t.Fatal("block whose hash does not match the header was accepted")
+	}
+	if err := bc.PushBlock(valid); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPushBlockAdjustsDifficulty(t *testing.T) {
+	cases := []struct {
+		name     string
+		settings *ProofOfWorkSettings
+		interval time.Duration
+		blocks   int
+		want     int
+	}{
+		{
+			name:     "fast blocks",
+			settings: &ProofOfWorkSettings{InitialDifficulty: 3, BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 5},
+			interval: time.Second,
+			blocks:   10,
+			want:     5,
+		},
+		{
+			name:     "slow blocks",
+			settings: &ProofOfWorkSettings{InitialDifficulty: 3, BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 5},
+			interval: time.Minute,
+			blocks:   10,
+			want:     1,
+		},
+		{
+			name:     "chain shorter than the adjustment interval",
+			settings: &ProofOfWorkSettings{InitialDifficulty: 3, BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 50},
+			interval: time.Second,
+			blocks:   10,
+			want:     3,
+		},
+		{
+			name:     "no block generation interval",
+			settings: &ProofOfWorkSettings{InitialDifficulty: 3, BlockGenerationInterval: 0, DifficultyAdjustmentInterval: 5},
+			interval: time.Minute,
+			blocks:   10,
+			want:     1,
+		},
+		{
+			name:     "no adjustment interval",
+			settings: &ProofOfWorkSettings{InitialDifficulty: 3, BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 0},
+			interval: time.Second,
+			blocks:   10,
+			want:     3,
+		},
+		{
+			name:     "fixed difficulty",
+			settings: &ProofOfWorkSettings{InitialDifficulty: 3, BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 5, FixedDifficulty: true},
+			interval: time.Second,
+			blocks:   10,
+			want:     3,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, clock := newTestFactory(WithProofOfWork(c.settings), WithInstantSeal())
+			bc := f.NewBlockChain()
+			for i := 0; i < c.blocks; i++ {
+				clock.Advance(c.interval)
+				if err := bc.PushBlock(f.NewBlock([]string{"entry"}, bc.GetLastBlock())); err != nil {
+					t.Fatalf("could not push block %d: %s", i+1, err)
+				}
+			}
+
+			if got := bc.GetStats().CurrentDifficulty; got != c.want {
+				t.Fatalf("difficulty = %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+// chainSpec describes how a test chain is mined.
+type chainSpec struct {
+	blocks   int
+	interval time.Duration
+	// difficulty of all blocks, with proof of work.
+	difficulty int
+	// checkpoint, if not 0, is the height of a checkpoint on this chain.
+	checkpoint int64
+}
+
+type replaceCase struct {
+	name string
+	// current is the chain of the node and next the one it is replaced
+	// with. Chains mined with the same interval share their first blocks.
+	current chainSpec
+	next    chainSpec
+	// alter, if not nil, alters the next chain before replacing.
+	alter func(chain []*pb.Block) []*pb.Block
+	// finalize, if not 0, is the height of the current chain that is
+	// finalized before replacing it.
+	finalize     int64
+	wantErr      error
+	wantReplaced bool
+}
+
+func runReplaceCases(t *testing.T, cases []replaceCase, options func(spec chainSpec) []FactoryOptions) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			nextFactory, nextClock := newTestFactory(options(c.next)...)
+			next := mineChain(nextFactory, nextClock, c.next.blocks, c.next.interval)
+
+			currentFactory, currentClock := newTestFactory(options(c.current)...)
+			current := mineChain(currentFactory, currentClock, c.current.blocks, c.current.interval)
+
+			// the node accepts the difficulty of both chains, so that they
+			// are only compared by their weight.
+			node := c.current
+			if c.next.difficulty < node.difficulty {
+				node.difficulty = c.next.difficulty
+			}
+			currentOptions := options(node)
+			switch {
+			case c.current.checkpoint > 0:
+				currentOptions = append(currentOptions, WithCheckpoints(Checkpoints{c.current.checkpoint: current[c.current.checkpoint].Hash}))
+			case c.next.checkpoint > 0:
+				currentOptions = append(currentOptions, WithCheckpoints(Checkpoints{c.next.checkpoint: next[c.next.checkpoint].Hash}))
+			}
+
+			f, clock := newTestFactory(currentOptions...)
+			clock.Advance(time.Hour)
+			bc := f.NewBlockChain()
+			for _, b := range current[1:] {
+				if err := bc.PushBlock(b); err != nil {
+					t.Fatalf("could not push block %d: %s", b.Header.Index, err)
+				}
+			}
+			if c.finalize > 0 {
+				if err := bc.Finalize(c.finalize, current[c.finalize].Hash); err != nil {
+					t.Fatalf("could not finalize block %d: %s", c.finalize, err)
+				}
+			}
+
+			if c.alter != nil {
+				next = c.alter(next)
+			}
+			checkError(t, bc.ReplaceWith(next), c.wantErr)
+
+			after := bc.GetChain()
+			replaced := sameChain(after, next)
+			if !replaced && !sameChain(after, current) {
+				t.Fatal("chain was changed but not replaced")
+			}
+			if replaced != c.wantReplaced {
+				t.Fatalf("replaced = %t, want %t", replaced, c.wantReplaced)
+			}
+		})
+	}
+}
+
+func TestReplaceWith(t *testing.T) {
+	runReplaceCases(t, []replaceCase{
+		{
+			name:         "longer chain",
+			current:      chainSpec{blocks: 2, interval: 10 * time.Second},
+			next:         chainSpec{blocks: 3, interval: 7 * time.Second},
+			wantReplaced: true,
+		},
+		{
+			name:    "same length",
+			current: chainSpec{blocks: 2, interval: 10 * time.Second},
+			next:    chainSpec{blocks: 2, interval: 7 * time.Second},
+		},
+		{
+			name:    "shorter chain",
+			current: chainSpec{blocks: 3, interval: 10 * time.Second},
+			next:    chainSpec{blocks: 2, interval: 7 * time.Second},
+			wantErr: errAny,
+		},
+		{
+			name:    "empty chain",
+			current: chainSpec{blocks: 0, interval: 10 * time.Second},
+			next:    chainSpec{blocks: 0, interval: 7 * time.Second},
+			alter:   func([]*pb.Block) []*pb.Block { return nil },
+			wantErr: errAny,
+		},
+		{
+			name:    "invalid longer chain",
+			current: chainSpec{blocks: 2, interval: 10 * time.Second},
+			next:    chainSpec{blocks: 3, interval: 7 * time.Second},
+			alter: func(chain []*pb.Block) []*pb.Block {
+				chain[2] = cloneBlock(chain[2])
+				chain[2].Entries = []string{"altered"}
+				return chain
+			},
+			wantErr: errAny,
+		},
+		{
+			name:    "other genesis",
+			current: chainSpec{blocks: 2, interval: 10 * time.Second},
+			next:    chainSpec{blocks: 3, interval: 7 * time.Second},
+			alter: func(chain []*pb.Block) []*pb.Block {
+				chain[0] = newGenesisBlock(&GenesisSettings{ChainID: "other", Timestamp: testStart.Unix()})
+				return chain
+			},
+			wantErr: errAny,
+		},
+		{
+			name:    "conflicting checkpoint",
+			current: chainSpec{blocks: 3, interval: 10 * time.Second, checkpoint: 2},
+			next:    chainSpec{blocks: 4, interval: 7 * time.Second},
+			wantErr: ErrCheckpointMismatch,
+		},
+		{
+			name:     "finalized block reorganized",
+			current:  chainSpec{blocks: 3, interval: 10 * time.Second},
+			next:     chainSpec{blocks: 4, interval: 7 * time.Second},
+			finalize: 2,
+			wantErr:  ErrFinalizedReorg,
+		},
+		{
+			name:         "finalized block kept",
+			current:      chainSpec{blocks: 3, interval: 10 * time.Second},
+			next:         chainSpec{blocks: 4, interval: 10 * time.Second},
+			finalize:     2,
+			wantReplaced: true,
+		},
+	}, func(chainSpec) []FactoryOptions { return nil })
+}
+
+func TestReplaceWithProofOfWork(t *testing.T) {
+	runReplaceCases(t, []replaceCase{
+		{
+			name:         "heavier but shorter chain",
+			current:      chainSpec{blocks: 3, interval: 10 * time.Second, difficulty: 1},
+			next:         chainSpec{blocks: 2, interval: 7 * time.Second, difficulty: 3},
+			wantReplaced: true,
+		},
+		{
+			name:    "same cumulative difficulty",
+			current: chainSpec{blocks: 2, interval: 10 * time.Second, difficulty: 2},
+			next:    chainSpec{blocks: 2, interval: 7 * time.Second, difficulty: 2},
+		},
+		{
+			name:    "lighter but longer chain",
+			current: chainSpec{blocks: 2, interval: 10 * time.Second, difficulty: 3},
+			next:    chainSpec{blocks: 3, interval: 7 * time.Second, difficulty: 1},
+			wantErr: errAny,
+		},
+		{
+			name:    "hash does not match the header",
+			current: chainSpec{blocks: 2, interval: 10 * time.Second, difficulty: 1},
+			next:    chainSpec{blocks: 3, interval: 7 * time.Second, difficulty: 3},
+			alter: func(chain []*pb.Block) []*pb.Block {
+				chain[3] = cloneBlock(chain[3])
+				chain[3].Header.Nonce++
+				return chain
+			},
+			wantErr: errAny,
+		},
+		{
+			name:    "declared difficulty out of range",
+			current: chainSpec{blocks: 2, interval: 10 * time.Second, difficulty: 1},
+			next:    chainSpec{blocks: 3, interval: 7 * time.Second, difficulty: 3},
+			alter: func(chain []*pb.Block) []*pb.Block {
+				chain[3] = cloneBlock(chain[3])
+				chain[3].Header.Difficulty = int64(maxDifficulty) + 1
+				chain[3].Hash = (&ProofOfWork{}).hashHeader(chain[3].Header)
+				return chain
+			},
+			wantErr: errAny,
+		},
+		{
+			name:         "lighter chain including the checkpoints",
+			current:      chainSpec{blocks: 2, interval: 10 * time.Second, difficulty: 10},
+			next:         chainSpec{blocks: 3, interval: 7 * time.Second, difficulty: 1, checkpoint: 3},
+			wantReplaced: true,
+		},
+		{
+			name:    "heavier chain missing the checkpoints",
+			current: chainSpec{blocks: 3, interval: 10 * time.Second, difficulty: 1, checkpoint: 2},
+			next:    chainSpec{blocks: 1, interval: 7 * time.Second, difficulty: 10},
+			wantErr: errAny,
+		},
+	}, func(spec chainSpec) []FactoryOptions {
+		return []FactoryOptions{
+			WithProofOfWork(&ProofOfWorkSettings{InitialDifficulty: spec.difficulty, FixedDifficulty: true}),
+			WithInstantSeal(),
+		}
+	})
+}
+
+func TestReplaceWithAdjustedDifficulty(t *testing.T) {
+	settings := &ProofOfWorkSettings{InitialDifficulty: 2, BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 2}
+	// mine pushes the blocks to a chain, so that the difficulty is adjusted,
+	// and returns the chain and the difficulty of its next block.
+	mine := func(blocks int, interval time.Duration) ([]*pb.Block, int) {
+		f, clock := newTestFactory(WithProofOfWork(settings))
+		bc := f.NewBlockChain()
+		for i := 0; i < blocks; i++ {
+			clock.Advance(interval)
+			if err := bc.PushBlock(f.NewBlock([]string{fmt.Sprintf("entry-%d", i)}, bc.GetLastBlock())); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return bc.GetChain(), bc.GetStats().CurrentDifficulty
+	}
+	slow, slowDifficulty := mine(6, time.Minute)
+	fast, fastDifficulty := mine(6, time.Second)
+
+	cases := []struct {
+		name           string
+		chain          func() []*pb.Block
+		wantErr        error
+		wantDifficulty int
+	}{
+		{
+			name:           "difficulty went down",
+			chain:          func() []*pb.Block { return slow },
+			wantDifficulty: slowDifficulty,
+		},
+		{
+			name:           "difficulty went up",
+			chain:          func() []*pb.Block { return fast },
+			wantDifficulty: fastDifficulty,
+		},
+		{
+			name: "block easier than the adjusted difficulty",
+			chain: func() []*pb.Block {
+				// the block is above the minimum difficulty, but below the
+				// one that the blocks before it require.
+				easy, clock := newTestFactory(WithProofOfWork(&ProofOfWorkSettings{InitialDifficulty: 2, FixedDifficulty: true}))
+				clock.Set(time.Unix(fast[6].Header.Timestamp, 0))
+				forged := append([]*pb.Block{}, fast[:6]...)
+				return append(forged, easy.NewBlock([]string{"forged"}, fast[5]))
+			},
+			wantErr: errAny,
+		},
+	}
+
+	if slowDifficulty != 0 || fastDifficulty != 5 {
+		t.Fatalf("difficulties = %d and %d, want 0 and 5", slowDifficulty, fastDifficulty)
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, clock := newTestFactory(WithProofOfWork(settings))
+			clock.Advance(time.Hour)
+			bc := f.NewBlockChain()
+
+			err := bc.ReplaceWith(c.chain())
+			checkError(t, err, c.wantErr)
+
+			if err == nil && bc.GetStats().CurrentDifficulty != c.wantDifficulty {
+				t.Fatalf("difficulty = %d, want %d", bc.GetStats().CurrentDifficulty, c.wantDifficulty)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package block
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+)
+
+var (
+	// ErrCheckpointMismatch is returned when a block does not have the hash
+	// of the checkpoint at its height.
+	ErrCheckpointMismatch = errors.New("block does not match the checkpoint at its height")
+)
+
+// Checkpoints maps the heights of known blocks to their hashes.
+//
+// Chains that conflict with a checkpoint are always refused. Blocks up to the
+// last checkpoint that a chain actually includes are committed to by its
+// hash, so their signatures are not verified: this makes the initial sync of
+// new nodes much faster.
+type Checkpoints map[int64][]byte
+
+// ParseCheckpoints parses the provided checkpoints, e.g. the ones of the
+// consensus settings, whose hashes are in their hex representation.
+func ParseCheckpoints(source map[int64]string) (Checkpoints, error) {
+	checkpoints := Checkpoints{}
+	for height, hash := range source {
+		if height <= 0 {
+			return nil, fmt.Errorf("checkpoint at height %d is not valid: height must be positive", height)
+		}
+
+		decoded, err := hex.DecodeString(hash)
+		if err != nil || len(decoded) == 0 {
+			return nil, fmt.Errorf("checkpoint at height %d is not valid: %q is not a hash", height, hash)
+		}
+		checkpoints[height] = decoded
+	}
+
+	return checkpoints, nil
+}
+
+// Last returns the height of the last checkpoint, or 0 if there are none.
+func (c Checkpoints) Last() int64 {
+	last := int64(0)
+	for height := range c {
+		if height > last {
+			last = height
+		}
+	}
+
+	return last
+}
+
+// Reached returns true if a chain whose last block has the provided height
+// includes all the checkpoints.
+func (c Checkpoints) Reached(height int64) bool {
+	return height >= c.Last()
+}
+
+// check returns ErrCheckpointMismatch if there is a checkpoint at the height
+// of the block and it has a different hash.
+func (c Checkpoints) check(index int64, hash []byte) error {
+	if expected, exists := c[index]; exists && !bytes.Equal(expected, hash) {
+		return ErrCheckpointMismatch
+	}
+
+	return nil
+}
+
+// Anchor returns the height of the last block that matches a checkpoint, or
+// 0 if there is none. The hashes are the ones of the blocks of a chain, in
+// order from the genesis one.
+//
+// The hash of the anchor commits to all the blocks before it, so their
+// signatures do not need to be verified -- as long as the chain is linked,
+// i.e. each block has the hash of its header and of the previous block.
+func (c Checkpoints) Anchor(hashes [][]byte) int64 {
+	anchor := int64(0)
+	for height, hash := range c {
+		if height < int64(len(hashes)) && height > anchor && bytes.Equal(hashes[height], hash) {
+			anchor = height
+		}
+	}
+
+	return anchor
+}
+
+// matches returns true if there is a checkpoint at the height of the block
+// and it has the same hash.
+func (c Checkpoints) matches(index int64, hash []byte) bool {
+	expected, exists := c[index]
+	return exists && bytes.Equal(expected, hash)
+}
+
+// chainAnchor returns the anchor of the provided chain.
+func (c Checkpoints) chainAnchor(chain []*pb.Block) int64 {
+	if len(c) == 0 {
+		return 0
+	}
+
+	hashes := make([][]byte, len(chain))
+	for i, block := range chain {
+		hashes[i] = block.GetHash()
+	}
+
+	return c.Anchor(hashes)
+}
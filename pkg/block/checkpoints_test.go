@@ -0,0 +1,139 @@
+package block
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+)
+
+func TestCheckpointsAnchor(t *testing.T) {
+	hashes := [][]byte{[]byte("0"), []byte("1"), []byte("2"), []byte("3")}
+
+	cases := []struct {
+		name        string
+		checkpoints Checkpoints
+		hashes      [][]byte
+		want        int64
+	}{
+		{
+			name:   "no checkpoints",
+			hashes: hashes,
+			want:   0,
+		},
+		{
+			name:        "last matching checkpoint",
+			checkpoints: Checkpoints{1: []byte("1"), 2: []byte("2")},
+			hashes:      hashes,
+			want:        2,
+		},
+		{
+			name:        "checkpoint after the chain",
+			checkpoints: Checkpoints{1: []byte("1"), 5: []byte("5")},
+			hashes:      hashes,
+			want:        1,
+		},
+		{
+			name:        "conflicting checkpoint",
+			checkpoints: Checkpoints{1: []byte("1"), 3: []byte("other")},
+			hashes:      hashes,
+			want:        1,
+		},
+		{
+			name:        "empty chain",
+			checkpoints: Checkpoints{1: []byte("1")},
+			want:        0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.checkpoints.Anchor(c.hashes); got != c.want {
+				t.Fatalf("Anchor() = %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+// TestCheckpointsCommitSignatures checks that signatures are only skipped for
+// blocks that are committed to by a checkpoint the chain includes.
+func TestCheckpointsCommitSignatures(t *testing.T) {
+	_, signer, _ := ed25519.GenerateKey(nil)
+	newFactory := func(checkpoints Checkpoints) (*BlockFactory, *FakeClock) {
+		poa, err := NewProofOfAuthority(&ProofOfAuthoritySettings{
+			Validators: []string{hex.EncodeToString(signer.Public().(ed25519.PublicKey))},
+		}, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return newTestFactory(WithProofOfAuthority(poa), WithCheckpoints(checkpoints))
+	}
+
+	f, clock := newFactory(nil)
+	chain := mineChain(f, clock, 3, 10*time.Second)
+	// the signature is not part of the hash, so the forged chain still
+	// matches the checkpoints.
+	forged := append([]*pb.Block{}, chain...)
+	for i := 1; i < len(forged); i++ {
+		forged[i] = cloneBlock(chain[i])
+		forged[i].Header.Signature = make([]byte, ed25519.SignatureSize)
+	}
+
+	cases := []struct {
+		name        string
+		chain       []*pb.Block
+		checkpoints Checkpoints
+		wantErr     bool
+	}{
+		{
+			name:        "forged signatures before a matching checkpoint",
+			chain:       forged,
+			checkpoints: Checkpoints{3: chain[3].Hash},
+		},
+		{
+			name:        "forged signatures on a chain short of the checkpoint",
+			chain:       forged[:3],
+			checkpoints: Checkpoints{3: chain[3].Hash},
+			wantErr:     true,
+		},
+		{
+			name:        "forged signature after the checkpoint",
+			chain:       forged,
+			checkpoints: Checkpoints{2: chain[2].Hash},
+			wantErr:     true,
+		},
+		{
+			name:    "forged signatures without checkpoints",
+			chain:   forged,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, clock := newFactory(c.checkpoints)
+			clock.Set(time.Unix(chain[len(chain)-1].Header.Timestamp, 0))
+
+			err := f.ValidateChain(c.chain)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("ValidateChain() error = %v, wantErr %t", err, c.wantErr)
+			}
+
+			bc := f.NewBlockChain()
+			err = bc.ReplaceWith(c.chain)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("ReplaceWith() error = %v, wantErr %t", err, c.wantErr)
+			}
+
+			// blocks pushed one by one are never committed to by a
+			// checkpoint before it.
+			bc = f.NewBlockChain()
+			if err := bc.PushBlock(c.chain[1]); err == nil {
+				t.Fatal("block with a forged signature was pushed")
+			}
+		})
+	}
+}
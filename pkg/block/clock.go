@@ -0,0 +1,63 @@
+package block
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time, which blocks are timestamped with and their
+// timestamps are validated against.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the clock of the machine, which is used unless another one
+// is provided.
+var SystemClock Clock = systemClock{}
+
+// FakeClock is a Clock that only moves when told to, so that blocks mined
+// with it always have the same timestamps. It is meant for tests.
+type FakeClock struct {
+	now  time.Time
+	lock sync.Mutex
+}
+
+// NewFakeClock creates and returns a new instance of the FakeClock, set to the
+// provided time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now:  now,
+		lock: sync.Mutex{},
+	}
+}
+
+// Now returns the time the clock is set to.
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+// Set sets the clock to the provided time.
+func (c *FakeClock) Set(now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = now
+}
+
+// Advance moves the clock forward by the provided duration.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,57 @@
+package block
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConsensusSettings defines the consensus used by the chain.
+type ConsensusSettings struct {
+	ProofOfWork *ProofOfWorkSettings `yaml:"proofOfWork"`
+	// ProofOfAuthority replaces the proof of work, if provided.
+	ProofOfAuthority *ProofOfAuthoritySettings `yaml:"proofOfAuthority"`
+	// Finality enables the finality gadget on top of the consensus, if
+	// provided.
+	Finality *FinalitySettings `yaml:"finality"`
+	// Checkpoints maps the heights of known blocks to the hex
+	// representation of their hashes.
+	Checkpoints map[int64]string `yaml:"checkpoints"`
+}
+
+// LoadConsensusSettings reads the consensus settings from the provided yaml
+// file.
+func LoadConsensusSettings(filePath string) (*ConsensusSettings, error) {
+	csBytes, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO: update this for proof of stake as well
+	var consesusSettings ConsensusSettings
+	if err := yaml.Unmarshal(csBytes, &consesusSettings); err != nil {
+		return nil, err
+	}
+
+	return &consesusSettings, nil
+}
+
+// FactoryOptions returns the options of a block factory that follows the
+// consensus: proof of authority if it is defined, proof of work otherwise.
+//
+// The signer is only used with proof of authority, to seal blocks: it can be
+// nil if the node is not a validator.
+func (c *ConsensusSettings) FactoryOptions(signer ed25519.PrivateKey) ([]FactoryOptions, error) {
+	if c.ProofOfAuthority == nil {
+		return []FactoryOptions{WithProofOfWork(c.ProofOfWork)}, nil
+	}
+
+	poa, err := NewProofOfAuthority(c.ProofOfAuthority, signer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proof of authority settings: %w", err)
+	}
+
+	return []FactoryOptions{WithProofOfAuthority(poa)}, nil
+}
@@ -0,0 +1,427 @@
+package block
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+)
+
+// ProofOfAuthoritySettings defines settings for the Proof of Authority
+// consensus.
+type ProofOfAuthoritySettings struct {
+	// Validators are the hex representations of the public keys of the
+	// validators that can seal blocks at the beginning of the chain, i.e.
+	// the addresses of their wallets. They take turns in this order.
+	Validators []string `yaml:"validators"`
+}
+
+// ProofOfAuthority implements the Proof of Authority consensus: blocks are not
+// mined but sealed by a set of validators that take turns, round-robin by
+// height. A validator can seal a block out of turn, e.g. when the one in turn
+// is offline, but chains with more blocks sealed in turn are preferred.
+//
+// Validators can vote to add or remove a validator by putting their vote in
+// the blocks they seal: the validator set changes as soon as more than half of
+// the validators voted the same.
+type ProofOfAuthority struct {
+	validators [][]byte
+	signer     ed25519.PrivateKey
+	clock      Clock
+
+	// proposals maps the hex representation of each candidate this node
+	// votes for to whether it votes to add it.
+	proposals map[string]bool
+	lock      sync.Mutex
+}
+
+// NewProofOfAuthority creates a new Proof of Authority consensus
+// implementation and returns it to the caller. This should be stored inside a
+// block factory.
+//
+// The signer is the private key used to seal blocks: it can be nil if the node
+// is not a validator, in which case it can only validate blocks.
+func NewProofOfAuthority(settings *ProofOfAuthoritySettings, signer ed25519.PrivateKey) (*ProofOfAuthority, error) {
+	if settings == nil || len(settings.Validators) == 0 {
+		return nil, fmt.Errorf("no validators provided")
+	}
+
+	validators := make([][]byte, 0, len(settings.Validators))
+	seen := map[string]bool{}
+	for _, validator := range settings.Validators {
+		key, err := hex.DecodeString(validator)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("validator %q is not a valid public key", validator)
+		}
+
+		if seen[hex.EncodeToString(key)] {
+			return nil, fmt.Errorf("validator %q is provided more than once", validator)
+		}
+		seen[hex.EncodeToString(key)] = true
+
+		validators = append(validators, key)
+	}
+
+	if signer != nil && len(signer) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("signer is not a valid private key")
+	}
+
+	return &ProofOfAuthority{
+		validators: validators,
+		signer:     signer,
+		clock:      SystemClock,
+		proposals:  map[string]bool{},
+		lock:       sync.Mutex{},
+	}, nil
+}
+
+// Propose makes the node vote to add the candidate to the validator set, if
+// authorize is true, or to remove it otherwise. The vote is put in the blocks
+// sealed by the node until the validator set changes accordingly.
+func (p *ProofOfAuthority) Propose(candidate string, authorize bool) error {
+	key, err := hex.DecodeString(candidate)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("candidate is not a valid public key")
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.proposals[hex.EncodeToString(key)] = authorize
+	return nil
+}
+
+// Discard stops the node from voting for the candidate.
+func (p *ProofOfAuthority) Discard(candidate string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.proposals, strings.ToLower(candidate))
+}
+
+// Proposals returns the candidates that the node votes for, mapped to whether
+// it votes to add them.
+func (p *ProofOfAuthority) Proposals() map[string]bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	proposals := make(map[string]bool, len(p.proposals))
+	for candidate, authorize := range p.proposals {
+		proposals[candidate] = authorize
+	}
+
+	return proposals
+}
+
+// proposalsDone discards the proposals that are already satisfied by the
+// validator set.
+func (p *ProofOfAuthority) proposalsDone(authorities *Authorities) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for candidate, authorize := range p.proposals {
+		if authorities.isValidator(candidate) == authorize {
+			delete(p.proposals, candidate)
+		}
+	}
+}
+
+// seal fills the validator fields of the header, with one of the proposals of
+// the node as vote, and returns its hash.
+func (p *ProofOfAuthority) seal(header *pb.BlockHeader) ([]byte, error) {
+	if p.signer == nil {
+		return nil, fmt.Errorf("this node is not a validator")
+	}
+
+	header.Validator = p.signer.Public().(ed25519.PublicKey)
+
+	p.lock.Lock()
+	candidates := make([]string, 0, len(p.proposals))
+	for candidate := range p.proposals {
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) > 0 {
+		// vote for a different candidate at each height, so that all of
+		// them get a chance.
+		sort.Strings(candidates)
+		candidate := candidates[int(header.Index)%len(candidates)]
+		header.VoteCandidate, _ = hex.DecodeString(candidate)
+		header.VoteAuthorize = p.proposals[candidate]
+	}
+	p.lock.Unlock()
+
+	hash := p.hashHeader(header)
+	header.Signature = ed25519.Sign(p.signer, hash)
+
+	return hash, nil
+}
+
+// hashHeader calculates and returns the hash of a sealed header. The signature
+// is not part of it, as it signs the hash itself.
+func (p *ProofOfAuthority) hashHeader(header *pb.BlockHeader) []byte {
+	data := bytes.Join(
+		[][]byte{
+			func() []byte {
+				bytesVal := make([]byte, 8)
+				binary.LittleEndian.PutUint64(bytesVal, uint64(header.Index))
+				return bytesVal
+			}(),
+			func() []byte {
+				bytesVal := make([]byte, 8)
+				binary.LittleEndian.PutUint64(bytesVal, uint64(header.Timestamp))
+				return bytesVal
+			}(),
+			header.PreviousBlockHash,
+			header.MerkleRoot,
+			header.Validator,
+			header.VoteCandidate,
+			func() []byte {
+				if header.VoteAuthorize {
+					return []byte{1}
+				}
+				return []byte{0}
+			}(),
+		},
+		[]byte{},
+	)
+
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+// validateSeal checks that the hash belongs to the header and that it was
+// signed by the validator the header declares. Whether that is an actual
+// validator depends on the chain, and is checked by Authorities. The
+// signature is not verified if the block is committed to by a checkpoint the
+// chain includes, i.e. it is not after the anchor of the chain.
+func (p *ProofOfAuthority) validateSeal(header *pb.BlockHeader, hash []byte, committed bool) error {
+	if !bytes.Equal(p.hashHeader(header), hash) {
+		return fmt.Errorf("hash does not match the block header")
+	}
+
+	if header.Difficulty != 0 || header.Nonce != 0 {
+		return fmt.Errorf("sealed blocks must not have a difficulty or a nonce")
+	}
+
+	if len(header.Validator) != ed25519.PublicKeySize {
+		return fmt.Errorf("validator is not a valid public key")
+	}
+
+	if len(header.VoteCandidate) != 0 && len(header.VoteCandidate) != ed25519.PublicKeySize {
+		return fmt.Errorf("vote candidate is not a valid public key")
+	}
+
+	if committed {
+		return nil
+	}
+
+	if !ed25519.Verify(header.Validator, hash, header.Signature) {
+		return fmt.Errorf("signature is not valid")
+	}
+
+	return nil
+}
+
+func (p *ProofOfAuthority) validateBlockTimestamps(newBlock, prevBlock *pb.Block) error {
+	if newBlock.Header.Timestamp > p.clock.Now().Unix()+60 ||
+		newBlock.Header.Timestamp < prevBlock.Header.Timestamp {
+		return fmt.Errorf("timestamp is not valid")
+	}
+
+	return nil
+}
+
+// genesisAuthorities returns the validator set at the beginning of the chain.
+func (p *ProofOfAuthority) genesisAuthorities() *Authorities {
+	return &Authorities{
+		validators: p.validators,
+		votes:      map[vote]map[string]bool{},
+		lastSealed: map[string]int64{},
+	}
+}
+
+// validateChain checks the provided chain and returns the validator set after
+// its last block, along with the number of blocks sealed in turn.
+func (p *ProofOfAuthority) validateChain(chain []*pb.Block, genesis *pb.Block, checkpoints Checkpoints) (*Authorities, int, error) {
+	if len(chain) == 0 {
+		return nil, 0, fmt.Errorf("chain is empty")
+	}
+
+	if err := validateGenesisBlock(chain[0], genesis); err != nil {
+		return nil, 0, err
+	}
+
+	// blocks are linked below, so the anchor commits to the ones before it.
+	anchor := checkpoints.chainAnchor(chain)
+	authorities, inTurn := p.genesisAuthorities(), 0
+	for i := 1; i < len(chain); i++ {
+		if err := validateBlock(chain[i], chain[i-1]); err != nil {
+			return nil, 0, err
+		}
+		if err := checkpoints.check(chain[i].Header.Index, chain[i].Hash); err != nil {
+			return nil, 0, err
+		}
+		if err := p.validateSeal(chain[i].Header, chain[i].Hash, int64(i) <= anchor); err != nil {
+			return nil, 0, err
+		}
+		if err := p.validateBlockTimestamps(chain[i], chain[i-1]); err != nil {
+			return nil, 0, err
+		}
+
+		if authorities.InTurn(chain[i].Header) {
+			inTurn++
+		}
+
+		next, err := authorities.Apply(chain[i].Header)
+		if err != nil {
+			return nil, 0, err
+		}
+		authorities = next
+	}
+
+	return authorities, inTurn, nil
+}
+
+// vote is a vote to add or remove a candidate from the validator set.
+type vote struct {
+	candidate string
+	authorize bool
+}
+
+// Authorities is the validator set of a chain after one of its blocks, along
+// with the votes to change it that did not pass yet. It is never modified:
+// applying a block returns a new one.
+type Authorities struct {
+	validators [][]byte
+	// votes maps each vote to the validators that cast it.
+	votes map[vote]map[string]bool
+	// lastSealed maps each validator to the height of the last block it
+	// sealed.
+	lastSealed map[string]int64
+}
+
+// Validators returns the hex representations of the public keys of the
+// validators, in the order they take turns.
+func (a *Authorities) Validators() []string {
+	validators := make([]string, len(a.validators))
+	for i, validator := range a.validators {
+		validators[i] = hex.EncodeToString(validator)
+	}
+
+	return validators
+}
+
+// InTurn returns true if the header was sealed by the validator whose turn it
+// is at its height.
+func (a *Authorities) InTurn(header *pb.BlockHeader) bool {
+	inTurn := a.validators[int(header.Index%int64(len(a.validators)))]
+	return bytes.Equal(inTurn, header.Validator)
+}
+
+func (a *Authorities) isValidator(key string) bool {
+	for _, validator := range a.validators {
+		if hex.EncodeToString(validator) == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply checks that the header, whose signature must have already been
+// verified, was sealed by a validator that is allowed to seal it, and returns
+// the validator set after it.
+//
+// A validator can only seal one of any floor(N/2)+1 consecutive blocks, where
+// N is the number of validators, so that no minority can take over the chain
+// by sealing out of turn.
+func (a *Authorities) Apply(header *pb.BlockHeader) (*Authorities, error) {
+	sealer := hex.EncodeToString(header.Validator)
+	if !a.isValidator(sealer) {
+		return nil, fmt.Errorf("block was not sealed by a validator")
+	}
+
+	if last, exists := a.lastSealed[sealer]; exists &&
+		header.Index-last <= int64(len(a.validators)/2) {
+		return nil, fmt.Errorf("validator sealed another block too recently")
+	}
+
+	next := &Authorities{
+		validators: a.validators,
+		votes:      make(map[vote]map[string]bool, len(a.votes)),
+		lastSealed: make(map[string]int64, len(a.lastSealed)+1),
+	}
+	for v, voters := range a.votes {
+		next.votes[v] = voters
+	}
+	for validator, height := range a.lastSealed {
+		next.lastSealed[validator] = height
+	}
+	next.lastSealed[sealer] = header.Index
+
+	if len(header.VoteCandidate) == 0 {
+		return next, nil
+	}
+
+	// Votes that would not change anything, like adding a validator that is
+	// already in the set, are ignored rather than rejected: the vote may
+	// have passed while the block was being sealed.
+	v := vote{candidate: hex.EncodeToString(header.VoteCandidate), authorize: header.VoteAuthorize}
+	if next.isValidator(v.candidate) == v.authorize ||
+		(!v.authorize && len(next.validators) == 1) {
+		return next, nil
+	}
+
+	voters := make(map[string]bool, len(next.votes[v])+1)
+	for voter := range next.votes[v] {
+		voters[voter] = true
+	}
+	voters[sealer] = true
+	next.votes[v] = voters
+
+	if len(voters) <= len(next.validators)/2 {
+		return next, nil
+	}
+
+	// the vote passed: votes about the candidate are not needed anymore.
+	delete(next.votes, vote{candidate: v.candidate, authorize: true})
+	delete(next.votes, vote{candidate: v.candidate, authorize: false})
+
+	if v.authorize {
+		next.validators = append(append([][]byte{}, next.validators...), header.VoteCandidate)
+		return next, nil
+	}
+
+	validators := make([][]byte, 0, len(next.validators)-1)
+	for _, validator := range next.validators {
+		if hex.EncodeToString(validator) != v.candidate {
+			validators = append(validators, validator)
+		}
+	}
+	next.validators = validators
+	delete(next.lastSealed, v.candidate)
+
+	// the votes of the removed validator do not count anymore.
+	for other, voters := range next.votes {
+		if !voters[v.candidate] {
+			continue
+		}
+
+		remaining := map[string]bool{}
+		for voter := range voters {
+			if voter != v.candidate {
+				remaining[voter] = true
+			}
+		}
+		next.votes[other] = remaining
+	}
+
+	return next, nil
+}
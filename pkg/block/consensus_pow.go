@@ -2,17 +2,26 @@ package block
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
 	"math"
 	"math/big"
-	"time"
 
 	"github.com/SunSince90/go-naivecoin/pkg/pb"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// cancelCheckInterval is how many nonces are tried before checking
+	// whether mining was cancelled.
+	cancelCheckInterval int64 = 1 << 16
+	// maxDifficulty is the highest difficulty, where all the 64 hexadecimal
+	// digits of the hash must be zero.
+	maxDifficulty int = 64
+)
+
 // ProofOfWorkSettings defines settings for the Proof of Work consensus.
 type ProofOfWorkSettings struct {
 	// InitialDifficulty is the difficulty set when starting the program.
@@ -23,13 +32,21 @@ type ProofOfWorkSettings struct {
 	// DifficultyAdjustmentInterval defines how many blocks should the
 	// mining difficulty should be re-adjusted.
 	DifficultyAdjustmentInterval int `yaml:"difficultyAdjustmentInterval"`
+	// FixedDifficulty disables the adjustment of the difficulty, so that all
+	// blocks are mined with the initial one.
+	FixedDifficulty bool `yaml:"fixedDifficulty"`
 }
 
 // ProofOfWork implements the Proof of Work consensus.
 type ProofOfWork struct {
-	difficulty  int
-	blockGenInt int
-	diffAdjInt  int
+	difficulty        int
+	initialDifficulty int
+	blockGenInt       int
+	diffAdjInt        int
+	fixedDifficulty   bool
+	clock             Clock
+	// instantSeal skips the search of a hash that meets the difficulty.
+	instantSeal bool
 }
 
 // NewProofOfWork creates a new Proof of Work consensus implementation and
@@ -61,13 +78,16 @@ func NewProofOfWork(settings *ProofOfWorkSettings) *ProofOfWork {
 	}()
 
 	return &ProofOfWork{
-		difficulty:  difficulty,
-		blockGenInt: blockGenInt,
-		diffAdjInt:  diffAdjInt,
+		difficulty:        difficulty,
+		initialDifficulty: difficulty,
+		blockGenInt:       blockGenInt,
+		diffAdjInt:        diffAdjInt,
+		fixedDifficulty:   settings != nil && settings.FixedDifficulty,
+		clock:             SystemClock,
 	}
 }
 
-func (p *ProofOfWork) calculateHash(block *pb.Block) (int64, int64, []byte) {
+func (p *ProofOfWork) calculateHash(ctx context.Context, header *pb.BlockHeader) (int64, int64, []byte, error) {
 	target := big.NewInt(1)
 	targetBits := p.difficulty * 4 // remember that it's hexadecimal representation
 
@@ -75,8 +95,20 @@ func (p *ProofOfWork) calculateHash(block *pb.Block) (int64, int64, []byte) {
 	var nonce int64 = 0
 	var hash [32]byte
 
+	// the difficulty is part of the header, so it must be set before mining
+	header.Difficulty = int64(p.difficulty)
+
+	if p.instantSeal {
+		hash = sha256.Sum256(p.prepareData(header, nonce))
+		return int64(p.difficulty), nonce, hash[:], nil
+	}
+
 	for nonce < math.MaxInt64 {
-		data := p.prepareData(block, nonce)
+		if nonce%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return 0, 0, nil, ctx.Err()
+		}
+
+		data := p.prepareData(header, nonce)
 		hash = sha256.Sum256(data)
 
 		if big.NewInt(0).SetBytes(hash[:]).Cmp(target) == -1 {
@@ -86,27 +118,27 @@ func (p *ProofOfWork) calculateHash(block *pb.Block) (int64, int64, []byte) {
 		nonce++
 	}
 
-	return int64(p.difficulty), int64(nonce), hash[:]
+	return int64(p.difficulty), int64(nonce), hash[:], nil
 }
 
-func (p *ProofOfWork) prepareData(block *pb.Block, nonce int64) []byte {
+func (p *ProofOfWork) prepareData(header *pb.BlockHeader, nonce int64) []byte {
 	data := bytes.Join(
 		[][]byte{
 			func() []byte {
 				bytesVal := make([]byte, 8)
-				binary.LittleEndian.PutUint64(bytesVal, uint64(block.Index))
+				binary.LittleEndian.PutUint64(bytesVal, uint64(header.Index))
 				return bytesVal
 			}(),
 			func() []byte {
 				bytesVal := make([]byte, 8)
-				binary.LittleEndian.PutUint64(bytesVal, uint64(block.Timestamp))
+				binary.LittleEndian.PutUint64(bytesVal, uint64(header.Timestamp))
 				return bytesVal
 			}(),
-			block.PreviousBlockHash,
-			[]byte(block.Data),
+			header.PreviousBlockHash,
+			header.MerkleRoot,
 			func() []byte {
 				bytesVal := make([]byte, 8)
-				binary.LittleEndian.PutUint64(bytesVal, uint64(p.difficulty))
+				binary.LittleEndian.PutUint64(bytesVal, uint64(header.Difficulty))
 				return bytesVal
 			}(),
 			func() []byte {
@@ -121,16 +153,50 @@ func (p *ProofOfWork) prepareData(block *pb.Block, nonce int64) []byte {
 	return data
 }
 
-func (p *ProofOfWork) validateBlockHash(block *pb.Block) error {
-	target := big.NewInt(1)
-	targetBits := p.difficulty * 4
+// validateBlockHash checks that the hash of the block is the one of its
+// header and that it satisfies the difficulty declared in it, which cannot be
+// lower than the provided one, i.e. the difficulty that the chain before the
+// block requires.
+func (p *ProofOfWork) validateBlockHash(block *pb.Block, difficulty int) error {
+	hash := p.hashHeader(block.Header)
+	if !bytes.Equal(hash, block.Hash) {
+		return fmt.Errorf("hash does not match the block header")
+	}
 
-	target.Lsh(target, uint(256-targetBits))
+	if block.Header.Difficulty < int64(difficulty) {
+		return fmt.Errorf("difficulty is lower than the expected one at height %d", block.Header.Index)
+	}
+
+	// the difficulty declared in the header is added to the cumulative one,
+	// so the hash must satisfy it.
+	return p.validateHeaderTarget(block.Header, hash)
+}
+
+// hashHeader calculates and returns the hash of an already mined header.
+func (p *ProofOfWork) hashHeader(header *pb.BlockHeader) []byte {
+	hash := sha256.Sum256(p.prepareData(header, header.Nonce))
+	return hash[:]
+}
+
+// validateHeaderTarget checks that the hash satisfies the difficulty declared
+// in the header, and that it is not lower than the minimum one at its height.
+func (p *ProofOfWork) validateHeaderTarget(header *pb.BlockHeader, hash []byte) error {
+	if header.Difficulty < 0 || header.Difficulty > int64(maxDifficulty) {
+		return fmt.Errorf("difficulty is not valid")
+	}
+
+	if header.Difficulty < p.minDifficulty(header.Index) {
+		return fmt.Errorf("difficulty is lower than the minimum one at height %d", header.Index)
+	}
+
+	if p.instantSeal {
+		return nil
+	}
 
-	data := p.prepareData(block, block.Nonce)
-	hash := sha256.Sum256(data)
+	target := big.NewInt(1)
+	target.Lsh(target, uint(256-header.Difficulty*4))
 
-	if big.NewInt(0).SetBytes(hash[:]).Cmp(target) != -1 {
+	if big.NewInt(0).SetBytes(hash).Cmp(target) != -1 {
 		return fmt.Errorf("hash is not valid")
 	}
 
@@ -138,56 +204,113 @@ func (p *ProofOfWork) validateBlockHash(block *pb.Block) error {
 }
 
 func (p *ProofOfWork) validateBlockTimestamps(newBlock, prevBlock *pb.Block) error {
-	now := time.Now().Unix()
+	now := p.clock.Now().Unix()
 
-	if newBlock.Timestamp > now+60 /*|| prevBlock.Timestamp < prevBlock.Timestamp+60*/ {
+	if newBlock.Header.Timestamp > now+60 /*|| prevBlock.Timestamp < prevBlock.Timestamp+60*/ {
 		return fmt.Errorf("timestamp is not valid")
 	}
 
 	return nil
 }
 
-func (p *ProofOfWork) validateChain(chain []*pb.Block) (*big.Int, error) {
-	genesis := newGenesisBlock()
-	if chain[0].Index != 0 ||
-		chain[0].Timestamp != 0 ||
-		len(chain[0].PreviousBlockHash) > 0 ||
-		chain[0].Data != genesisBlockData ||
-		!bytes.Equal(genesis.Hash, chain[0].Hash) {
-		return nil, fmt.Errorf("genesis block is wrong")
+// validateChain checks the chain and returns its cumulative difficulty and
+// the difficulty of the block that would follow it.
+//
+// The difficulty is recomputed along the chain from the initial one, rather
+// than taken from the current chain of the node, so that each block is
+// checked against the difficulty required at its height.
+func (p *ProofOfWork) validateChain(chain []*pb.Block, genesis *pb.Block, checkpoints Checkpoints) (*big.Int, int, error) {
+	if len(chain) == 0 {
+		return nil, 0, fmt.Errorf("chain is empty")
+	}
+
+	if err := validateGenesisBlock(chain[0], genesis); err != nil {
+		return nil, 0, err
 	}
 
 	cumulativeDifficulty := big.NewInt(0)
+	difficulty := p.initialDifficulty
 	for i := 1; i < len(chain); i++ {
 		if err := validateBlock(chain[i], chain[i-1]); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
-		if err := p.validateBlockHash(chain[i]); err != nil {
-			return nil, err
+		if err := checkpoints.check(chain[i].Header.Index, chain[i].Hash); err != nil {
+			return nil, 0, err
+		}
+		if err := p.validateBlockHash(chain[i], difficulty); err != nil {
+			return nil, 0, err
 		}
 		if err := p.validateBlockTimestamps(chain[i], chain[i-1]); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
-		exp := big.NewInt(0).Exp(big.NewInt(2), big.NewInt(chain[i].Difficulty), nil)
+		exp := big.NewInt(0).Exp(big.NewInt(2), big.NewInt(chain[i].Header.Difficulty), nil)
 		cumulativeDifficulty = big.NewInt(0).Add(cumulativeDifficulty, exp)
+		difficulty = p.nextDifficulty(difficulty, chain[:i+1])
 	}
 
-	return cumulativeDifficulty, nil
+	return cumulativeDifficulty, difficulty, nil
+}
 
+// minDifficulty returns the lowest difficulty a block at the provided height
+// can have according to the settings: the difficulty starts from the initial
+// one and is decreased at most by one every diffAdjInt blocks.
+//
+// Without this, headers could declare no difficulty at all and add chain work
+// for free.
+func (p *ProofOfWork) minDifficulty(index int64) int64 {
+	if p.fixedDifficulty || p.diffAdjInt <= 0 {
+		return int64(p.initialDifficulty)
+	}
+
+	min := int64(p.initialDifficulty) - index/int64(p.diffAdjInt)
+	if min < 0 {
+		return 0
+	}
+
+	return min
 }
 
+// adjustDifficulty updates the difficulty after the last block of the chain
+// was added, as explained in nextDifficulty.
 func (p *ProofOfWork) adjustDifficulty(chain []*pb.Block) {
-	prevAdjBlock := chain[len(chain)-p.blockGenInt]
-	expectedTime := p.blockGenInt * p.diffAdjInt
-	lastBlock := chain[len(chain)-1]
-
-	switch diff := lastBlock.Timestamp - prevAdjBlock.Timestamp; {
-	case diff < int64(expectedTime)/2:
+	switch next := p.nextDifficulty(p.difficulty, chain); {
+	case next > p.difficulty:
 		log.Info().Msg("incrementing difficulty by one")
-		p.difficulty++
-	case diff > int64(expectedTime)*2:
+		p.difficulty = next
+	case next < p.difficulty:
 		log.Info().Msg("decreasing difficulty by one")
-		p.difficulty--
+		p.difficulty = next
 	}
 }
+
+// nextDifficulty returns the difficulty of the block following the last one
+// of the chain, given the difficulty that the last one required.
+//
+// The difficulty is updated every diffAdjInt blocks, so that a block keeps
+// being mined every blockGenInt seconds: it is increased if the last
+// diffAdjInt blocks took less than half the expected time and decreased if
+// they took more than twice as much. Nothing is done until diffAdjInt blocks
+// follow the genesis one.
+func (p *ProofOfWork) nextDifficulty(difficulty int, chain []*pb.Block) int {
+	if p.fixedDifficulty || p.diffAdjInt <= 0 || len(chain) <= p.diffAdjInt {
+		return difficulty
+	}
+
+	lastBlock := chain[len(chain)-1]
+	if lastBlock.Header.Index%int64(p.diffAdjInt) != 0 {
+		return difficulty
+	}
+
+	prevAdjBlock := chain[len(chain)-p.diffAdjInt]
+	expectedTime := int64(p.blockGenInt * p.diffAdjInt)
+
+	switch diff := lastBlock.Header.Timestamp - prevAdjBlock.Header.Timestamp; {
+	case diff < expectedTime/2 && difficulty < maxDifficulty:
+		return difficulty + 1
+	case diff > expectedTime*2 && difficulty > 0:
+		return difficulty - 1
+	}
+
+	return difficulty
+}
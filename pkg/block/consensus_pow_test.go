@@ -0,0 +1,362 @@
+package block
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+)
+
+func TestProofOfWorkValidateChain(t *testing.T) {
+	f, clock := newTestFactory(WithProofOfWork(&ProofOfWorkSettings{InitialDifficulty: 2, FixedDifficulty: true}))
+	chain := mineChain(f, clock, 3, 10*time.Second)
+	newEngine := func(difficulty int, now time.Time) *ProofOfWork {
+		p := NewProofOfWork(&ProofOfWorkSettings{InitialDifficulty: difficulty})
+		p.clock = NewFakeClock(now)
+		return p
+	}
+	alterLast := func(alter func(b *pb.Block)) func() []*pb.Block {
+		return func() []*pb.Block {
+			altered := append([]*pb.Block{}, chain...)
+			altered[3] = cloneBlock(chain[3])
+			alter(altered[3])
+			return altered
+		}
+	}
+
+	cases := []struct {
+		name        string
+		engine      *ProofOfWork
+		chain       func() []*pb.Block
+		checkpoints Checkpoints
+		wantErr     error
+		// want is the cumulative difficulty of a valid chain.
+		want int64
+	}{
+		{
+			name:   "valid",
+			engine: newEngine(2, clock.Now()),
+			chain:  func() []*pb.Block { return chain },
+			want:   3 * 4,
+		},
+		{
+			name:   "only genesis",
+			engine: newEngine(2, clock.Now()),
+			chain:  func() []*pb.Block { return chain[:1] },
+			want:   0,
+		},
+		{
+			name:        "matching checkpoint",
+			engine:      newEngine(2, clock.Now()),
+			chain:       func() []*pb.Block { return chain },
+			checkpoints: Checkpoints{3: chain[3].Hash},
+			want:        3 * 4,
+		},
+		{
+			name:    "empty",
+			engine:  newEngine(2, clock.Now()),
+			chain:   func() []*pb.Block { return nil },
+			wantErr: errAny,
+		},
+		{
+			name:    "missing genesis",
+			engine:  newEngine(2, clock.Now()),
+			chain:   func() []*pb.Block { return chain[1:] },
+			wantErr: errAny,
+		},
+		{
+			name:    "missing block",
+			engine:  newEngine(2, clock.Now()),
+			chain:   func() []*pb.Block { return []*pb.Block{chain[0], chain[2], chain[3]} },
+			wantErr: errAny,
+		},
+		{
+			name:        "conflicting checkpoint",
+			engine:      newEngine(2, clock.Now()),
+			chain:       func() []*pb.Block { return chain },
+			checkpoints: Checkpoints{3: chain[2].Hash},
+			wantErr:     ErrCheckpointMismatch,
+		},
+		{
+			name:    "hash does not match the header",
+			engine:  newEngine(2, clock.Now()),
+			chain:   alterLast(func(b *pb.Block) { b.Header.Timestamp++ }),
+			wantErr: errAny,
+		},
+		{
+			name:   "declared difficulty out of range",
+			engine: newEngine(2, clock.Now()),
+			chain: alterLast(func(b *pb.Block) {
+				b.Header.Difficulty = -1
+				b.Hash = f.HashHeader(b.Header)
+			}),
+			wantErr: errAny,
+		},
+		{
+			name:   "declared difficulty not met",
+			engine: newEngine(2, clock.Now()),
+			chain: alterLast(func(b *pb.Block) {
+				b.Header.Difficulty = 40
+				b.Hash = f.HashHeader(b.Header)
+			}),
+			wantErr: errAny,
+		},
+		{
+			name:    "initial difficulty not met",
+			engine:  newEngine(8, clock.Now()),
+			chain:   func() []*pb.Block { return chain },
+			wantErr: errAny,
+		},
+		{
+			name:    "timestamp in the future",
+			engine:  newEngine(2, testStart.Add(-time.Minute)),
+			chain:   func() []*pb.Block { return chain },
+			wantErr: errAny,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cdiff, _, err := c.engine.validateChain(c.chain(), chain[0], c.checkpoints)
+			checkError(t, err, c.wantErr)
+
+			if err == nil && cdiff.Int64() != c.want {
+				t.Fatalf("cumulative difficulty = %s, want %d", cdiff, c.want)
+			}
+		})
+	}
+}
+
+func TestAdjustDifficulty(t *testing.T) {
+	// timedChain returns a chain whose blocks have the provided timestamps,
+	// in seconds, after the genesis block.
+	timedChain := func(timestamps ...int64) []*pb.Block {
+		chain := []*pb.Block{{Header: &pb.BlockHeader{}}}
+		for i, ts := range timestamps {
+			chain = append(chain, &pb.Block{Header: &pb.BlockHeader{Index: int64(i + 1), Timestamp: ts}})
+		}
+
+		return chain
+	}
+
+	cases := []struct {
+		name       string
+		settings   *ProofOfWorkSettings
+		difficulty int
+		chain      []*pb.Block
+		want       int
+	}{
+		{
+			name:       "fast blocks",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 4},
+			difficulty: 3,
+			chain:      timedChain(10, 11, 12, 13),
+			want:       4,
+		},
+		{
+			name:       "slow blocks",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 4},
+			difficulty: 3,
+			chain:      timedChain(0, 100, 200, 300),
+			want:       2,
+		},
+		{
+			name:       "blocks on time",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 4},
+			difficulty: 3,
+			chain:      timedChain(0, 10, 20, 30),
+			want:       3,
+		},
+		{
+			name:       "exactly half the expected time",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 4},
+			difficulty: 3,
+			chain:      timedChain(0, 10, 15, 20),
+			want:       3,
+		},
+		{
+			name:       "exactly twice the expected time",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 4},
+			difficulty: 3,
+			chain:      timedChain(0, 30, 60, 80),
+			want:       3,
+		},
+		{
+			name:       "not at the adjustment interval",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 4},
+			difficulty: 3,
+			chain:      timedChain(10, 11, 12, 13, 14),
+			want:       3,
+		},
+		{
+			name:       "chain shorter than the adjustment interval",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 10},
+			difficulty: 3,
+			chain:      timedChain(10, 11),
+			want:       3,
+		},
+		{
+			name:       "only genesis",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 1},
+			difficulty: 3,
+			chain:      timedChain(),
+			want:       3,
+		},
+		{
+			name:       "block generation interval longer than the chain",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 100, DifficultyAdjustmentInterval: 2},
+			difficulty: 3,
+			chain:      timedChain(10, 11),
+			want:       4,
+		},
+		{
+			name:       "no adjustment interval",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 0},
+			difficulty: 3,
+			chain:      timedChain(10, 11, 12, 13),
+			want:       3,
+		},
+		{
+			name:       "fixed difficulty",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 4, FixedDifficulty: true},
+			difficulty: 3,
+			chain:      timedChain(10, 11, 12, 13),
+			want:       3,
+		},
+		{
+			name:       "minimum difficulty",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 4},
+			difficulty: 0,
+			chain:      timedChain(0, 100, 200, 300),
+			want:       0,
+		},
+		{
+			name:       "maximum difficulty",
+			settings:   &ProofOfWorkSettings{BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 4},
+			difficulty: maxDifficulty,
+			chain:      timedChain(10, 11, 12, 13),
+			want:       maxDifficulty,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := NewProofOfWork(c.settings)
+			p.difficulty = c.difficulty
+
+			p.adjustDifficulty(c.chain)
+			if p.difficulty != c.want {
+				t.Fatalf("difficulty = %d, want %d", p.difficulty, c.want)
+			}
+		})
+	}
+}
+
+func TestMinDifficulty(t *testing.T) {
+	cases := []struct {
+		name     string
+		settings *ProofOfWorkSettings
+		index    int64
+		want     int64
+	}{
+		{
+			name:     "first block",
+			settings: &ProofOfWorkSettings{InitialDifficulty: 3, DifficultyAdjustmentInterval: 10},
+			index:    1,
+			want:     3,
+		},
+		{
+			name:     "after some adjustments",
+			settings: &ProofOfWorkSettings{InitialDifficulty: 3, DifficultyAdjustmentInterval: 10},
+			index:    25,
+			want:     1,
+		},
+		{
+			name:     "after many adjustments",
+			settings: &ProofOfWorkSettings{InitialDifficulty: 3, DifficultyAdjustmentInterval: 10},
+			index:    1000,
+			want:     0,
+		},
+		{
+			name:     "fixed difficulty",
+			settings: &ProofOfWorkSettings{InitialDifficulty: 3, DifficultyAdjustmentInterval: 10, FixedDifficulty: true},
+			index:    1000,
+			want:     3,
+		},
+		{
+			name:     "no adjustment interval",
+			settings: &ProofOfWorkSettings{InitialDifficulty: 3, DifficultyAdjustmentInterval: 0},
+			index:    1000,
+			want:     3,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := NewProofOfWork(c.settings).minDifficulty(c.index); got != c.want {
+				t.Fatalf("minDifficulty() = %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestGenesisDifficulty(t *testing.T) {
+	// the genesis difficulty is lower than the one of the settings, which it
+	// replaces.
+	f, clock := newTestFactory(
+		WithProofOfWork(&ProofOfWorkSettings{InitialDifficulty: 3, DifficultyAdjustmentInterval: 10}),
+		WithGenesis(&GenesisSettings{ChainID: "test", Timestamp: testStart.Unix(), Difficulty: 1}),
+	)
+	chain := mineChain(f, clock, 3, 10*time.Second)
+
+	bc := f.NewBlockChain()
+	for _, b := range chain[1:] {
+		if b.Header.Difficulty != 1 {
+			t.Fatalf("block was mined with difficulty %d, want 1", b.Header.Difficulty)
+		}
+		if err := bc.PushBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	other, _ := newTestFactory(
+		WithProofOfWork(&ProofOfWorkSettings{InitialDifficulty: 3, DifficultyAdjustmentInterval: 10}),
+		WithGenesis(&GenesisSettings{ChainID: "test", Timestamp: testStart.Unix(), Difficulty: 1}),
+	)
+	other.clock.(*FakeClock).Set(clock.Now())
+	if err := other.NewBlockChain().ReplaceWith(chain); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func BenchmarkMineBlock(b *testing.B) {
+	for _, difficulty := range []int{1, 2, 3, 4} {
+		b.Run(fmt.Sprintf("difficulty-%d", difficulty), func(b *testing.B) {
+			f, _ := newTestFactory(WithProofOfWork(&ProofOfWorkSettings{InitialDifficulty: difficulty, FixedDifficulty: true}))
+			genesis := f.GenesisBlock()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// different entries give different hashes, so that the
+				// average work is measured.
+				if _, err := f.MineBlock(context.Background(), []string{fmt.Sprintf("entry-%d", i)}, genesis); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkProofOfWorkValidateChain(b *testing.B) {
+	f, clock := newTestFactory(WithProofOfWork(&ProofOfWorkSettings{InitialDifficulty: 2, FixedDifficulty: true}))
+	chain := mineChain(f, clock, 100, 10*time.Second)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := f.pow.validateChain(chain, chain[0], nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package block
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// ExportFormat is a format that a chain can be exported to and imported from.
+type ExportFormat string
+
+const (
+	// FormatProtobuf writes each block as a protobuf message preceded by
+	// its length as a varint.
+	FormatProtobuf ExportFormat = "protobuf"
+	// FormatJSONLines writes each block as JSON on its own line.
+	FormatJSONLines ExportFormat = "jsonl"
+	// FormatCSV writes a row for each entry of each block, with the header
+	// of the block repeated on each of them. Blocks without entries are
+	// written as a single row without entry index.
+	FormatCSV ExportFormat = "csv"
+)
+
+const (
+	// maxExportedBlockSize is the maximum size of a block that is accepted
+	// when importing a chain in protobuf format.
+	maxExportedBlockSize uint64 = 64 << 20
+)
+
+var (
+	csvHeader = []string{"height", "hash", "previous_hash", "timestamp", "difficulty", "nonce", "merkle_root", "entry_index", "entry"}
+)
+
+// ParseExportFormat returns the export format with the provided name.
+func ParseExportFormat(name string) (ExportFormat, error) {
+	switch format := ExportFormat(name); format {
+	case FormatProtobuf, FormatJSONLines, FormatCSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown format %s", name)
+	}
+}
+
+// ExportFormatFromPath returns the export format of a file from its
+// extension: .pb or .bin for protobuf, .jsonl for JSON lines and .csv for
+// CSV.
+func ExportFormatFromPath(path string) (ExportFormat, error) {
+	switch filepath.Ext(path) {
+	case ".pb", ".bin":
+		return FormatProtobuf, nil
+	case ".jsonl":
+		return FormatJSONLines, nil
+	case ".csv":
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("cannot infer the format of %s from its extension", path)
+	}
+}
+
+// Export writes the whole chain to w in the provided format.
+func (b *BlockChain) Export(w io.Writer, format ExportFormat) error {
+	return WriteChain(w, b.GetChain(), format)
+}
+
+// WriteChain writes the provided chain to w in the provided format.
+func WriteChain(w io.Writer, chain []*pb.Block, format ExportFormat) error {
+	bw := bufio.NewWriter(w)
+
+	var err error
+	switch format {
+	case FormatProtobuf:
+		err = writeProtobuf(bw, chain)
+	case FormatJSONLines:
+		err = writeJSONLines(bw, chain)
+	case FormatCSV:
+		err = writeCSV(bw, chain)
+	default:
+		err = fmt.Errorf("unknown format %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// ReadChain reads a chain written by WriteChain in the provided format.
+//
+// The chain is not validated.
+func ReadChain(r io.Reader, format ExportFormat) ([]*pb.Block, error) {
+	br := bufio.NewReader(r)
+
+	switch format {
+	case FormatProtobuf:
+		return readProtobuf(br)
+	case FormatJSONLines:
+		return readJSONLines(br)
+	case FormatCSV:
+		return readCSV(br)
+	default:
+		return nil, fmt.Errorf("unknown format %s", format)
+	}
+}
+
+func writeProtobuf(w io.Writer, chain []*pb.Block) error {
+	length := make([]byte, binary.MaxVarintLen64)
+	for i, block := range chain {
+		data, err := proto.Marshal(block)
+		if err != nil {
+			return fmt.Errorf("could not marshal block %d: %w", i, err)
+		}
+
+		n := binary.PutUvarint(length, uint64(len(data)))
+		if _, err := w.Write(length[:n]); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readProtobuf(r *bufio.Reader) ([]*pb.Block, error) {
+	chain := []*pb.Block{}
+	for {
+		length, err := binary.ReadUvarint(r)
+		if errors.Is(err, io.EOF) {
+			return chain, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read length of block %d: %w", len(chain), err)
+		}
+		if length > maxExportedBlockSize {
+			return nil, fmt.Errorf("block %d is too big: %d bytes", len(chain), length)
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("could not read block %d: %w", len(chain), err)
+		}
+
+		var block pb.Block
+		if err := proto.Unmarshal(data, &block); err != nil {
+			return nil, fmt.Errorf("could not unmarshal block %d: %w", len(chain), err)
+		}
+		chain = append(chain, &block)
+	}
+}
+
+func writeJSONLines(w io.Writer, chain []*pb.Block) error {
+	// the encoder already terminates each value with a new line
+	encoder := json.NewEncoder(w)
+	for i, block := range chain {
+		if err := encoder.Encode(block); err != nil {
+			return fmt.Errorf("could not encode block %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func readJSONLines(r io.Reader) ([]*pb.Block, error) {
+	chain := []*pb.Block{}
+	decoder := json.NewDecoder(r)
+	for {
+		var block pb.Block
+		err := decoder.Decode(&block)
+		if errors.Is(err, io.EOF) {
+			return chain, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not decode block %d: %w", len(chain), err)
+		}
+		chain = append(chain, &block)
+	}
+}
+
+func writeCSV(w io.Writer, chain []*pb.Block) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for i, block := range chain {
+		if block.Header == nil {
+			return fmt.Errorf("block %d has no header", i)
+		}
+
+		row := []string{
+			strconv.FormatInt(block.Header.Index, 10),
+			hex.EncodeToString(block.Hash),
+			hex.EncodeToString(block.Header.PreviousBlockHash),
+			strconv.FormatInt(block.Header.Timestamp, 10),
+			strconv.FormatInt(block.Header.Difficulty, 10),
+			strconv.FormatInt(block.Header.Nonce, 10),
+			hex.EncodeToString(block.Header.MerkleRoot),
+			"",
+			"",
+		}
+
+		if len(block.Entries) == 0 {
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for j, entry := range block.Entries {
+			row[7], row[8] = strconv.Itoa(j), entry
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func readCSV(r io.Reader) ([]*pb.Block, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+	cr.ReuseRecord = true
+
+	if _, err := cr.Read(); err != nil {
+		return nil, fmt.Errorf("could not read csv header: %w", err)
+	}
+
+	chain := []*pb.Block{}
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return chain, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := cr.FieldPos(0)
+		height, err := strconv.ParseInt(row[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: height is not valid", line)
+		}
+
+		// rows of the same block are consecutive: a new height starts a new
+		// block.
+		if len(chain) == 0 || chain[len(chain)-1].Header.Index != height {
+			block, err := parseCSVBlock(row)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			block.Header.Index = height
+			chain = append(chain, block)
+		}
+
+		if row[7] != "" {
+			block := chain[len(chain)-1]
+			if row[7] != strconv.Itoa(len(block.Entries)) {
+				return nil, fmt.Errorf("line %d: entry index is not valid", line)
+			}
+			block.Entries = append(block.Entries, row[8])
+		}
+	}
+}
+
+func parseCSVBlock(row []string) (*pb.Block, error) {
+	var err error
+	header := &pb.BlockHeader{}
+	block := &pb.Block{Header: header}
+
+	if block.Hash, err = hex.DecodeString(row[1]); err != nil {
+		return nil, fmt.Errorf("hash is not valid")
+	}
+	if header.PreviousBlockHash, err = hex.DecodeString(row[2]); err != nil {
+		return nil, fmt.Errorf("previous hash is not valid")
+	}
+	if header.Timestamp, err = strconv.ParseInt(row[3], 10, 64); err != nil {
+		return nil, fmt.Errorf("timestamp is not valid")
+	}
+	if header.Difficulty, err = strconv.ParseInt(row[4], 10, 64); err != nil {
+		return nil, fmt.Errorf("difficulty is not valid")
+	}
+	if header.Nonce, err = strconv.ParseInt(row[5], 10, 64); err != nil {
+		return nil, fmt.Errorf("nonce is not valid")
+	}
+	if header.MerkleRoot, err = hex.DecodeString(row[6]); err != nil {
+		return nil, fmt.Errorf("merkle root is not valid")
+	}
+
+	return block, nil
+}
@@ -0,0 +1,82 @@
+package block
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultCheckpointInterval is the number of blocks between two
+	// checkpoints of the finality gadget, if not provided.
+	DefaultCheckpointInterval int = 10
+)
+
+var (
+	// ErrFinalizedReorg is returned when replacing the chain would remove a
+	// finalized block.
+	ErrFinalizedReorg = errors.New("chain does not include the last finalized block")
+)
+
+// FinalitySettings defines settings for the finality gadget, which finalizes
+// checkpoint blocks with the votes of a set of validators.
+type FinalitySettings struct {
+	// Validators are the hex representations of the public keys of the
+	// validators that vote on checkpoints, i.e. the addresses of their
+	// wallets.
+	Validators []string `yaml:"validators"`
+	// CheckpointInterval is the number of blocks between two checkpoints:
+	// only blocks whose index is a multiple of it can be finalized.
+	CheckpointInterval int `yaml:"checkpointInterval"`
+}
+
+// Finalize marks the block at the provided height as finalized, so that the
+// chain is never replaced with one that does not include it. The block must
+// be on the chain and have the provided hash.
+func (b *BlockChain) Finalize(height int64, hash []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if height <= b.finalized {
+		return fmt.Errorf("a later block is already finalized")
+	}
+
+	if height >= int64(len(b.chain)) || !bytes.Equal(b.chain[height].Hash, hash) {
+		return ErrBlockNotFound
+	}
+
+	b.finalized = height
+	b.events.Publish(events.EventBlockFinalized, b.chain[height])
+	b.metrics.BlockFinalized(height)
+	log.Info().Int64("height", height).Msg("block finalized")
+	return nil
+}
+
+// FinalizedHeight returns the index of the last finalized block. The genesis
+// block is always final.
+func (b *BlockChain) FinalizedHeight() int64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.finalized
+}
+
+// checkFinality returns ErrFinalizedReorg if the new chain does not include
+// the last finalized block.
+// The caller must hold the lock.
+func (b *BlockChain) checkFinality(newChain []*pb.Block) error {
+	if b.finalized == 0 {
+		return nil
+	}
+
+	if int64(len(newChain)) <= b.finalized ||
+		!bytes.Equal(newChain[b.finalized].GetHash(), b.chain[b.finalized].Hash) {
+		return ErrFinalizedReorg
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+//go:build go1.18
+// +build go1.18
+
+package block
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// fuzzConsensus returns the options of each consensus that blocks are
+// fuzzed with. Blocks are sealed instantly, so that altered blocks are not
+// refused only because of their hash.
+func fuzzConsensus() [][]FactoryOptions {
+	return [][]FactoryOptions{
+		nil,
+		{
+			WithProofOfWork(&ProofOfWorkSettings{InitialDifficulty: 1, BlockGenerationInterval: 10, DifficultyAdjustmentInterval: 2}),
+			WithInstantSeal(),
+		},
+	}
+}
+
+// fuzzFactories returns new factories for each consensus that blocks are
+// fuzzed with.
+func fuzzFactories() []*BlockFactory {
+	factories := []*BlockFactory{}
+	for _, options := range fuzzConsensus() {
+		f, clock := newTestFactory(options...)
+		// accept the timestamps of any block mined by the seeds.
+		clock.Advance(time.Hour)
+		factories = append(factories, f)
+	}
+
+	return factories
+}
+
+// fuzzSeeds returns the chains used to seed the fuzz targets, one for each
+// consensus, in the same order as fuzzFactories.
+func fuzzSeeds() [][]*pb.Block {
+	seeds := [][]*pb.Block{}
+	for _, options := range fuzzConsensus() {
+		f, clock := newTestFactory(options...)
+		seeds = append(seeds, mineChain(f, clock, 4, 10*time.Second))
+	}
+
+	return seeds
+}
+
+// checkChain fails the test if the chain is not made of valid blocks
+// following the genesis one.
+func checkChain(t *testing.T, bc *BlockChain) {
+	t.Helper()
+
+	chain := bc.GetChain()
+	var err error
+	if bc.pow != nil {
+		_, _, err = bc.pow.validateChain(chain, bc.genesis, bc.checkpoints)
+	} else {
+		err = validateChain(chain, bc.genesis, bc.checkpoints)
+	}
+	if err != nil {
+		t.Fatalf("chain is not valid: %s", err)
+	}
+
+	if bc.GetLastBlock() != chain[len(chain)-1] {
+		t.Fatal("last block is not the one at the end of the chain")
+	}
+}
+
+func FuzzPushBlock(f *testing.F) {
+	for _, seeds := range fuzzSeeds() {
+		for _, b := range seeds {
+			data, err := proto.Marshal(b)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(data)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		block := &pb.Block{}
+		if err := proto.Unmarshal(data, block); err != nil {
+			t.Skip()
+		}
+
+		for _, factory := range fuzzFactories() {
+			bc := factory.NewBlockChain()
+
+			err := bc.PushBlock(block)
+			switch {
+			case err != nil && bc.Length() != 1:
+				t.Fatalf("chain has %d blocks after a rejected block, want 1", bc.Length())
+			case err == nil && bc.Length() != 2:
+				t.Fatalf("chain has %d blocks after an accepted block, want 2", bc.Length())
+			}
+			checkChain(t, bc)
+		}
+	})
+}
+
+func FuzzReplaceWith(f *testing.F) {
+	seeds := fuzzSeeds()
+	f.Add([]byte{})
+	for _, chain := range seeds {
+		for _, n := range []int{1, 2, len(chain)} {
+			data, err := proto.Marshal(&pb.BlockChain{Blocks: chain[:n]})
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(data)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		newChain := &pb.BlockChain{}
+		if err := proto.Unmarshal(data, newChain); err != nil {
+			t.Skip()
+		}
+
+		for i, factory := range fuzzFactories() {
+			bc := factory.NewBlockChain()
+			// the current chain shares the first blocks of the seeds of the
+			// same consensus, so that altered seeds can replace it.
+			for _, b := range seeds[i][1:3] {
+				if err := bc.PushBlock(b); err != nil {
+					t.Fatalf("could not push seed block: %s", err)
+				}
+			}
+			before := bc.GetChain()
+
+			err := bc.ReplaceWith(newChain.Blocks)
+			after := bc.GetChain()
+			switch {
+			case err != nil && !sameChain(after, before):
+				t.Fatal("chain changed after a refused replacement")
+			case err == nil && !sameChain(after, before) && !sameChain(after, newChain.Blocks):
+				t.Fatal("chain is neither the current one nor the new one")
+			case !bytes.Equal(after[0].Hash, bc.genesis.Hash):
+				t.Fatal("chain does not start from the genesis block")
+			}
+			checkChain(t, bc)
+		}
+	})
+}
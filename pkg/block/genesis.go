@@ -0,0 +1,183 @@
+package block
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// NetworkMainnet is the name of the main network.
+	NetworkMainnet string = "mainnet"
+	// NetworkTestnet is the name of the test network.
+	NetworkTestnet string = "testnet"
+	// NetworkRegtest is the name of the regression test network, where
+	// blocks are mined instantly at the minimum difficulty.
+	NetworkRegtest string = "regtest"
+)
+
+// GenesisSettings defines the genesis block of a chain.
+type GenesisSettings struct {
+	// ChainID identifies the chain. If not empty, it is the first entry of
+	// the genesis block, so that chains with different IDs never share the
+	// same genesis block.
+	ChainID string `yaml:"chainID"`
+	// Timestamp of the genesis block.
+	Timestamp int64 `yaml:"timestamp"`
+	// Data contains entries included in the genesis block.
+	Data []string `yaml:"data"`
+	// Allocations are included in the genesis block after the data, each as
+	// a JSON entry.
+	Allocations []Allocation `yaml:"allocations"`
+	// Difficulty of the genesis block. With proof of work, this is also the
+	// difficulty the first blocks are mined with, unless it is 0.
+	Difficulty int64 `yaml:"difficulty"`
+}
+
+// Allocation assigns an amount to an address in the genesis block.
+//
+// The chain does not track balances: allocations are only recorded.
+type Allocation struct {
+	Address string `yaml:"address" json:"address"`
+	Amount  uint64 `yaml:"amount" json:"amount"`
+}
+
+// Network contains the settings of a named network.
+type Network struct {
+	// Name of the network.
+	Name string
+	// Genesis is the genesis block of the network.
+	Genesis *GenesisSettings
+	// Consensus overrides the consensus settings of the node, if not nil.
+	Consensus *ConsensusSettings
+}
+
+var (
+	networks = map[string]Network{
+		// mainnet keeps the genesis block that was used before networks
+		// were introduced, so it has no chain ID.
+		NetworkMainnet: {
+			Name: NetworkMainnet,
+			Genesis: &GenesisSettings{
+				Data: []string{genesisBlockData},
+			},
+		},
+		NetworkTestnet: {
+			Name: NetworkTestnet,
+			Genesis: &GenesisSettings{
+				ChainID: "naivecoin-testnet",
+				Data:    []string{"this is the testnet genesis block!"},
+			},
+		},
+		NetworkRegtest: {
+			Name: NetworkRegtest,
+			Genesis: &GenesisSettings{
+				ChainID: "naivecoin-regtest",
+				Data:    []string{"this is the regtest genesis block!"},
+			},
+			Consensus: &ConsensusSettings{
+				ProofOfWork: &ProofOfWorkSettings{
+					InitialDifficulty:            0,
+					BlockGenerationInterval:      1,
+					DifficultyAdjustmentInterval: 1,
+					FixedDifficulty:              true,
+				},
+			},
+		},
+	}
+)
+
+// GetNetwork returns the built-in network with the provided name.
+func GetNetwork(name string) (*Network, error) {
+	network, exists := networks[name]
+	if !exists {
+		names := make([]string, 0, len(networks))
+		for n := range networks {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("unknown network %s, must be one of %s", name, strings.Join(names, ", "))
+	}
+
+	return &network, nil
+}
+
+// LoadGenesisSettings reads the genesis settings from the provided yaml
+// file.
+func LoadGenesisSettings(filePath string) (*GenesisSettings, error) {
+	gsBytes, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var genesisSettings GenesisSettings
+	if err := yaml.Unmarshal(gsBytes, &genesisSettings); err != nil {
+		return nil, err
+	}
+
+	if genesisSettings.Difficulty < 0 || genesisSettings.Difficulty > 64 {
+		return nil, fmt.Errorf("difficulty must be between 0 and 64")
+	}
+
+	return &genesisSettings, nil
+}
+
+// entries returns the entries of the genesis block.
+func (g *GenesisSettings) entries() []string {
+	entries := []string{}
+	if g.ChainID != "" {
+		entries = append(entries, "chainID:"+g.ChainID)
+	}
+	entries = append(entries, g.Data...)
+
+	for _, allocation := range g.Allocations {
+		// marshaling a struct with strings and integers cannot fail
+		entry, _ := json.Marshal(allocation)
+		entries = append(entries, string(entry))
+	}
+
+	return entries
+}
+
+func newGenesisBlock(settings *GenesisSettings) *pb.Block {
+	entries := settings.entries()
+	genesis := &pb.Block{
+		Header: &pb.BlockHeader{
+			Index:             0,
+			Timestamp:         settings.Timestamp,
+			PreviousBlockHash: []byte{},
+			MerkleRoot:        calculateMerkleRoot(entries),
+			Difficulty:        settings.Difficulty,
+		},
+		Entries: entries,
+		Hash:    []byte{},
+	}
+	genesis.Hash = calculateHash(genesis.Header)
+	return genesis
+}
+
+// LoadNetwork returns the built-in network with the provided name. If
+// genesisPath is not empty, the genesis block of the network is replaced with
+// the one defined in that file.
+func LoadNetwork(name, genesisPath string) (*Network, error) {
+	network, err := GetNetwork(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if genesisPath != "" {
+		genesisSettings, err := LoadGenesisSettings(genesisPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not load genesis settings: %w", err)
+		}
+		network.Genesis = genesisSettings
+	}
+
+	return network, nil
+}
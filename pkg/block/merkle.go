@@ -0,0 +1,167 @@
+package block
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// merkleLeafPrefix and merkleNodePrefix are prepended to the data being
+	// hashed, so that a leaf can never be passed off as an inner node and
+	// vice versa (second preimage attack).
+	merkleLeafPrefix byte = 0x00
+	merkleNodePrefix byte = 0x01
+	// merkleCountPrefix is prepended to the number of entries and the root
+	// of their tree, which are hashed together into the merkle root.
+	merkleCountPrefix byte = 0x02
+)
+
+// HashEntry calculates and returns the hash of a single entry of the block
+// body, that is a leaf of the merkle tree.
+//
+// This is the hash that clients use to ask for inclusion proofs of their
+// entries.
+func HashEntry(entry string) []byte {
+	hash := sha256.Sum256(append([]byte{merkleLeafPrefix}, []byte(entry)...))
+	return hash[:]
+}
+
+// hashMerkleNode calculates and returns the hash of an inner node of the
+// merkle tree from its two children.
+func hashMerkleNode(left, right []byte) []byte {
+	data := make([]byte, 0, 1+len(left)+len(right))
+	data = append(data, merkleNodePrefix)
+	data = append(data, left...)
+	data = append(data, right...)
+
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+// hashMerkleCount calculates and returns the merkle root from the number of
+// entries and the root of their tree.
+//
+// As the last node of an odd level is paired with itself, the tree alone
+// does not tell how many entries there are, and a branch could prove an entry
+// at an index past the last one (CVE-2012-2459): committing to the number of
+// entries lets verifiers reject such indexes.
+func hashMerkleCount(count int, treeRoot []byte) []byte {
+	data := make([]byte, 9, 9+len(treeRoot))
+	data[0] = merkleCountPrefix
+	binary.LittleEndian.PutUint64(data[1:], uint64(count))
+	data = append(data, treeRoot...)
+
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+// merkleDepth returns the number of levels above the leaves of the merkle
+// tree of the provided number of entries, i.e. the length of its branches.
+func merkleDepth(count int64) int {
+	depth := 0
+	for ; count > 1; count = (count + 1) / 2 {
+		depth++
+	}
+
+	return depth
+}
+
+// calculateMerkleRoot calculates and returns the merkle root of the provided
+// entries, which commits to both the entries and their number.
+//
+// When a level has an odd number of nodes, the last one is paired with
+// itself. The root of an empty body is the hash of no data.
+func calculateMerkleRoot(entries []string) []byte {
+	if len(entries) == 0 {
+		hash := sha256.Sum256([]byte{})
+		return hash[:]
+	}
+
+	level := make([][]byte, len(entries))
+	for i, entry := range entries {
+		level[i] = HashEntry(entry)
+	}
+
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			right := level[i]
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+
+			next = append(next, hashMerkleNode(level[i], right))
+		}
+
+		level = next
+	}
+
+	return hashMerkleCount(len(entries), level[0])
+}
+
+// calculateMerkleBranch returns the sibling hashes needed to recompute the
+// merkle root of the provided entries starting from the entry at the given
+// index, ordered from the leaf level up to the root.
+func calculateMerkleBranch(entries []string, index int) [][]byte {
+	level := make([][]byte, len(entries))
+	for i, entry := range entries {
+		level[i] = HashEntry(entry)
+	}
+
+	branch := [][]byte{}
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling >= len(level) {
+			// the last node of an odd level is paired with itself
+			sibling = index
+		}
+		branch = append(branch, level[sibling])
+
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			right := level[i]
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+
+			next = append(next, hashMerkleNode(level[i], right))
+		}
+
+		level = next
+		index /= 2
+	}
+
+	return branch
+}
+
+// VerifyMerkleBranch checks that the entry with the provided hash, found at
+// the given index of a block body with count entries, is included in the
+// merkle tree with the provided root. It returns an error if not.
+func VerifyMerkleBranch(entryHash []byte, index, count int64, branch [][]byte, root []byte) error {
+	if index < 0 || index >= count {
+		return fmt.Errorf("entry index is not valid")
+	}
+
+	if len(branch) != merkleDepth(count) {
+		return fmt.Errorf("merkle branch does not match the number of entries")
+	}
+
+	hash := entryHash
+	for _, sibling := range branch {
+		if index%2 == 0 {
+			hash = hashMerkleNode(hash, sibling)
+		} else {
+			hash = hashMerkleNode(sibling, hash)
+		}
+
+		index /= 2
+	}
+
+	if !bytes.Equal(hashMerkleCount(int(count), hash), root) {
+		return fmt.Errorf("merkle branch does not lead to the merkle root")
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package block
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestVerifyMerkleBranch(t *testing.T) {
+	for count := 1; count <= 9; count++ {
+		entries := []string{}
+		for i := 0; i < count; i++ {
+			entries = append(entries, fmt.Sprintf("entry-%d", i))
+		}
+		root := calculateMerkleRoot(entries)
+
+		for i, entry := range entries {
+			branch := calculateMerkleBranch(entries, i)
+			if err := VerifyMerkleBranch(HashEntry(entry), int64(i), int64(count), branch, root); err != nil {
+				t.Fatalf("entry %d of %d: %s", i, count, err)
+			}
+		}
+	}
+}
+
+func TestVerifyMerkleBranchRejects(t *testing.T) {
+	entries := []string{"a", "b", "c", "d", "e"}
+	root := calculateMerkleRoot(entries)
+	branch := calculateMerkleBranch(entries, 2)
+
+	// the last entry repeated has the same tree as the original entries, as
+	// the last node of an odd level is paired with itself.
+	duplicated := append(append([]string{}, entries...), "e")
+	duplicatedBranch := calculateMerkleBranch(duplicated, 5)
+
+	cases := []struct {
+		name   string
+		entry  string
+		index  int64
+		count  int64
+		branch [][]byte
+	}{
+		{
+			name:   "other entry",
+			entry:  "a",
+			index:  2,
+			count:  5,
+			branch: branch,
+		},
+		{
+			name:   "other index",
+			entry:  "c",
+			index:  3,
+			count:  5,
+			branch: branch,
+		},
+		{
+			name:   "negative index",
+			entry:  "c",
+			index:  -1,
+			count:  5,
+			branch: branch,
+		},
+		{
+			name:   "other count",
+			entry:  "c",
+			index:  2,
+			count:  6,
+			branch: branch,
+		},
+		{
+			name:   "short branch",
+			entry:  "c",
+			index:  2,
+			count:  5,
+			branch: branch[:len(branch)-1],
+		},
+		{
+			name:   "duplicated last entry",
+			entry:  "e",
+			index:  5,
+			count:  5,
+			branch: duplicatedBranch,
+		},
+		{
+			name:   "duplicated last entry with a forged count",
+			entry:  "e",
+			index:  5,
+			count:  6,
+			branch: duplicatedBranch,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := VerifyMerkleBranch(HashEntry(c.entry), c.index, c.count, c.branch, root); err == nil {
+				t.Fatal("expected an error, got nil")
+			}
+		})
+	}
+}
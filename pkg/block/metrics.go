@@ -0,0 +1,58 @@
+package block
+
+import (
+	"math/big"
+	"time"
+)
+
+// Reasons for which a block can be rejected.
+const (
+	// RejectedInvalidBlock is used when the block does not follow the last
+	// one or its entries don't match the merkle root.
+	RejectedInvalidBlock string = "invalid_block"
+	// RejectedInvalidHash is used when the hash does not satisfy the proof
+	// of work.
+	RejectedInvalidHash string = "invalid_hash"
+	// RejectedInvalidTimestamp is used when the timestamp is too far in the
+	// future.
+	RejectedInvalidTimestamp string = "invalid_timestamp"
+	// RejectedInvalidSeal is used when the block was not sealed by a
+	// validator allowed to seal it, with proof of authority.
+	RejectedInvalidSeal string = "invalid_seal"
+	// RejectedCheckpointMismatch is used when the block conflicts with a
+	// checkpoint.
+	RejectedCheckpointMismatch string = "checkpoint_mismatch"
+)
+
+// Metrics receives measurements about the blockchains and the blocks mined by
+// a block factory, e.g. to expose them for Prometheus. Its methods are called
+// while holding the lock of the chain, so they must not block.
+type Metrics interface {
+	// BlockRejected is called when a block is not pushed to the chain, with
+	// one of the reasons above.
+	BlockRejected(reason string)
+	// ChainReplaced is called when the chain is replaced with a peer's one.
+	ChainReplaced()
+	// ChainUpdated is called when the last block of the chain changes.
+	ChainUpdated(height int64, cumulativeDifficulty *big.Int)
+	// DifficultyUpdated is called with the difficulty the next block will
+	// be mined with, with proof of work.
+	DifficultyUpdated(difficulty int)
+	// BlockFinalized is called when the block at the provided height is
+	// finalized.
+	BlockFinalized(height int64)
+	// BlockMined is called when a block is mined with proof of work, with
+	// how long it took and how many hashes were calculated.
+	BlockMined(duration time.Duration, hashes int64)
+}
+
+// nopMetrics discards all measurements. It is used when no Metrics are
+// provided.
+type nopMetrics struct{}
+
+func (nopMetrics) BlockRejected(string)            {}
+func (nopMetrics) ChainReplaced()                  {}
+func (nopMetrics) ChainUpdated(int64, *big.Int)    {}
+func (nopMetrics) DifficultyUpdated(int)           {}
+func (nopMetrics) BlockFinalized(int64)            {}
+func (nopMetrics) BlockMined(time.Duration, int64) {}
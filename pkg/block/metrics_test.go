@@ -0,0 +1,62 @@
+package block
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// testMetrics records the measurements it receives.
+type testMetrics struct {
+	rejected   []string
+	replaced   int
+	height     int64
+	difficulty int
+	mined      int
+}
+
+func (m *testMetrics) BlockRejected(reason string)      { m.rejected = append(m.rejected, reason) }
+func (m *testMetrics) ChainReplaced()                   { m.replaced++ }
+func (m *testMetrics) ChainUpdated(h int64, _ *big.Int) { m.height = h }
+func (m *testMetrics) DifficultyUpdated(d int)          { m.difficulty = d }
+func (m *testMetrics) BlockFinalized(int64)             {}
+func (m *testMetrics) BlockMined(time.Duration, int64)  { m.mined++ }
+
+func TestMetrics(t *testing.T) {
+	settings := &ProofOfWorkSettings{InitialDifficulty: 1, FixedDifficulty: true}
+	first, second := &testMetrics{}, &testMetrics{}
+	f, clock := newTestFactory(WithProofOfWork(settings), WithMetrics(first))
+	other, _ := newTestFactory(WithProofOfWork(settings), WithMetrics(second))
+	bc, otherBC := f.NewBlockChain(), other.NewBlockChain()
+
+	if first.difficulty != 1 {
+		t.Fatalf("difficulty = %d, want 1", first.difficulty)
+	}
+
+	clock.Advance(10 * time.Second)
+	mined, err := f.MineBlock(context.Background(), []string{"entry"}, bc.GetLastBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.PushBlock(mined); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.PushBlock(mined); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+
+	if first.mined != 1 || first.height != 1 || len(first.rejected) != 1 || first.rejected[0] != RejectedInvalidBlock {
+		t.Fatalf("unexpected measurements %+v", first)
+	}
+
+	if err := otherBC.ReplaceWith(bc.GetChain()); err != nil {
+		t.Fatal(err)
+	}
+	if second.replaced != 1 || second.height != 1 || second.mined != 0 {
+		t.Fatalf("unexpected measurements %+v", second)
+	}
+	if first.replaced != 0 {
+		t.Fatal("chains reported to the metrics of each other")
+	}
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// verifyPageSize is the number of blocks downloaded at once when
+	// verifying or exporting the chain.
+	verifyPageSize int = 1000
+	// hashLength is the length of the hex representation of a block hash.
+	hashLength int = 64
+)
+
+func newChainCommand(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "chain",
+		Short: "Query the chain of a node",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "get height|hash",
+			Short: "Get a block by height or hash",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var b *pb.Block
+				var err error
+				if len(args[0]) == hashLength {
+					hash, decodeErr := hex.DecodeString(args[0])
+					if decodeErr != nil {
+						return fmt.Errorf("hash must be a valid hex string")
+					}
+					b, err = opts.newClient().GetBlockByHash(cmd.Context(), hash)
+				} else {
+					height, parseErr := strconv.ParseInt(args[0], 10, 64)
+					if parseErr != nil {
+						return fmt.Errorf("argument must be a height or a hash")
+					}
+					b, err = opts.newClient().GetBlockByHeight(cmd.Context(), height)
+				}
+				if err != nil {
+					return err
+				}
+
+				return printJSON(cmd.OutOrStdout(), b)
+			},
+		},
+		&cobra.Command{
+			Use:   "tip",
+			Short: "Get the last block of the chain",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				b, err := opts.newClient().GetChainTip(cmd.Context())
+				if err != nil {
+					return err
+				}
+
+				return printJSON(cmd.OutOrStdout(), b)
+			},
+		},
+		newChainVerifyCommand(opts),
+		newChainExportCommand(opts),
+	)
+
+	return cmd
+}
+
+func newChainVerifyCommand(opts *globalOptions) *cobra.Command {
+	factoryOpts := &blockFactoryOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Download the whole chain and verify it",
+		Long: `Download the whole chain and verify it.
+
+The network of the node must be provided with --network, or --genesis if it
+uses a custom genesis block. Its consensus settings must be provided with
+--consensus-settings if it mines blocks with proof of work and its network
+does not define them, or their hashes will not match.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			bf, err := factoryOpts.newBlockFactory()
+			if err != nil {
+				return err
+			}
+
+			chain := []*pb.Block{}
+			err = opts.newClient().ForEachBlock(cmd.Context(), 0, verifyPageSize, func(b *pb.Block) error {
+				chain = append(chain, b)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("could not download chain: %w", err)
+			}
+
+			if err := bf.ValidateChain(chain); err != nil {
+				return fmt.Errorf("chain is not valid: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "chain of %d blocks is valid\n", len(chain))
+			return nil
+		},
+	}
+
+	factoryOpts.addFlags(cmd.Flags())
+	return cmd
+}
+
+func newChainExportCommand(opts *globalOptions) *cobra.Command {
+	var formatName string
+
+	cmd := &cobra.Command{
+		Use:   "export file",
+		Short: "Download the whole chain and export it to a file",
+		Long: `Download the whole chain and export it to a file.
+
+The format is inferred from the extension of the file unless --format is
+provided: .pb or .bin for length-delimited protobuf, .jsonl for JSON lines and
+.csv for CSV, with a row for each entry. The file can be used to bootstrap a
+node with "naivecoin node --bootstrap-file".`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := func() (block.ExportFormat, error) {
+				if formatName != "" {
+					return block.ParseExportFormat(formatName)
+				}
+				return block.ExportFormatFromPath(args[0])
+			}()
+			if err != nil {
+				return err
+			}
+
+			chain := []*pb.Block{}
+			err = opts.newClient().ForEachBlock(cmd.Context(), 0, verifyPageSize, func(b *pb.Block) error {
+				chain = append(chain, b)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("could not download chain: %w", err)
+			}
+
+			file, err := os.Create(args[0])
+			if err != nil {
+				return err
+			}
+
+			if err := block.WriteChain(file, chain, format); err != nil {
+				file.Close()
+				return fmt.Errorf("could not export chain: %w", err)
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "exported %d blocks to %s\n", len(chain), args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&formatName, "format", "", "the format of the file: protobuf, jsonl or csv.")
+	return cmd
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/SunSince90/go-naivecoin/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+func newPeersCommand(opts *globalOptions) *cobra.Command {
+	var adminAPI, adminToken string
+
+	cmd := &cobra.Command{
+		Use:   "peers",
+		Short: "Inspect the peers of a node through its admin API",
+	}
+	cmd.PersistentFlags().StringVar(&adminAPI, "admin-api", "http://localhost:8083", "the URL of the admin API of the node.")
+	cmd.PersistentFlags().StringVar(&adminToken, "admin-token", "", "the token of the admin API.")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the peers of the node",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			adminClient := client.NewClient(adminAPI, client.WithAPIKey(adminToken), client.WithHTTPClient(newHTTPClient(opts.timeout)))
+
+			list, err := adminClient.GetPeers(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tIP\tSTATE\tHEIGHT\tLATENCY\tSCORE\tBANNED")
+			for _, peer := range list.Peers {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.1fms\t%d\t%t\n", peer.Name, peer.IP, peer.State, peer.Height, peer.LatencyMs, peer.Score, false)
+			}
+			for _, name := range list.Banned {
+				fmt.Fprintf(w, "%s\t\t\t\t\t\t%t\n", name, true)
+			}
+
+			return w.Flush()
+		},
+	})
+
+	return cmd
+}
@@ -0,0 +1,137 @@
+// Package cli contains the commands of the naivecoin command line interface
+// that talk to a running node through its APIs.
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// envPrefix is the prefix of the environment variables that can be used
+	// instead of flags.
+	envPrefix string = "NAIVECOIN"
+)
+
+// globalOptions contains the flags shared by all commands.
+type globalOptions struct {
+	api     string
+	apiKey  string
+	timeout time.Duration
+}
+
+func (o *globalOptions) newClient() *client.Client {
+	return client.NewClient(o.api, client.WithAPIKey(o.apiKey), client.WithHTTPClient(newHTTPClient(o.timeout)))
+}
+
+// NewRootCommand returns the root naivecoin command with all the commands
+// that talk to a running node. The command that runs the node itself is
+// added by the caller.
+//
+// It must be run with ExecuteContext, as commands use its context for their
+// requests.
+func NewRootCommand() *cobra.Command {
+	opts := &globalOptions{}
+
+	root := &cobra.Command{
+		Use:   "naivecoin",
+		Short: "An exercise with blockchain and cryptocurrencies",
+		Long: `An exercise with blockchain and cryptocurrencies.
+
+Every flag can also be set with an environment variable named after it,
+e.g. --api-key can be set with ` + envName("api-key") + `.`,
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			return bindEnv(cmd.Flags())
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.api, "api", "http://localhost:8080", "the URL of the public API of the node.")
+	flags.StringVar(&opts.apiKey, "api-key", "", "the API key or JWT used to authenticate writes.")
+	flags.DurationVar(&opts.timeout, "timeout", 30*time.Second, "the timeout of each request to the node.")
+
+	root.AddCommand(
+		newWalletCommand(opts),
+		newChainCommand(opts),
+		newPeersCommand(opts),
+		newVerifyCommand(),
+		newCompletionCommand(),
+	)
+
+	return root
+}
+
+// bindEnv sets the flags that were not provided on the command line from
+// their environment variables, if set.
+func bindEnv(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Changed || err != nil {
+			return
+		}
+
+		value, exists := os.LookupEnv(envName(f.Name))
+		if !exists {
+			return
+		}
+
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value for %s: %w", envName(f.Name), setErr)
+		}
+	})
+
+	return err
+}
+
+// envName returns the name of the environment variable of a flag.
+func envName(flagName string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func newHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout}
+}
+
+func newCompletionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Generate the completion script for the provided shell",
+		Long: `Generate the completion script for the provided shell.
+
+To load completions in the current bash session:
+
+  source <(naivecoin completion bash)`,
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, out := cmd.Root(), cmd.OutOrStdout()
+
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletion(out)
+			case "zsh":
+				return root.GenZshCompletion(out)
+			case "fish":
+				return root.GenFishCompletion(out, true)
+			default:
+				return root.GenPowerShellCompletion(out)
+			}
+		},
+	}
+}
+
+func printJSON(out io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,251 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newVerifyCommand() *cobra.Command {
+	factoryOpts := &blockFactoryOptions{}
+	var truncate bool
+
+	cmd := &cobra.Command{
+		Use:   "verify file",
+		Short: "Verify a chain stored in a file and optionally repair it",
+		Long: `Verify a chain stored in a file and optionally repair it.
+
+The file must contain a JSON array of blocks, as returned by GET /blocks, or
+a chain exported with "naivecoin chain export": its format is inferred from
+its extension.
+Every block is checked for its link to the previous one, its hash, its merkle
+root and, with --consensus-settings, the checkpoints, its proof of work and
+timestamp. The
+network and consensus settings must be the ones of the node that produced the
+chain, or its hashes will not match.
+
+The first block that is not valid is reported. With --truncate the file is
+cut back to the last valid block, after keeping a copy of the original one
+with the .bak suffix.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bf, err := factoryOpts.newBlockFactory()
+			if err != nil {
+				return err
+			}
+
+			chain, err := readChainFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			err = bf.ValidateChain(chain)
+			if err == nil {
+				fmt.Fprintf(out, "chain of %d blocks is valid\n", len(chain))
+				return nil
+			}
+
+			var invalidErr *block.InvalidBlockError
+			if !errors.As(err, &invalidErr) {
+				return fmt.Errorf("chain is not valid: %w", err)
+			}
+
+			fmt.Fprintln(out, invalidErr.Error())
+			printDiagnostic(out, bf, chain, invalidErr.Height)
+
+			if !truncate {
+				return fmt.Errorf("chain is not valid")
+			}
+
+			if invalidErr.Height == 0 {
+				return fmt.Errorf("cannot truncate a chain with an invalid genesis block")
+			}
+
+			if err := truncateChainFile(args[0], chain[:invalidErr.Height]); err != nil {
+				return fmt.Errorf("could not truncate chain: %w", err)
+			}
+
+			fmt.Fprintf(out, "chain truncated to %d blocks, original saved in %s.bak\n", invalidErr.Height, args[0])
+			return nil
+		},
+	}
+
+	factoryOpts.addFlags(cmd.Flags())
+	cmd.Flags().BoolVar(&truncate, "truncate", false, "whether to truncate the chain back to the last valid block.")
+	return cmd
+}
+
+// blockFactoryOptions contains the flags needed to create a block factory
+// with the same settings as a node.
+type blockFactoryOptions struct {
+	consensusPath string
+	network       string
+	genesisPath   string
+}
+
+func (o *blockFactoryOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.consensusPath, "consensus-settings", "", "the path to where the consensus settings of the node are stored.")
+	flags.StringVar(&o.network, "network", block.NetworkMainnet, "the network of the node: mainnet, testnet or regtest.")
+	flags.StringVar(&o.genesisPath, "genesis", "", "the path to where the genesis settings of the node are stored, if it does not use the genesis block of its network.")
+}
+
+// newBlockFactory returns a block factory for the network of the node. The
+// consensus settings provided with --consensus-settings take precedence over
+// the ones of the network.
+func (o *blockFactoryOptions) newBlockFactory() (*block.BlockFactory, error) {
+	network, err := block.LoadNetwork(o.network, o.genesisPath)
+	if err != nil {
+		return nil, err
+	}
+
+	consensusSettings := network.Consensus
+	if o.consensusPath != "" {
+		consensusSettings, err = block.LoadConsensusSettings(o.consensusPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not load consensus settings: %w", err)
+		}
+	}
+
+	options := []block.FactoryOptions{block.WithGenesis(network.Genesis)}
+	var settingsCheckpoints map[int64]string
+	if consensusSettings != nil {
+		consensusOptions, err := consensusSettings.FactoryOptions(nil)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, consensusOptions...)
+		settingsCheckpoints = consensusSettings.Checkpoints
+	}
+
+	checkpoints, err := block.ParseCheckpoints(settingsCheckpoints)
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, block.WithCheckpoints(checkpoints))
+
+	return block.NewBlockFactory(options...), nil
+}
+
+// printDiagnostic prints what is stored in the block at the provided height
+// next to what was expected from it.
+func printDiagnostic(out io.Writer, bf *block.BlockFactory, chain []*pb.Block, height int) {
+	b := chain[height]
+	if b.Header == nil {
+		fmt.Fprintln(out, "  header:                 missing")
+		return
+	}
+
+	fmt.Fprintf(out, "  index:                  %d\n", b.Header.Index)
+	fmt.Fprintf(out, "  timestamp:              %d\n", b.Header.Timestamp)
+	fmt.Fprintf(out, "  difficulty:             %d\n", b.Header.Difficulty)
+	fmt.Fprintf(out, "  nonce:                  %d\n", b.Header.Nonce)
+	fmt.Fprintf(out, "  stored hash:            %s\n", hex.EncodeToString(b.Hash))
+	fmt.Fprintf(out, "  computed hash:          %s\n", hex.EncodeToString(bf.HashHeader(b.Header)))
+	fmt.Fprintf(out, "  previous hash:          %s\n", hex.EncodeToString(b.Header.PreviousBlockHash))
+	if height > 0 {
+		fmt.Fprintf(out, "  expected previous hash: %s\n", hex.EncodeToString(chain[height-1].Hash))
+	}
+}
+
+// readChainFile reads the chain stored in path. Files with the .json
+// extension contain a JSON array of blocks, all others are in one of the
+// export formats.
+func readChainFile(path string) ([]*pb.Block, error) {
+	if filepath.Ext(path) == ".json" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read chain: %w", err)
+		}
+
+		var chain []*pb.Block
+		if err := json.Unmarshal(data, &chain); err != nil {
+			return nil, fmt.Errorf("could not decode chain: %w", err)
+		}
+
+		return chain, nil
+	}
+
+	format, err := block.ExportFormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read chain: %w", err)
+	}
+	defer file.Close()
+
+	chain, err := block.ReadChain(file, format)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode chain: %w", err)
+	}
+
+	return chain, nil
+}
+
+// encodeChain encodes the chain in the format of the file stored in path.
+func encodeChain(path string, chain []*pb.Block) ([]byte, error) {
+	if filepath.Ext(path) == ".json" {
+		return json.Marshal(chain)
+	}
+
+	format, err := block.ExportFormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := block.WriteChain(&buf, chain, format); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// truncateChainFile replaces the chain stored in path with the provided one,
+// keeping a backup of the original file.
+func truncateChainFile(path string, chain []*pb.Block) error {
+	data, err := encodeChain(path, chain)
+	if err != nil {
+		return err
+	}
+
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path+".bak", original, 0644); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so that the chain is never left half
+	// written
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
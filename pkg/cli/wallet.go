@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/wallet"
+	"github.com/spf13/cobra"
+)
+
+func defaultWalletDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".naivecoin/wallets"
+	}
+
+	return filepath.Join(home, ".naivecoin", "wallets")
+}
+
+func newWalletCommand(opts *globalOptions) *cobra.Command {
+	var walletDir string
+
+	cmd := &cobra.Command{
+		Use:   "wallet",
+		Short: "Manage wallets and send transfers",
+	}
+	cmd.PersistentFlags().StringVar(&walletDir, "wallet-dir", defaultWalletDir(), "the directory where wallets are stored.")
+
+	completeWallets := func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		wallets, _ := wallet.List(walletDir)
+		names := make([]string, 0, len(wallets))
+		for _, w := range wallets {
+			names = append(names, w.Name)
+		}
+
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "new name",
+			Short: "Create a new wallet",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				w, err := wallet.NewWallet(args[0])
+				if err != nil {
+					return err
+				}
+
+				if err := w.Save(walletDir); err != nil {
+					return err
+				}
+
+				fmt.Fprintln(cmd.OutOrStdout(), w.Address())
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "list",
+			Short: "List the wallets and their addresses",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				wallets, err := wallet.List(walletDir)
+				if err != nil {
+					return err
+				}
+
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "NAME\tADDRESS")
+				for _, wal := range wallets {
+					fmt.Fprintf(w, "%s\t%s\n", wal.Name, wal.Address())
+				}
+
+				return w.Flush()
+			},
+		},
+		&cobra.Command{
+			Use:   "send wallet address amount",
+			Short: "Sign a transfer and submit it to the node",
+			Long: `Sign a transfer and submit it to the node.
+
+The chain does not track balances: the transfer is stored as a signed entry
+that anyone can verify.`,
+			Args:              cobra.ExactArgs(3),
+			ValidArgsFunction: completeWallets,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				w, err := wallet.Load(walletDir, args[0])
+				if err != nil {
+					return err
+				}
+
+				amount, err := strconv.ParseUint(args[2], 10, 64)
+				if err != nil {
+					return fmt.Errorf("amount must be a positive integer")
+				}
+
+				transfer, err := w.NewTransfer(args[1], amount)
+				if err != nil {
+					return err
+				}
+
+				entry, err := transfer.Entry()
+				if err != nil {
+					return err
+				}
+
+				if err := opts.newClient().SubmitEntry(cmd.Context(), entry); err != nil {
+					return fmt.Errorf("could not submit transfer: %w", err)
+				}
+
+				fmt.Fprintln(cmd.OutOrStdout(), hex.EncodeToString(block.HashEntry(entry)))
+				return nil
+			},
+		},
+	)
+
+	return cmd
+}
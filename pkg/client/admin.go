@@ -0,0 +1,20 @@
+package client
+
+import (
+	"context"
+
+	"github.com/SunSince90/go-naivecoin/pkg/servers"
+)
+
+// GetPeers returns the peers of the node and the banned ones.
+//
+// This is part of the admin API: the client must be created with the URL of
+// the admin server and its token as API key.
+func (c *Client) GetPeers(ctx context.Context) (*servers.PeersList, error) {
+	var list servers.PeersList
+	if err := c.get(ctx, "/peers", &list); err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
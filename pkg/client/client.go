@@ -0,0 +1,306 @@
+// Package client is a typed client for the public API of full nodes, as
+// described by the OpenAPI document they serve on /openapi.json.
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/SunSince90/go-naivecoin/pkg/servers"
+)
+
+const (
+	defaultRetries      int           = 3
+	defaultRetryBackoff time.Duration = 500 * time.Millisecond
+)
+
+// ErrNotFound is returned when the requested resource does not exist.
+var ErrNotFound = errors.New("not found")
+
+// APIError is returned when the node answers with an unexpected status.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Message is the body of the response, if any.
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+// Client calls the public API of a full node.
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	apiKey       string
+	retries      int
+	retryBackoff time.Duration
+}
+
+// Option is a function that sets up a client.
+type Option func(*Client)
+
+// WithHTTPClient uses the provided HTTP client to make requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey authenticates write requests with the provided API key or JWT.
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithRetries sets how many times a failed request is retried and how long
+// to wait before the first retry. The wait doubles on each retry.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.retryBackoff = backoff
+	}
+}
+
+// NewClient returns a client for the node listening on the provided base
+// URL, e.g. http://localhost:8080.
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		retries:      defaultRetries,
+		retryBackoff: defaultRetryBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// GetChain returns the full chain.
+func (c *Client) GetChain(ctx context.Context) ([]*pb.Block, error) {
+	var blocks []*pb.Block
+	if err := c.get(ctx, "/blocks", &blocks); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// GetBlocks returns a page of at most limit blocks starting from the
+// provided height.
+func (c *Client) GetBlocks(ctx context.Context, from, limit int) (*servers.BlocksPage, error) {
+	query := url.Values{}
+	query.Set("from", strconv.Itoa(from))
+	query.Set("limit", strconv.Itoa(limit))
+
+	var page servers.BlocksPage
+	if err := c.get(ctx, "/blocks?"+query.Encode(), &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// ForEachBlock calls fn for each block starting from the provided height,
+// fetching pages of pageSize blocks as needed. It stops at the first error
+// returned by fn.
+func (c *Client) ForEachBlock(ctx context.Context, from, pageSize int, fn func(*pb.Block) error) error {
+	for {
+		page, err := c.GetBlocks(ctx, from, pageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range page.Blocks {
+			if err := fn(b); err != nil {
+				return err
+			}
+		}
+
+		if page.Next == nil {
+			return nil
+		}
+		from = *page.Next
+	}
+}
+
+// GetBlockByHeight returns the block with the provided height.
+func (c *Client) GetBlockByHeight(ctx context.Context, height int64) (*pb.Block, error) {
+	var b pb.Block
+	if err := c.get(ctx, fmt.Sprintf("/blocks/%d", height), &b); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// GetBlockByHash returns the block with the provided hash.
+func (c *Client) GetBlockByHash(ctx context.Context, hash []byte) (*pb.Block, error) {
+	var b pb.Block
+	if err := c.get(ctx, "/blocks/hash/"+hex.EncodeToString(hash), &b); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// GetChainTip returns the last block of the chain.
+func (c *Client) GetChainTip(ctx context.Context) (*pb.Block, error) {
+	var b pb.Block
+	if err := c.get(ctx, "/chain/tip", &b); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// GetChainStats returns statistics about the chain.
+func (c *Client) GetChainStats(ctx context.Context) (*block.ChainStats, error) {
+	var stats block.ChainStats
+	if err := c.get(ctx, "/chain/stats", &stats); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// GetProof returns the proof that the entry with the provided hash is
+// included in the chain. The proof is not verified.
+func (c *Client) GetProof(ctx context.Context, entryHash []byte) (*pb.MerkleProof, error) {
+	var proof pb.MerkleProof
+	if err := c.get(ctx, "/proofs/"+hex.EncodeToString(entryHash), &proof); err != nil {
+		return nil, err
+	}
+
+	return &proof, nil
+}
+
+// SubmitEntry asks the node to mine a block containing the provided entry.
+//
+// The request is only retried if the node did not process it, i.e. when
+// rate limited.
+func (c *Client) SubmitEntry(ctx context.Context, entry string) error {
+	resp, err := c.do(ctx, http.MethodPost, "/blocks", entry)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	resp, err := c.do(ctx, http.MethodGet, path, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("could not decode response: %w", err)
+	}
+
+	return nil
+}
+
+// do sends the request, retrying it with exponential backoff when it can be
+// safely repeated.
+func (c *Client) do(ctx context.Context, method, path, body string) (*http.Response, error) {
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, body)
+		if attempt >= c.retries || !c.shouldRetry(method, resp, err) {
+			return resp, err
+		}
+
+		wait := backoff
+		if resp != nil {
+			if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(retryAfter) * time.Second
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path, body string) (*http.Response, error) {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "text/plain")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+func (c *Client) shouldRetry(method string, resp *http.Response, err error) bool {
+	if err != nil {
+		// posting may have reached the node already
+		return method == http.MethodGet &&
+			!errors.Is(err, context.Canceled) &&
+			!errors.Is(err, context.DeadlineExceeded)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	return method == http.MethodGet && resp.StatusCode >= http.StatusInternalServerError
+}
+
+func checkResponse(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	}
+
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    string(message),
+	}
+}
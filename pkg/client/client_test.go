@@ -0,0 +1,388 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/mining"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
+	"github.com/SunSince90/go-naivecoin/pkg/servers"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	testClient string = "tester"
+	testAPIKey string = "secret"
+)
+
+// serve serves the Fiber app on a local port until the test ends and returns
+// its base URL.
+func serve(t *testing.T, app *fiber.App) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the server waits for open connections when shutting down, so they
+	// must not be kept alive by the client.
+	app.Server().DisableKeepalive = true
+	go app.Listener(listener)
+	t.Cleanup(func() { app.Shutdown() })
+
+	return "http://" + listener.Addr().String()
+}
+
+// testMiner mines entries right away, and refuses the ones equal to
+// rejectedEntry.
+type testMiner struct {
+	factory    *block.BlockFactory
+	blockchain *block.BlockChain
+	clock      *block.FakeClock
+}
+
+const rejectedEntry string = "rejected"
+
+func (m *testMiner) Mine(_ context.Context, entry string) (*pb.Block, error) {
+	if entry == rejectedEntry {
+		return nil, mining.ErrBlockRejected
+	}
+
+	m.clock.Advance(10 * time.Second)
+	newBlock := m.factory.NewBlock([]string{entry}, m.blockchain.GetLastBlock())
+	if err := m.blockchain.PushBlock(newBlock); err != nil {
+		return nil, err
+	}
+
+	return newBlock, nil
+}
+
+// newTestNode returns a chain with the provided number of blocks after the
+// genesis one and a client of the public server of the node storing it.
+func newTestNode(t *testing.T, blocks int, settings servers.WriteSettings, options ...Option) (*block.BlockChain, *Client) {
+	t.Helper()
+
+	clock := block.NewFakeClock(time.Unix(1600000000, 0))
+	bus := events.NewBus()
+	t.Cleanup(bus.Close)
+	f := block.NewBlockFactory(
+		block.WithGenesis(&block.GenesisSettings{ChainID: "test", Timestamp: clock.Now().Unix()}),
+		block.WithClock(clock),
+		block.WithEventBus(bus),
+	)
+	bc := f.NewBlockChain()
+	miner := &testMiner{factory: f, blockchain: bc, clock: clock}
+	for i := 0; i < blocks; i++ {
+		if _, err := miner.Mine(context.Background(), "entry"+string(rune('a'+i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := servers.NewPublicServer(bc, bus, miner, settings)
+
+	return bc, NewClient(serve(t, server.FiberApp), options...)
+}
+
+func TestReadBlocks(t *testing.T) {
+	bc, c := newTestNode(t, 5, servers.WriteSettings{})
+	ctx := context.Background()
+	chain := bc.GetChain()
+
+	got, err := c.GetChain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(chain) {
+		t.Fatalf("chain has %d blocks, want %d", len(got), len(chain))
+	}
+
+	page, err := c.GetBlocks(ctx, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Blocks) != 2 || page.Blocks[0].Header.Index != 1 || page.Next == nil || *page.Next != 3 {
+		t.Fatalf("unexpected page: %d blocks, next %v", len(page.Blocks), page.Next)
+	}
+
+	heights := []int64{}
+	err = c.ForEachBlock(ctx, 2, 2, func(b *pb.Block) error {
+		heights = append(heights, b.Header.Index)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heights) != 4 || heights[0] != 2 || heights[3] != 5 {
+		t.Fatalf("visited heights %v, want 2 to 5", heights)
+	}
+
+	stop := errors.New("stop")
+	visited := 0
+	err = c.ForEachBlock(ctx, 0, 2, func(b *pb.Block) error {
+		visited++
+		return stop
+	})
+	if !errors.Is(err, stop) || visited != 1 {
+		t.Fatalf("ForEachBlock() error = %v after %d blocks, want to stop after the first one", err, visited)
+	}
+
+	byHeight, err := c.GetBlockByHeight(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(byHeight.Hash, chain[3].Hash) {
+		t.Fatal("block by height is not the one on the chain")
+	}
+
+	byHash, err := c.GetBlockByHash(ctx, chain[2].Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byHash.Header.Index != 2 {
+		t.Fatalf("block by hash has height %d, want 2", byHash.Header.Index)
+	}
+
+	tip, err := c.GetChainTip(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tip.Hash, bc.GetLastBlock().Hash) {
+		t.Fatal("tip is not the last block")
+	}
+
+	stats, err := c.GetChainStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Height != 5 {
+		t.Fatalf("stats height = %d, want 5", stats.Height)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	_, c := newTestNode(t, 1, servers.WriteSettings{})
+	ctx := context.Background()
+
+	if _, err := c.GetBlockByHeight(ctx, 10); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetBlockByHeight() error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := c.GetBlockByHash(ctx, []byte("missing")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetBlockByHash() error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := c.GetProof(ctx, block.HashEntry("missing")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetProof() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestSubmitEntry(t *testing.T) {
+	settings := servers.WriteSettings{
+		Auth: servers.AuthSettings{APIKeys: map[string]string{testClient: testAPIKey}},
+	}
+	ctx := context.Background()
+
+	cases := []struct {
+		name       string
+		settings   servers.WriteSettings
+		options    []Option
+		entry      string
+		wantStatus int
+	}{
+		{
+			name:     "authenticated",
+			settings: settings,
+			options:  []Option{WithAPIKey(testAPIKey)},
+			entry:    "submitted",
+		},
+		{
+			name:       "missing credentials",
+			settings:   settings,
+			entry:      "submitted",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong credentials",
+			settings:   settings,
+			options:    []Option{WithAPIKey("wrong")},
+			entry:      "submitted",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "rejected entry",
+			options:    []Option{WithAPIKey(testAPIKey)},
+			entry:      rejectedEntry,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "payload too large",
+			settings:   servers.WriteSettings{MaxPayloadSize: 8},
+			entry:      "longer than eight bytes",
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "mining disabled",
+			settings:   servers.WriteSettings{DisableMining: true},
+			entry:      "submitted",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bc, client := newTestNode(t, 0, c.settings, c.options...)
+
+			err := client.SubmitEntry(ctx, c.entry)
+			var apiErr *APIError
+			switch {
+			case c.wantStatus == 0 && err != nil:
+				t.Fatalf("unexpected error: %s", err)
+			case c.wantStatus != 0 && (!errors.As(err, &apiErr) || apiErr.StatusCode != c.wantStatus):
+				t.Fatalf("SubmitEntry() error = %v, want status %d", err, c.wantStatus)
+			case c.wantStatus == 0 && bc.Length() != 2:
+				t.Fatalf("chain has %d blocks after submitting, want 2", bc.Length())
+			}
+
+			if c.wantStatus != 0 {
+				return
+			}
+
+			proof, err := client.GetProof(ctx, block.HashEntry(c.entry))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(proof.BlockHash, bc.GetLastBlock().Hash) {
+				t.Fatal("proof is not for the mined block")
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	settings := servers.WriteSettings{RateLimit: 1, RateLimitWindow: time.Hour}
+	_, c := newTestNode(t, 0, settings, WithRetries(0, 0))
+
+	if err := c.SubmitEntry(context.Background(), "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	var apiErr *APIError
+	if err := c.SubmitEntry(context.Background(), "second"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("SubmitEntry() error = %v, want status %d", err, http.StatusTooManyRequests)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	cases := []struct {
+		name string
+		// failures is the number of requests that fail before the node
+		// answers.
+		failures  int32
+		status    int
+		retries   int
+		submit    bool
+		wantCalls int32
+		wantErr   bool
+	}{
+		{
+			name:      "get retried after a server error",
+			failures:  2,
+			status:    http.StatusServiceUnavailable,
+			retries:   3,
+			wantCalls: 3,
+		},
+		{
+			name:      "get retried until the retries are over",
+			failures:  10,
+			status:    http.StatusInternalServerError,
+			retries:   2,
+			wantCalls: 3,
+			wantErr:   true,
+		},
+		{
+			name:      "post not retried after a server error",
+			failures:  1,
+			status:    http.StatusServiceUnavailable,
+			retries:   3,
+			submit:    true,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "post retried when rate limited",
+			failures:  1,
+			status:    http.StatusTooManyRequests,
+			retries:   3,
+			submit:    true,
+			wantCalls: 2,
+		},
+		{
+			name:      "get not retried after a client error",
+			failures:  1,
+			status:    http.StatusBadRequest,
+			retries:   3,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			calls := int32(0)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) <= c.failures {
+					w.WriteHeader(c.status)
+					return
+				}
+
+				w.Write([]byte(`{"height": 1}`))
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, WithRetries(c.retries, time.Millisecond))
+			var err error
+			if c.submit {
+				err = client.SubmitEntry(context.Background(), "entry")
+			} else {
+				_, err = client.GetChainStats(context.Background())
+			}
+
+			if (err != nil) != c.wantErr {
+				t.Fatalf("error = %v, wantErr %t", err, c.wantErr)
+			}
+			if got := atomic.LoadInt32(&calls); got != c.wantCalls {
+				t.Fatalf("node was called %d times, want %d", got, c.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGetPeers(t *testing.T) {
+	f := block.NewBlockFactory()
+	manager := peers.NewPeersManager(f.NewBlockChain())
+	manager.BanPeer("banned")
+	admin := servers.NewAdminServer("token", manager, nil, nil)
+	url := serve(t, admin.FiberApp)
+
+	list, err := NewClient(url, WithAPIKey("token")).GetPeers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Peers) != 0 || len(list.Banned) != 1 || list.Banned[0] != "banned" {
+		t.Fatalf("unexpected peers list: %+v", list)
+	}
+
+	var apiErr *APIError
+	_, err = NewClient(url, WithAPIKey("wrong")).GetPeers(context.Background())
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("GetPeers() error = %v, want status %d", err, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// DiscoveryMode is the kind of objects peers are discovered from.
+type DiscoveryMode string
+
+const (
+	// DiscoveryPods discovers peers from the running pods matching a label
+	// selector.
+	DiscoveryPods DiscoveryMode = "pods"
+	// DiscoveryEndpointSlices discovers peers from the ready endpoints of a
+	// headless Service.
+	DiscoveryEndpointSlices DiscoveryMode = "endpointslices"
+)
+
+const (
+	// DefaultPeerSelector is the label selector of the pods of the nodes.
+	DefaultPeerSelector string = "app=go-naivecoin"
+	// DefaultPortName is the name of the port that serves peer
+	// communications.
+	DefaultPortName string = "grpc"
+)
+
+// DiscoverySettings tell how and where to look for peers.
+type DiscoverySettings struct {
+	// Mode is the kind of objects peers are discovered from.
+	Mode DiscoveryMode
+	// Selector is the label selector of the pods that are peers. It is only
+	// used with DiscoveryPods.
+	Selector string
+	// Namespaces where peers are looked for. If empty, only the namespace
+	// of the node is.
+	Namespaces []string
+	// Service is the name of the headless Service whose endpoints are
+	// peers. It is only used with DiscoveryEndpointSlices.
+	Service string
+	// PortName is the name of the port that serves peer communications, in
+	// the containers of the pods or in the EndpointSlices. Peers without
+	// such port are reached on peers.DefaultPort.
+	PortName string
+}
+
+// NewPeerReconciler creates the controller that discovers peers as described
+// by the settings, and sends events about them to peerEvents.
+func NewPeerReconciler(mgr manager.Manager, settings DiscoverySettings, peerEvents chan *peers.PeerEvent) error {
+	if settings.PortName == "" {
+		settings.PortName = DefaultPortName
+	}
+
+	switch settings.Mode {
+	case DiscoveryPods, "":
+		_, err := NewPodReconciler(mgr, settings, peerEvents)
+		return err
+	case DiscoveryEndpointSlices:
+		_, err := NewEndpointSliceReconciler(mgr, settings, peerEvents)
+		return err
+	default:
+		return fmt.Errorf("unknown discovery mode %q", settings.Mode)
+	}
+}
+
+// podIdentity returns the name and the namespace of the pod running this
+// node.
+func podIdentity() (string, string, error) {
+	name := os.Getenv("NAME")
+	if name == "" {
+		return "", "", fmt.Errorf("could not retrieve pod name")
+	}
+
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		return "", "", fmt.Errorf("could not get namespace from environment variable")
+	}
+
+	return name, namespace, nil
+}
+
+// peerName returns the name of the peer running in the provided pod: when
+// watching multiple namespaces pods may have the same name, so the namespace
+// is added to it.
+func peerName(settings DiscoverySettings, namespace, name string) string {
+	if len(settings.Namespaces) <= 1 {
+		return name
+	}
+
+	return fmt.Sprintf("%s.%s", name, namespace)
+}
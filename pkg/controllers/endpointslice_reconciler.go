@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// EndpointSliceReconciler discovers peers from the EndpointSlices of a
+// headless Service: unlike pods, endpoints are only ready when the node is, so
+// nodes that are still syncing are not used as peers.
+type EndpointSliceReconciler struct {
+	client.Client
+	myself    string
+	namespace string
+	settings  DiscoverySettings
+	tracker   *peerTracker
+}
+
+// NewEndpointSliceReconciler creates the controller that discovers peers from
+// the EndpointSlices of the Service in the settings.
+func NewEndpointSliceReconciler(mgr manager.Manager, settings DiscoverySettings, peerEvents chan *peers.PeerEvent) (*EndpointSliceReconciler, error) {
+	myself, namespace, err := podIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	if mgr == nil {
+		return nil, fmt.Errorf("nil manager provided")
+	}
+
+	if settings.Service == "" {
+		return nil, fmt.Errorf("no service provided")
+	}
+
+	er := &EndpointSliceReconciler{
+		Client:    mgr.GetClient(),
+		myself:    myself,
+		namespace: namespace,
+		settings:  settings,
+		tracker:   newPeerTracker(peerEvents),
+	}
+
+	c, err := newUnelectedController("endpointslice-controller", mgr, controller.Options{
+		Reconciler: er,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A Service may have many EndpointSlices, so they are reconciled
+	// together by enqueuing the Service they belong to.
+	err = c.Watch(&source.Kind{Type: &discoveryv1.EndpointSlice{}},
+		handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+			return []reconcile.Request{{
+				NamespacedName: types.NamespacedName{
+					Namespace: obj.GetNamespace(),
+					Name:      obj.GetLabels()[discoveryv1.LabelServiceName],
+				},
+			}}
+		}),
+		predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetLabels()[discoveryv1.LabelServiceName] == settings.Service
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return er, nil
+}
+
+// Reconcile announces the ready endpoints of the Service as peers and removes
+// the ones that are not ready anymore.
+func (e *EndpointSliceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var slices discoveryv1.EndpointSliceList
+	err := e.List(ctx, &slices,
+		client.InNamespace(req.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: req.Name},
+	)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	src := req.NamespacedName.String()
+	ready := map[string]bool{}
+
+	for _, slice := range slices.Items {
+		if slice.AddressType != discoveryv1.AddressTypeIPv4 && slice.AddressType != discoveryv1.AddressTypeIPv6 {
+			continue
+		}
+		port := endpointPort(slice.Ports, e.settings.PortName)
+
+		for _, endpoint := range slice.Endpoints {
+			// a nil condition means that readiness is unknown, and it
+			// should be interpreted as ready.
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			if len(endpoint.Addresses) == 0 {
+				continue
+			}
+
+			name, uid := endpointIdentity(endpoint)
+			if name == e.myself && req.Namespace == e.namespace {
+				continue
+			}
+
+			peer := &peers.Peer{
+				Name: peerName(e.settings, req.Namespace, name),
+				IP:   endpoint.Addresses[0],
+				Port: port,
+				UID:  uid,
+			}
+			ready[peer.Name] = true
+
+			// Endpoints of a headless Service have a hostname when they
+			// belong to a StatefulSet governed by it.
+			e.tracker.found(peer, src, endpoint.Hostname != nil)
+		}
+	}
+
+	result := ctrl.Result{}
+	for _, name := range e.tracker.namesFrom(src) {
+		if ready[name] {
+			continue
+		}
+
+		if res := e.tracker.gone(name); res.RequeueAfter > 0 && (result.RequeueAfter == 0 || res.RequeueAfter < result.RequeueAfter) {
+			result = res
+		}
+	}
+
+	return result, nil
+}
+
+// endpointPort returns the number of the port with the provided name, or 0 if
+// there is none.
+func endpointPort(ports []discoveryv1.EndpointPort, name string) int32 {
+	for _, port := range ports {
+		if port.Name != nil && *port.Name == name && port.Port != nil {
+			return *port.Port
+		}
+	}
+
+	return 0
+}
+
+// endpointIdentity returns the name and the UID of the pod behind the
+// endpoint. Endpoints that are not pods are named after their hostname or
+// address.
+func endpointIdentity(endpoint discoveryv1.Endpoint) (string, string) {
+	if ref := endpoint.TargetRef; ref != nil && ref.Kind == "Pod" {
+		return ref.Name, string(ref.UID)
+	}
+
+	if endpoint.Hostname != nil {
+		return *endpoint.Hostname, ""
+	}
+
+	return endpoint.Addresses[0], ""
+}
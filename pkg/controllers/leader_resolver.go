@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// MinerLeaseName is the name of the Lease held by the node that is
+	// elected to mine blocks.
+	MinerLeaseName string = "naivecoin-miner"
+)
+
+// LeaderResolver finds the node that holds the MinerLeaseName Lease.
+type LeaderResolver struct {
+	reader    client.Reader
+	myself    string
+	namespace string
+	settings  DiscoverySettings
+}
+
+// NewLeaderResolver creates and returns a new instance of the LeaderResolver.
+// Objects are read directly from the API server, so that leaders are never
+// looked for in a stale cache.
+func NewLeaderResolver(mgr manager.Manager, settings DiscoverySettings) (*LeaderResolver, error) {
+	myself, namespace, err := podIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.PortName == "" {
+		settings.PortName = DefaultPortName
+	}
+
+	return &LeaderResolver{
+		reader:    mgr.GetAPIReader(),
+		myself:    myself,
+		namespace: namespace,
+		settings:  settings,
+	}, nil
+}
+
+// Leader returns the peer that holds the lease, or nil if this node does.
+func (r *LeaderResolver) Leader(ctx context.Context) (*peers.Peer, error) {
+	var lease coordinationv1.Lease
+	if err := r.reader.Get(ctx, types.NamespacedName{Namespace: r.namespace, Name: MinerLeaseName}, &lease); err != nil {
+		return nil, fmt.Errorf("could not get lease: %w", err)
+	}
+
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" {
+		return nil, fmt.Errorf("no leader is elected")
+	}
+	if lease.Spec.RenewTime != nil && lease.Spec.LeaseDurationSeconds != nil {
+		expiration := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+		if time.Now().After(expiration) {
+			return nil, fmt.Errorf("lease of the leader expired")
+		}
+	}
+
+	// The identity of the leader is its hostname, i.e. the name of its
+	// pod, followed by a random suffix.
+	name := strings.SplitN(*lease.Spec.HolderIdentity, "_", 2)[0]
+	if name == r.myself {
+		return nil, nil
+	}
+
+	var pod corev1.Pod
+	if err := r.reader.Get(ctx, types.NamespacedName{Namespace: r.namespace, Name: name}, &pod); err != nil {
+		return nil, fmt.Errorf("could not get pod of leader %s: %w", name, err)
+	}
+	if pod.Status.PodIP == "" {
+		return nil, fmt.Errorf("leader %s has no IP", name)
+	}
+
+	return &peers.Peer{
+		Name: peerName(r.settings, r.namespace, name),
+		IP:   pod.Status.PodIP,
+		Port: containerPort(&pod, r.settings.PortName),
+		UID:  string(pod.UID),
+	}, nil
+}
@@ -4,31 +4,111 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/SunSince90/go-naivecoin/pkg/apis/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
-// NewControllerManager creates the pod controller and returns its manager so
-// that it could be started.
-func NewControllerManager() (manager.Manager, error) {
+// NewControllerManager creates the manager of the controllers that discover
+// peers in the provided namespaces, so that it could be started. If no
+// namespaces are provided, only the one of the node is watched.
+//
+// If leaderElection is true, the nodes elect a leader through the
+// MinerLeaseName Lease, and the runnables added to the manager only run on it.
+// Controllers that discover peers run on all nodes anyways.
+func NewControllerManager(namespaces []string, leaderElection bool) (manager.Manager, error) {
 	namespace := os.Getenv("NAMESPACE")
 	if namespace == "" {
 		return nil, fmt.Errorf("could not get namespace from environment variable")
 	}
+	if len(namespaces) == 0 {
+		namespaces = []string{namespace}
+	}
 
 	cfg, err := config.GetConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	mgr, err := manager.New(cfg, manager.Options{
-		Namespace:          namespace,
+	opts := manager.Options{
+		Namespace:          namespaces[0],
 		LeaderElection:     false,
 		MetricsBindAddress: "0",
-	})
+	}
+	if len(namespaces) > 1 {
+		opts.Namespace = ""
+		opts.NewCache = cache.MultiNamespacedCacheBuilder(namespaces)
+	}
+	if leaderElection {
+		opts.LeaderElection = true
+		opts.LeaderElectionID = MinerLeaseName
+		opts.LeaderElectionNamespace = namespace
+		opts.LeaderElectionResourceLock = resourcelock.LeasesResourceLock
+		// the next leader can be elected right away when the node stops,
+		// instead of waiting for the lease to expire.
+		opts.LeaderElectionReleaseOnCancel = true
+	}
+
+	mgr, err := manager.New(cfg, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	return mgr, nil
 }
+
+// unelectedController is a controller that runs on all nodes, even when the
+// manager uses leader election.
+type unelectedController struct {
+	controller.Controller
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (unelectedController) NeedLeaderElection() bool {
+	return false
+}
+
+// newUnelectedController creates a controller that runs on all nodes, and adds
+// it to the manager.
+func newUnelectedController(name string, mgr manager.Manager, options controller.Options) (controller.Controller, error) {
+	c, err := controller.NewUnmanaged(name, mgr, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mgr.Add(unelectedController{c}); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// NewOperatorManager returns the manager of the operator, which knows about
+// the v1alpha1 objects. If namespace is empty, networks are managed in all
+// namespaces.
+func NewOperatorManager(namespace string) (manager.Manager, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return manager.New(cfg, manager.Options{
+		Scheme:             scheme,
+		Namespace:          namespace,
+		LeaderElection:     false,
+		MetricsBindAddress: "0",
+	})
+}
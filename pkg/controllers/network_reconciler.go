@@ -0,0 +1,651 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/apis/v1alpha1"
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/servers"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// appLabel and appName are the label that the pod reconciler of the
+	// nodes looks for by default to find their peers.
+	appLabel string = "app"
+	appName  string = "go-naivecoin"
+	// networkLabel is the label with the name of the network of a node.
+	networkLabel string = "naivecoin.io/network"
+	// roleLabel is the label that tells whether a node mines blocks.
+	roleLabel string = "naivecoin.io/role"
+	roleMiner string = "miner"
+	roleNode  string = "node"
+	// settingsHashAnnotation is set on pods with the hash of their settings,
+	// so that they are restarted when the settings change.
+	settingsHashAnnotation string = "naivecoin.io/settings-hash"
+
+	settingsPath          string = "/settings"
+	dataPath              string = "/data"
+	validatorsPath        string = "/validators"
+	consensusSettingsFile string = "consensus-settings.yaml"
+	genesisSettingsFile   string = "genesis.yaml"
+
+	publicPort int32 = 8080
+	probesPort int32 = 8081
+	grpcPort   int32 = 8082
+
+	// statusRefreshInterval is how often the status of a network is updated
+	// when nothing else changes.
+	statusRefreshInterval time.Duration = 30 * time.Second
+)
+
+// NetworkReconciler creates the resources of NaiveCoinNetwork objects and
+// keeps their status up to date.
+type NetworkReconciler struct {
+	client.Client
+	scheme     *runtime.Scheme
+	httpClient *http.Client
+}
+
+// NewNetworkReconciler creates a NetworkReconciler and registers it with the
+// provided manager, whose scheme must include the v1alpha1 objects.
+func NewNetworkReconciler(mgr manager.Manager) (*NetworkReconciler, error) {
+	if mgr == nil {
+		return nil, fmt.Errorf("nil manager provided")
+	}
+
+	nr := &NetworkReconciler{
+		Client:     mgr.GetClient(),
+		scheme:     mgr.GetScheme(),
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+
+	c, err := controller.New("network-controller", mgr, controller.Options{
+		Reconciler: nr,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Watch(&source.Kind{Type: &v1alpha1.NaiveCoinNetwork{}}, &handler.EnqueueRequestForObject{}); err != nil {
+		return nil, err
+	}
+
+	// changes to the resources of a network, e.g. a StatefulSet whose pods
+	// became ready, trigger a reconciliation of the network itself.
+	owned := []client.Object{
+		&appsv1.StatefulSet{},
+		&corev1.Service{},
+		&corev1.ConfigMap{},
+		&corev1.ServiceAccount{},
+		&rbacv1.Role{},
+		&rbacv1.RoleBinding{},
+	}
+	for _, obj := range owned {
+		err := c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestForOwner{
+			OwnerType:    &v1alpha1.NaiveCoinNetwork{},
+			IsController: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nr, nil
+}
+
+// Reconcile creates or updates the resources of a network and its status.
+func (r *NetworkReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var network v1alpha1.NaiveCoinNetwork
+	if err := r.Get(ctx, req.NamespacedName, &network); err != nil {
+		// resources of deleted networks are garbage collected through
+		// their owner references.
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if network.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	settings, err := getNetworkSettings(&network)
+	if err != nil {
+		// the spec must be fixed before trying again.
+		log.Error().Err(err).Str("network", req.String()).Msg("network spec is not valid")
+		return ctrl.Result{}, nil
+	}
+
+	reconcilers := []func(context.Context, *v1alpha1.NaiveCoinNetwork, map[string]string) error{
+		r.reconcileConfigMap,
+		r.reconcileRBAC,
+		r.reconcileServices,
+		r.reconcileStatefulSets,
+	}
+	for _, reconcile := range reconcilers {
+		if err := reconcile(ctx, &network, settings); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.updateStatus(ctx, &network); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: statusRefreshInterval}, nil
+}
+
+// getNetworkSettings returns the settings files of the nodes of a network.
+func getNetworkSettings(network *v1alpha1.NaiveCoinNetwork) (map[string]string, error) {
+	spec := network.Spec
+	if spec.Image == "" {
+		return nil, fmt.Errorf("image is required")
+	}
+	if spec.Replicas < 0 || spec.Miners < 0 {
+		return nil, fmt.Errorf("replicas and miners cannot be negative")
+	}
+
+	builtin, err := block.GetNetwork(getNetworkName(network))
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]string{}
+	if spec.Consensus != nil {
+		consensusSettings := &block.ConsensusSettings{
+			Checkpoints: spec.Consensus.Checkpoints,
+		}
+		if pow := spec.Consensus.ProofOfWork; pow != nil {
+			consensusSettings.ProofOfWork = &block.ProofOfWorkSettings{
+				InitialDifficulty:            pow.InitialDifficulty,
+				BlockGenerationInterval:      pow.BlockGenerationInterval,
+				DifficultyAdjustmentInterval: pow.DifficultyAdjustmentInterval,
+				FixedDifficulty:              pow.FixedDifficulty,
+			}
+		}
+		if poa := spec.Consensus.ProofOfAuthority; poa != nil {
+			consensusSettings.ProofOfAuthority = &block.ProofOfAuthoritySettings{
+				Validators: poa.Validators,
+			}
+		}
+		if finality := spec.Consensus.Finality; finality != nil {
+			consensusSettings.Finality = &block.FinalitySettings{
+				Validators:         finality.Validators,
+				CheckpointInterval: finality.CheckpointInterval,
+			}
+		}
+
+		data, err := yaml.Marshal(consensusSettings)
+		if err != nil {
+			return nil, err
+		}
+		settings[consensusSettingsFile] = string(data)
+	} else if builtin.Consensus == nil {
+		return nil, fmt.Errorf("consensus is required for network %s", builtin.Name)
+	}
+
+	if spec.Genesis != nil {
+		genesisSettings := &block.GenesisSettings{
+			ChainID:    spec.Genesis.ChainID,
+			Timestamp:  spec.Genesis.Timestamp,
+			Data:       spec.Genesis.Data,
+			Difficulty: spec.Genesis.Difficulty,
+		}
+		for _, allocation := range spec.Genesis.Allocations {
+			genesisSettings.Allocations = append(genesisSettings.Allocations, block.Allocation{
+				Address: allocation.Address,
+				Amount:  allocation.Amount,
+			})
+		}
+
+		data, err := yaml.Marshal(genesisSettings)
+		if err != nil {
+			return nil, err
+		}
+		settings[genesisSettingsFile] = string(data)
+	}
+
+	return settings, nil
+}
+
+func getNetworkName(network *v1alpha1.NaiveCoinNetwork) string {
+	if network.Spec.Network == "" {
+		return block.NetworkMainnet
+	}
+
+	return network.Spec.Network
+}
+
+func (r *NetworkReconciler) reconcileConfigMap(ctx context.Context, network *v1alpha1.NaiveCoinNetwork, settings map[string]string) error {
+	cm := &corev1.ConfigMap{ObjectMeta: r.objectMeta(network, "settings")}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		cm.Labels = getNetworkLabels(network)
+		cm.Data = settings
+		return controllerutil.SetControllerReference(network, cm, r.scheme)
+	})
+
+	return err
+}
+
+// reconcileRBAC creates the service account of the nodes, which need to
+// watch pods to find their peers.
+func (r *NetworkReconciler) reconcileRBAC(ctx context.Context, network *v1alpha1.NaiveCoinNetwork, _ map[string]string) error {
+	sa := &corev1.ServiceAccount{ObjectMeta: r.objectMeta(network, "node")}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, sa, func() error {
+		sa.Labels = getNetworkLabels(network)
+		return controllerutil.SetControllerReference(network, sa, r.scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	role := &rbacv1.Role{ObjectMeta: r.objectMeta(network, "node")}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, role, func() error {
+		role.Labels = getNetworkLabels(network)
+		role.Rules = []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{"discovery.k8s.io"},
+				Resources: []string{"endpointslices"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			// needed to elect the designated miner.
+			{
+				APIGroups: []string{"coordination.k8s.io"},
+				Resources: []string{"leases"},
+				Verbs:     []string{"get", "create", "update"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"events"},
+				Verbs:     []string{"create", "patch"},
+			},
+		}
+		return controllerutil.SetControllerReference(network, role, r.scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	binding := &rbacv1.RoleBinding{ObjectMeta: r.objectMeta(network, "node")}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, binding, func() error {
+		binding.Labels = getNetworkLabels(network)
+		binding.RoleRef = rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     role.Name,
+		}
+		binding.Subjects = []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      sa.Name,
+				Namespace: sa.Namespace,
+			},
+		}
+		return controllerutil.SetControllerReference(network, binding, r.scheme)
+	})
+
+	return err
+}
+
+// reconcileServices creates the services of the network:
+//   - a headless one, named after the network, for the StatefulSets
+//   - <name>-public, to read from any node
+//   - <name>-miners, to submit entries to the miners
+func (r *NetworkReconciler) reconcileServices(ctx context.Context, network *v1alpha1.NaiveCoinNetwork, _ map[string]string) error {
+	headless := &corev1.Service{ObjectMeta: r.objectMeta(network, "")}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, headless, func() error {
+		headless.Labels = getNetworkLabels(network)
+		if headless.CreationTimestamp.IsZero() {
+			headless.Spec.ClusterIP = corev1.ClusterIPNone
+		}
+		headless.Spec.Selector = getNetworkLabels(network)
+		// peers must find each other before being ready, as they are
+		// ready only once synced with them.
+		headless.Spec.PublishNotReadyAddresses = true
+		headless.Spec.Ports = []corev1.ServicePort{
+			newServicePort("public", publicPort),
+			newServicePort("grpc", grpcPort),
+		}
+		return controllerutil.SetControllerReference(network, headless, r.scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	services := map[string]map[string]string{
+		"public": getNetworkLabels(network),
+		"miners": getRoleLabels(network, roleMiner),
+	}
+	for suffix, selector := range services {
+		svc := &corev1.Service{ObjectMeta: r.objectMeta(network, suffix)}
+		selector := selector
+		_, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+			svc.Labels = getNetworkLabels(network)
+			svc.Spec.Selector = selector
+			svc.Spec.Ports = []corev1.ServicePort{newServicePort("public", publicPort)}
+			return controllerutil.SetControllerReference(network, svc, r.scheme)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newServicePort(name string, port int32) corev1.ServicePort {
+	return corev1.ServicePort{
+		Name:       name,
+		Port:       port,
+		TargetPort: intstr.FromInt(int(port)),
+		Protocol:   corev1.ProtocolTCP,
+	}
+}
+
+// reconcileStatefulSets creates a StatefulSet for the miners and one for the
+// other nodes.
+func (r *NetworkReconciler) reconcileStatefulSets(ctx context.Context, network *v1alpha1.NaiveCoinNetwork, settings map[string]string) error {
+	// the hash is calculated on sorted keys, as map order is random.
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte(settings[key]))
+	}
+	settingsHash := hex.EncodeToString(hash.Sum(nil))
+
+	replicas := map[string]int32{
+		roleMiner: network.Spec.Miners,
+		roleNode:  network.Spec.Replicas,
+	}
+	for role, count := range replicas {
+		sts := &appsv1.StatefulSet{ObjectMeta: r.objectMeta(network, role)}
+		role, count := role, count
+
+		_, err := controllerutil.CreateOrUpdate(ctx, r.Client, sts, func() error {
+			sts.Labels = getNetworkLabels(network)
+			sts.Spec.Replicas = &count
+
+			// the selector and the volume claim templates cannot be
+			// changed after creation.
+			if sts.CreationTimestamp.IsZero() {
+				sts.Spec.ServiceName = network.Name
+				sts.Spec.Selector = &metav1.LabelSelector{MatchLabels: getRoleLabels(network, role)}
+				sts.Spec.PodManagementPolicy = appsv1.ParallelPodManagement
+				if storage := network.Spec.Storage; storage != nil {
+					sts.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "data"},
+							Spec: corev1.PersistentVolumeClaimSpec{
+								AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+								StorageClassName: storage.StorageClassName,
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{corev1.ResourceStorage: storage.Size},
+								},
+							},
+						},
+					}
+				}
+			}
+
+			podLabels := getRoleLabels(network, role)
+			podLabels[appLabel] = appName
+			sts.Spec.Template.Labels = podLabels
+			sts.Spec.Template.Annotations = map[string]string{settingsHashAnnotation: settingsHash}
+			sts.Spec.Template.Spec = r.getPodSpec(network, role, settings, len(sts.Spec.VolumeClaimTemplates) > 0)
+
+			return controllerutil.SetControllerReference(network, sts, r.scheme)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *NetworkReconciler) getPodSpec(network *v1alpha1.NaiveCoinNetwork, role string, settings map[string]string, withStorage bool) corev1.PodSpec {
+	// nodes of other networks in the same namespace are not peers.
+	peerSelector := labels.SelectorFromSet(labels.Set{appLabel: appName, networkLabel: network.Name})
+	args := []string{"--network", getNetworkName(network), "--peer-selector", peerSelector.String()}
+	if _, exists := settings[consensusSettingsFile]; exists {
+		args = append(args, "--consensus-settings", settingsPath+"/"+consensusSettingsFile)
+	}
+	if _, exists := settings[genesisSettingsFile]; exists {
+		args = append(args, "--genesis", settingsPath+"/"+genesisSettingsFile)
+	}
+	if network.Spec.BootstrapFile != "" {
+		args = append(args, "--bootstrap-file", network.Spec.BootstrapFile)
+	}
+	if role != roleMiner {
+		args = append(args, "--mine=false")
+	} else if network.Spec.DesignatedMiner {
+		args = append(args, "--designated-miner")
+	}
+	walletsSecret := getWalletsSecret(network)
+	if role == roleMiner && walletsSecret != "" {
+		// NAME is expanded by Kubernetes from the environment.
+		args = append(args, "--validator-wallet", validatorsPath+"/$(NAME).json")
+	}
+
+	fieldEnv := func(name, fieldPath string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: fieldPath},
+			},
+		}
+	}
+
+	probe := func(path string) *corev1.Probe {
+		return &corev1.Probe{
+			Handler: corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path:   path,
+					Port:   intstr.FromInt(int(probesPort)),
+					Scheme: corev1.URISchemeHTTP,
+				},
+			},
+			PeriodSeconds:    10,
+			TimeoutSeconds:   1,
+			SuccessThreshold: 1,
+			FailureThreshold: 3,
+		}
+	}
+
+	container := corev1.Container{
+		Name:  "naivecoin",
+		Image: network.Spec.Image,
+		Args:  args,
+		Env: []corev1.EnvVar{
+			fieldEnv("NAME", "metadata.name"),
+			fieldEnv("NAMESPACE", "metadata.namespace"),
+			fieldEnv("IP", "status.podIP"),
+		},
+		Ports: []corev1.ContainerPort{
+			{Name: "public", ContainerPort: publicPort, Protocol: corev1.ProtocolTCP},
+			{Name: "probes", ContainerPort: probesPort, Protocol: corev1.ProtocolTCP},
+			{Name: "grpc", ContainerPort: grpcPort, Protocol: corev1.ProtocolTCP},
+		},
+		ReadinessProbe: probe("/readyz"),
+		LivenessProbe:  probe("/healthz"),
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "settings", MountPath: settingsPath, ReadOnly: true},
+		},
+	}
+	if withStorage {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: "data", MountPath: dataPath})
+	}
+
+	volumes := []corev1.Volume{
+		{
+			Name: "settings",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: network.Name + "-settings"},
+				},
+			},
+		},
+	}
+	if role == roleMiner && walletsSecret != "" {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: "validators", MountPath: validatorsPath, ReadOnly: true})
+		volumes = append(volumes, corev1.Volume{
+			Name: "validators",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: walletsSecret},
+			},
+		})
+	}
+
+	return corev1.PodSpec{
+		ServiceAccountName: network.Name + "-node",
+		Containers:         []corev1.Container{container},
+		Volumes:            volumes,
+	}
+}
+
+// getWalletsSecret returns the name of the Secret with the wallets of the
+// validators, if the network uses proof of authority or finality.
+func getWalletsSecret(network *v1alpha1.NaiveCoinNetwork) string {
+	consensus := network.Spec.Consensus
+	if consensus == nil {
+		return ""
+	}
+
+	if consensus.ProofOfAuthority != nil && consensus.ProofOfAuthority.WalletsSecret != "" {
+		return consensus.ProofOfAuthority.WalletsSecret
+	}
+
+	if consensus.Finality != nil {
+		return consensus.Finality.WalletsSecret
+	}
+
+	return ""
+}
+
+// updateStatus asks each node of the network for its sync status and
+// reports it in the status of the network.
+func (r *NetworkReconciler) updateStatus(ctx context.Context, network *v1alpha1.NaiveCoinNetwork) error {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(network.Namespace), client.MatchingLabels(getNetworkLabels(network))); err != nil {
+		return err
+	}
+
+	status := v1alpha1.NaiveCoinNetworkStatus{
+		ObservedGeneration: network.Generation,
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		details, err := r.getSyncDetails(ctx, pod.Status.PodIP)
+		if err != nil {
+			log.Debug().Err(err).Str("pod", pod.Name).Msg("could not get sync status of node")
+			continue
+		}
+
+		status.Nodes = append(status.Nodes, v1alpha1.NodeStatus{
+			Name:   pod.Name,
+			Miner:  pod.Labels[roleLabel] == roleMiner,
+			Ready:  details.Ready,
+			Height: details.Height,
+			Peers:  details.Peers,
+		})
+		if details.Ready {
+			status.ReadyNodes++
+		}
+		if details.Height > status.Height {
+			status.Height = details.Height
+		}
+	}
+	sort.Slice(status.Nodes, func(i, j int) bool {
+		return status.Nodes[i].Name < status.Nodes[j].Name
+	})
+
+	if equality.Semantic.DeepEqual(network.Status, status) {
+		return nil
+	}
+
+	network.Status = status
+	return r.Status().Update(ctx, network)
+}
+
+func (r *NetworkReconciler) getSyncDetails(ctx context.Context, ip string) (*servers.SyncDetails, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s:%d/syncz", ip, probesPort), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var details servers.SyncDetails
+	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
+		return nil, err
+	}
+	if details.SyncStatus == nil {
+		return nil, fmt.Errorf("sync status is missing")
+	}
+
+	return &details, nil
+}
+
+// objectMeta returns the metadata of a resource of the network, whose name is
+// the one of the network followed by the suffix, if any.
+func (r *NetworkReconciler) objectMeta(network *v1alpha1.NaiveCoinNetwork, suffix string) metav1.ObjectMeta {
+	name := network.Name
+	if suffix != "" {
+		name += "-" + suffix
+	}
+
+	return metav1.ObjectMeta{Name: name, Namespace: network.Namespace}
+}
+
+func getNetworkLabels(network *v1alpha1.NaiveCoinNetwork) map[string]string {
+	return map[string]string{networkLabel: network.Name}
+}
+
+func getRoleLabels(network *v1alpha1.NaiveCoinNetwork, role string) map[string]string {
+	labels := getNetworkLabels(network)
+	labels[roleLabel] = role
+	return labels
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
+	"github.com/rs/zerolog/log"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// statefulPeerGracePeriod is how long a peer run by a StatefulSet is
+	// kept after its pod stops running: the pod is going to be scheduled
+	// again with the same name, so the peer is updated rather than removed
+	// and added again, and it keeps its score.
+	statefulPeerGracePeriod = 2 * time.Minute
+)
+
+// knownPeer is a peer that was announced on the peer events channel.
+type knownPeer struct {
+	peer        *peers.Peer
+	statefulSet bool
+	// source is what the peer was discovered from, e.g. a Service.
+	source string
+	// goneSince is when the pod of the peer stopped running, if we are
+	// waiting for it to be scheduled again.
+	goneSince time.Time
+}
+
+// peerTracker remembers the peers announced on the peer events channel, so
+// that a peer changing address is announced as updated rather than new.
+type peerTracker struct {
+	peerEvents chan *peers.PeerEvent
+	known      map[string]*knownPeer
+	lock       sync.Mutex
+}
+
+func newPeerTracker(peerEvents chan *peers.PeerEvent) *peerTracker {
+	return &peerTracker{
+		peerEvents: peerEvents,
+		known:      map[string]*knownPeer{},
+		lock:       sync.Mutex{},
+	}
+}
+
+// found announces a running peer, as a new one or as an updated one if a peer
+// with the same name was known with a different address.
+func (t *peerTracker) found(peer *peers.Peer, source string, statefulSet bool) {
+	t.lock.Lock()
+	known, exists := t.known[peer.Name]
+	eventType := peers.EventNewPeer
+	if exists {
+		if known.peer.UID == peer.UID && known.peer.Address() == peer.Address() {
+			// nothing changed
+			known.goneSince = time.Time{}
+			t.lock.Unlock()
+			return
+		}
+
+		eventType = peers.EventUpdatedPeer
+	}
+
+	t.known[peer.Name] = &knownPeer{
+		peer:        peer,
+		statefulSet: statefulSet,
+		source:      source,
+	}
+	t.lock.Unlock()
+
+	if eventType == peers.EventUpdatedPeer {
+		log.Info().Str("peer-name", peer.Name).Str("old-address", known.peer.Address()).Str("peer-address", peer.Address()).Msg("peer changed address")
+	} else {
+		log.Info().Str("peer-name", peer.Name).Str("peer-address", peer.Address()).Msg("found new peer")
+	}
+
+	t.peerEvents <- &peers.PeerEvent{
+		EventType: eventType,
+		Peer:      peer,
+	}
+}
+
+// gone removes a peer that is not running anymore. Peers of a StatefulSet are
+// only removed if they are not found again within statefulPeerGracePeriod, so
+// the returned result requeues the peer until then.
+func (t *peerTracker) gone(name string) ctrl.Result {
+	t.lock.Lock()
+	known, exists := t.known[name]
+	if !exists {
+		t.lock.Unlock()
+		return ctrl.Result{}
+	}
+
+	if known.statefulSet {
+		if known.goneSince.IsZero() {
+			known.goneSince = time.Now()
+		}
+
+		if waiting := time.Since(known.goneSince); waiting < statefulPeerGracePeriod {
+			t.lock.Unlock()
+			log.Info().Str("peer-name", name).Msg("waiting for peer to be scheduled again...")
+			return ctrl.Result{RequeueAfter: statefulPeerGracePeriod - waiting}
+		}
+	}
+
+	delete(t.known, name)
+	t.lock.Unlock()
+
+	// The UID tells the peers manager which pod is dead, in case a new one
+	// with the same name was already found.
+	log.Info().Str("peer-name", name).Str("peer-address", known.peer.Address()).Msg("removing peer...")
+	t.peerEvents <- &peers.PeerEvent{
+		EventType: peers.EventDeadPeer,
+		Peer: &peers.Peer{
+			Name: name,
+			IP:   known.peer.IP,
+			Port: known.peer.Port,
+			UID:  known.peer.UID,
+		},
+	}
+
+	return ctrl.Result{}
+}
+
+// namesFrom returns the names of the known peers discovered from source.
+func (t *peerTracker) namesFrom(source string) []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	names := []string{}
+	for name, known := range t.known {
+		if known.source == source {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
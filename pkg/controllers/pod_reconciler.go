@@ -3,12 +3,13 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"os"
 	"sync"
 
 	"github.com/SunSince90/go-naivecoin/pkg/peers"
 	"github.com/rs/zerolog/log"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -20,29 +21,43 @@ import (
 
 type PodReconciler struct {
 	client.Client
-	myself     string
-	peerEvents chan *peers.PeerEvent
-	lock       sync.Mutex
+	myself    string
+	namespace string
+	settings  DiscoverySettings
+	selector  labels.Selector
+	tracker   *peerTracker
+	lock      sync.Mutex
 }
 
-func NewPodReconciler(mgr manager.Manager, peerEvents chan *peers.PeerEvent) (*PodReconciler, error) {
-	myself := os.Getenv("NAME")
-	if myself == "" {
-		return nil, fmt.Errorf("could not retrieve pod name")
+func NewPodReconciler(mgr manager.Manager, settings DiscoverySettings, peerEvents chan *peers.PeerEvent) (*PodReconciler, error) {
+	myself, namespace, err := podIdentity()
+	if err != nil {
+		return nil, err
 	}
 
 	if mgr == nil {
 		return nil, fmt.Errorf("nil manager provided")
 	}
 
+	if settings.Selector == "" {
+		settings.Selector = DefaultPeerSelector
+	}
+	selector, err := labels.Parse(settings.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse peer selector: %w", err)
+	}
+
 	pr := &PodReconciler{
-		Client:     mgr.GetClient(),
-		myself:     myself,
-		peerEvents: peerEvents,
-		lock:       sync.Mutex{},
+		Client:    mgr.GetClient(),
+		myself:    myself,
+		namespace: namespace,
+		settings:  settings,
+		selector:  selector,
+		tracker:   newPeerTracker(peerEvents),
+		lock:      sync.Mutex{},
 	}
 
-	c, err := controller.New("pod-controller", mgr, controller.Options{
+	c, err := newUnelectedController("pod-controller", mgr, controller.Options{
 		Reconciler: pr,
 	})
 	if err != nil {
@@ -60,37 +75,53 @@ func NewPodReconciler(mgr manager.Manager, peerEvents chan *peers.PeerEvent) (*P
 
 func (p *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	var pod corev1.Pod
+	name := peerName(p.settings, req.Namespace, req.Name)
 
 	err := p.Get(ctx, req.NamespacedName, &pod)
 	if err != nil {
 		if client.IgnoreNotFound(err) != nil {
-			// We remove peers when they are in deletion phase anyways,
-			// so we don't really need to do anything here.
-			return ctrl.Result{}, client.IgnoreNotFound(err)
+			return ctrl.Result{}, err
 		}
 
-		return ctrl.Result{}, err
+		// We usually remove peers when they are in deletion phase, but
+		// peers of a StatefulSet may still be waiting for their pod to be
+		// scheduled again.
+		return p.tracker.gone(name), nil
+	}
+
+	if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+		return p.tracker.gone(name), nil
 	}
 
 	peer := &peers.Peer{
-		Name: pod.Name,
+		Name: name,
 		IP:   pod.Status.PodIP,
+		Port: containerPort(&pod, p.settings.PortName),
+		UID:  string(pod.UID),
 	}
-	eventType := peers.EventNewPeer
+	p.tracker.found(peer, "", isStatefulSetPod(&pod))
+	return ctrl.Result{}, nil
+}
 
-	if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
-		log.Info().Str("peer-name", peer.Name).Str("peer-ip", peer.IP).Msg("removing peer...")
-		eventType = peers.EventDeadPeer
-	} else {
-		log.Info().Str("peer-name", peer.Name).Str("peer-ip", peer.IP).Msg("found new peer")
+// containerPort returns the number of the port with the provided name in the
+// containers of the pod, or 0 if there is none.
+func containerPort(pod *corev1.Pod, name string) int32 {
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == name {
+				return port.ContainerPort
+			}
+		}
 	}
 
-	p.peerEvents <- &peers.PeerEvent{
-		EventType: eventType,
-		Peer:      peer,
-	}
+	return 0
+}
 
-	return ctrl.Result{}, nil
+// isStatefulSetPod returns true if the pod is controlled by a StatefulSet,
+// i.e. it will keep its name when scheduled again.
+func isStatefulSetPod(pod *corev1.Pod) bool {
+	owner := metav1.GetControllerOf(pod)
+	return owner != nil && owner.Kind == "StatefulSet"
 }
 
 // Create handles pod Create events.
@@ -140,6 +171,10 @@ func (p *PodReconciler) Update(ev event.UpdateEvent) bool {
 		return prevPod.DeletionTimestamp == nil
 	}
 
+	if currPod.Status.PodIP != prevPod.Status.PodIP {
+		return true
+	}
+
 	if currPod.Status.Phase == prevPod.Status.Phase {
 		return false
 	}
@@ -166,11 +201,11 @@ func (p *PodReconciler) Delete(ev event.DeleteEvent) bool {
 }
 
 func (p *PodReconciler) commonPredicates(pod *corev1.Pod) bool {
-	if pod.Name == p.myself {
+	if pod.Name == p.myself && pod.Namespace == p.namespace {
 		return false
 	}
 
-	if val, exists := pod.Labels["app"]; !exists || val != "go-naivecoin" {
+	if !p.selector.Matches(labels.Set(pod.Labels)) {
 		return false
 	}
 
@@ -0,0 +1,130 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/rs/zerolog/log"
+)
+
+// BlockEventType represents a type of event that could occur on the chain,
+// i.e. when a new block is accepted or when the chain is replaced.
+type BlockEventType string
+
+const (
+	// EventBlockAccepted represents an event about a new block pushed to the
+	// chain, regardless of who generated it.
+	EventBlockAccepted BlockEventType = "BLOCK_ACCEPTED"
+	// EventBlockMined represents an event about a block mined by this node.
+	// It always follows the EventBlockAccepted of the same block.
+	EventBlockMined BlockEventType = "BLOCK_MINED"
+	// EventBlockSubmitted represents an event about a block mined elsewhere
+	// that a client submitted to this node. It always follows the
+	// EventBlockAccepted of the same block.
+	EventBlockSubmitted BlockEventType = "BLOCK_SUBMITTED"
+	// EventReorg represents an event about the chain being replaced with a
+	// peer's one. The block is the new last block.
+	EventReorg BlockEventType = "REORG"
+	// EventBlockFinalized represents an event about a block being finalized:
+	// the chain can never be replaced with one that does not include it.
+	EventBlockFinalized BlockEventType = "BLOCK_FINALIZED"
+)
+
+// BlockEvent is a structure that is delivered to subscribers.
+type BlockEvent struct {
+	// EventType is the type of the event occurring on the chain.
+	EventType BlockEventType `json:"type"`
+	// Block that caused this event.
+	Block *pb.Block `json:"block"`
+}
+
+// Bus delivers block events to all its subscribers.
+//
+// Publishing never blocks: if a subscriber is too slow and its channel is
+// full, the event is dropped for that subscriber.
+type Bus struct {
+	subscribers map[int]chan *BlockEvent
+	nextID      int
+	closed      bool
+	lock        sync.Mutex
+}
+
+// NewBus creates and returns a new instance of the Bus.
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: map[int]chan *BlockEvent{},
+		lock:        sync.Mutex{},
+	}
+}
+
+// Publish delivers the event to all subscribers.
+func (b *Bus) Publish(eventType BlockEventType, block *pb.Block) {
+	if b == nil {
+		return
+	}
+
+	ev := &BlockEvent{
+		EventType: eventType,
+		Block:     block,
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	for id, sub := range b.subscribers {
+		select {
+		case sub <- ev:
+		default:
+			log.Warn().Int("subscriber", id).Str("event", string(eventType)).Msg("subscriber is too slow, dropping event")
+		}
+	}
+}
+
+// Subscribe returns a channel where events will be delivered to and an id
+// that must be used to unsubscribe. The channel is closed when
+// unsubscribing or when the bus is closed.
+func (b *Bus) Subscribe(size int) (int, chan *BlockEvent) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	ch := make(chan *BlockEvent, size)
+	if b.closed {
+		close(ch)
+		return -1, ch
+	}
+
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = ch
+
+	return id, ch
+}
+
+// Unsubscribe removes the subscriber with the provided id and closes its
+// channel.
+func (b *Bus) Unsubscribe(id int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if sub, exists := b.subscribers[id]; exists {
+		delete(b.subscribers, id)
+		close(sub)
+	}
+}
+
+// Close closes the channels of all subscribers and prevents new events from
+// being published.
+func (b *Bus) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.closed = true
+	for id, sub := range b.subscribers {
+		delete(b.subscribers, id)
+		close(sub)
+	}
+}
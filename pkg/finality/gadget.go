@@ -0,0 +1,419 @@
+// Package finality finalizes checkpoint blocks with the votes of a set of
+// validators, so that the chain can never be replaced below them, regardless
+// of the consensus used to create blocks.
+//
+// Votes happen in two steps, as in Tendermint: each validator prevotes the
+// checkpoint block it has on its chain, and precommits it once more than two
+// thirds of the validators prevoted the same block. A checkpoint is finalized
+// when more than two thirds of the validators precommitted it. Validators
+// never vote two different blocks at the same height, so two conflicting
+// checkpoints can only be finalized if more than a third of them misbehave.
+package finality
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/rs/zerolog/log"
+)
+
+// VoteType is the step of the vote of a validator.
+type VoteType string
+
+const (
+	// Prevote is the first vote of a validator for a checkpoint.
+	Prevote VoteType = "prevote"
+	// Precommit is the vote of a validator for a checkpoint that more than
+	// two thirds of the validators prevoted.
+	Precommit VoteType = "precommit"
+)
+
+const (
+	// rebroadcastInterval is how often the votes of the node for checkpoints
+	// that are not finalized yet are sent again, e.g. for peers that were
+	// not connected when they were first sent.
+	rebroadcastInterval = 30 * time.Second
+)
+
+var (
+	// ErrInvalidVote is returned when a vote received from a peer is not
+	// valid.
+	ErrInvalidVote = errors.New("vote is not valid")
+)
+
+// Broadcaster sends votes to the peers of the node.
+type Broadcaster interface {
+	BroadcastPrevote(vote *pb.FinalityVote)
+	BroadcastPrecommit(vote *pb.FinalityVote)
+}
+
+// outgoingVote is a vote that must be sent to peers.
+type outgoingVote struct {
+	voteType VoteType
+	vote     *pb.FinalityVote
+}
+
+// Gadget collects the votes of the validators and finalizes checkpoints on the
+// chain. If the node is a validator, it also votes.
+type Gadget struct {
+	blockchain  *block.BlockChain
+	bus         *events.Bus
+	broadcaster Broadcaster
+	validators  map[string]bool
+	interval    int64
+	signer      ed25519.PrivateKey
+	genesisHash []byte
+
+	// votes maps each vote type and checkpoint height to the vote of each
+	// validator.
+	votes map[VoteType]map[int64]map[string]*pb.FinalityVote
+	// own maps each vote type and checkpoint height to the vote of this
+	// node.
+	own  map[VoteType]map[int64]*pb.FinalityVote
+	lock sync.Mutex
+}
+
+// NewGadget creates and returns a new instance of the Gadget. The signer is
+// used to vote, and it is only used if it belongs to one of the validators: it
+// can be nil if the node only follows the votes of the others.
+func NewGadget(settings *block.FinalitySettings, signer ed25519.PrivateKey, blockchain *block.BlockChain, bus *events.Bus, broadcaster Broadcaster) (*Gadget, error) {
+	if settings == nil || len(settings.Validators) == 0 {
+		return nil, fmt.Errorf("no validators provided")
+	}
+
+	if settings.CheckpointInterval < 0 {
+		return nil, fmt.Errorf("checkpoint interval cannot be negative")
+	}
+
+	interval := int64(settings.CheckpointInterval)
+	if interval == 0 {
+		interval = int64(block.DefaultCheckpointInterval)
+	}
+
+	validators := map[string]bool{}
+	for _, validator := range settings.Validators {
+		key, err := hex.DecodeString(validator)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("validator %q is not a valid public key", validator)
+		}
+		validators[hex.EncodeToString(key)] = true
+	}
+
+	if signer != nil && !validators[hex.EncodeToString(signer.Public().(ed25519.PublicKey))] {
+		signer = nil
+	}
+
+	genesis, err := blockchain.GetBlockByHeight(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Gadget{
+		blockchain:  blockchain,
+		bus:         bus,
+		broadcaster: broadcaster,
+		validators:  validators,
+		interval:    interval,
+		signer:      signer,
+		genesisHash: genesis.Hash,
+		votes: map[VoteType]map[int64]map[string]*pb.FinalityVote{
+			Prevote:   {},
+			Precommit: {},
+		},
+		own: map[VoteType]map[int64]*pb.FinalityVote{
+			Prevote:   {},
+			Precommit: {},
+		},
+		lock: sync.Mutex{},
+	}, nil
+}
+
+// Validating returns true if the node votes on checkpoints.
+func (g *Gadget) Validating() bool {
+	return g.signer != nil
+}
+
+// Run votes on the checkpoints that reach the chain until the context is
+// cancelled.
+func (g *Gadget) Run(ctx context.Context) {
+	id, blockEvents := g.bus.Subscribe(100)
+	defer g.bus.Unsubscribe(id)
+
+	ticker := time.NewTicker(rebroadcastInterval)
+	defer ticker.Stop()
+
+	g.broadcast(g.chainUpdated())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-blockEvents:
+			if !ok {
+				return
+			}
+			if ev.EventType == events.EventBlockAccepted || ev.EventType == events.EventReorg {
+				g.broadcast(g.chainUpdated())
+			}
+		case <-ticker.C:
+			g.broadcast(append(g.pendingOwnVotes(), g.chainUpdated()...))
+		}
+	}
+}
+
+// HandleVote checks the vote that a peer sent and counts it. Valid votes that
+// were not received yet are relayed to the other peers.
+func (g *Gadget) HandleVote(voteType VoteType, vote *pb.FinalityVote) error {
+	if err := g.validateVote(voteType, vote); err != nil {
+		return err
+	}
+
+	g.lock.Lock()
+	if vote.Height <= g.blockchain.FinalizedHeight() {
+		// too late, but it is not the validator's fault.
+		g.lock.Unlock()
+		return nil
+	}
+
+	validator := hex.EncodeToString(vote.Validator)
+	if existing, exists := g.votes[voteType][vote.Height][validator]; exists {
+		g.lock.Unlock()
+		if bytes.Equal(existing.BlockHash, vote.BlockHash) {
+			return nil
+		}
+
+		log.Warn().Str("validator", validator).Int64("height", vote.Height).Str("type", string(voteType)).
+			Msg("validator voted two different blocks at the same height")
+		return fmt.Errorf("%w: validator already voted another block at this height", ErrInvalidVote)
+	}
+
+	g.addVote(voteType, vote)
+	outgoing := append([]outgoingVote{{voteType: voteType, vote: vote}}, g.tally(vote.Height)...)
+	g.lock.Unlock()
+
+	g.broadcast(outgoing)
+	return nil
+}
+
+func (g *Gadget) validateVote(voteType VoteType, vote *pb.FinalityVote) error {
+	if vote == nil {
+		return fmt.Errorf("%w: vote is nil", ErrInvalidVote)
+	}
+
+	if voteType != Prevote && voteType != Precommit {
+		return fmt.Errorf("%w: unknown vote type %s", ErrInvalidVote, voteType)
+	}
+
+	if vote.Height <= 0 || vote.Height%g.interval != 0 {
+		return fmt.Errorf("%w: block is not a checkpoint", ErrInvalidVote)
+	}
+
+	if !g.validators[hex.EncodeToString(vote.Validator)] {
+		return fmt.Errorf("%w: vote does not come from a validator", ErrInvalidVote)
+	}
+
+	if !ed25519.Verify(vote.Validator, g.signedData(voteType, vote.Height, vote.BlockHash), vote.Signature) {
+		return fmt.Errorf("%w: signature is not valid", ErrInvalidVote)
+	}
+
+	return nil
+}
+
+// signedData returns the data that validators sign when voting. It includes
+// the genesis block, so that votes are not valid on other networks.
+func (g *Gadget) signedData(voteType VoteType, height int64, hash []byte) []byte {
+	heightBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(heightBytes, uint64(height))
+
+	return bytes.Join([][]byte{[]byte(voteType), g.genesisHash, heightBytes, hash}, []byte{})
+}
+
+// addVote stores the vote.
+// The caller must hold the lock.
+func (g *Gadget) addVote(voteType VoteType, vote *pb.FinalityVote) {
+	if _, exists := g.votes[voteType][vote.Height]; !exists {
+		g.votes[voteType][vote.Height] = map[string]*pb.FinalityVote{}
+	}
+
+	g.votes[voteType][vote.Height][hex.EncodeToString(vote.Validator)] = vote
+}
+
+// vote signs and stores a vote of this node for the block at the provided
+// height.
+// The caller must hold the lock.
+func (g *Gadget) vote(voteType VoteType, height int64, hash []byte) outgoingVote {
+	vote := &pb.FinalityVote{
+		Height:    height,
+		BlockHash: hash,
+		Validator: g.signer.Public().(ed25519.PublicKey),
+		Signature: ed25519.Sign(g.signer, g.signedData(voteType, height, hash)),
+	}
+
+	g.own[voteType][height] = vote
+	g.addVote(voteType, vote)
+
+	log.Info().Str("type", string(voteType)).Int64("height", height).Str("block-hash", hex.EncodeToString(hash)).Msg("voted checkpoint")
+	return outgoingVote{voteType: voteType, vote: vote}
+}
+
+// quorum returns the hash of the block that more than two thirds of the
+// validators voted at the provided height, or nil if there is none.
+// The caller must hold the lock.
+func (g *Gadget) quorum(voteType VoteType, height int64) []byte {
+	count := map[string]int{}
+	for _, vote := range g.votes[voteType][height] {
+		hash := hex.EncodeToString(vote.BlockHash)
+		count[hash]++
+
+		if count[hash]*3 > len(g.validators)*2 {
+			return vote.BlockHash
+		}
+	}
+
+	return nil
+}
+
+// tally counts the votes for the checkpoint at the provided height: it
+// precommits the block if enough validators prevoted it, and finalizes it if
+// enough validators precommitted it.
+// The caller must hold the lock.
+func (g *Gadget) tally(height int64) []outgoingVote {
+	outgoing := []outgoingVote{}
+
+	if hash := g.quorum(Prevote, height); hash != nil && g.signer != nil && g.own[Precommit][height] == nil {
+		// only precommit blocks that are on our chain, so that we never
+		// help finalize a block we can't verify.
+		if b, err := g.blockchain.GetBlockByHeight(int(height)); err == nil && bytes.Equal(b.Hash, hash) {
+			outgoing = append(outgoing, g.vote(Precommit, height, hash))
+		}
+	}
+
+	hash := g.quorum(Precommit, height)
+	if hash == nil {
+		return outgoing
+	}
+
+	// if the block is not on the chain yet, this is tried again when the
+	// chain changes.
+	if err := g.blockchain.Finalize(height, hash); err != nil {
+		log.Debug().Err(err).Int64("height", height).Msg("could not finalize checkpoint yet")
+		return outgoing
+	}
+
+	g.prune(height)
+	return outgoing
+}
+
+// prune forgets the votes for the checkpoints up to the finalized one.
+// The caller must hold the lock.
+func (g *Gadget) prune(finalized int64) {
+	for _, voteType := range []VoteType{Prevote, Precommit} {
+		for height := range g.votes[voteType] {
+			if height <= finalized {
+				delete(g.votes[voteType], height)
+			}
+		}
+		for height := range g.own[voteType] {
+			if height <= finalized {
+				delete(g.own[voteType], height)
+			}
+		}
+	}
+}
+
+// chainUpdated prevotes the checkpoints on the chain that the node did not
+// vote yet, and counts the votes again as the blocks they are about may have
+// just arrived.
+func (g *Gadget) chainUpdated() []outgoingVote {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	outgoing := []outgoingVote{}
+	finalized := g.blockchain.FinalizedHeight()
+	chain := g.blockchain.GetChain()
+
+	if g.signer != nil && g.locksHold(chain, finalized) {
+		first := finalized - finalized%g.interval + g.interval
+		for height := first; height < int64(len(chain)); height += g.interval {
+			if g.own[Prevote][height] == nil {
+				outgoing = append(outgoing, g.vote(Prevote, height, chain[height].Hash))
+			}
+		}
+	}
+
+	heights := map[int64]bool{}
+	for _, voteType := range []VoteType{Prevote, Precommit} {
+		for height := range g.votes[voteType] {
+			heights[height] = true
+		}
+	}
+	for height := range heights {
+		if height > g.blockchain.FinalizedHeight() {
+			outgoing = append(outgoing, g.tally(height)...)
+		}
+	}
+
+	return outgoing
+}
+
+// locksHold returns true if the chain contains all the blocks that the node
+// precommitted and are not finalized yet. Otherwise the node was reorganized
+// away from a block that may be finalized by the others, and it must not vote
+// anything else until that is resolved.
+// The caller must hold the lock.
+func (g *Gadget) locksHold(chain []*pb.Block, finalized int64) bool {
+	for height, vote := range g.own[Precommit] {
+		if height <= finalized {
+			continue
+		}
+
+		if height >= int64(len(chain)) || !bytes.Equal(chain[height].Hash, vote.BlockHash) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pendingOwnVotes returns the votes of the node for checkpoints that are not
+// finalized yet.
+func (g *Gadget) pendingOwnVotes() []outgoingVote {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	finalized := g.blockchain.FinalizedHeight()
+	outgoing := []outgoingVote{}
+	for _, voteType := range []VoteType{Prevote, Precommit} {
+		for height, vote := range g.own[voteType] {
+			if height > finalized {
+				outgoing = append(outgoing, outgoingVote{voteType: voteType, vote: vote})
+			}
+		}
+	}
+
+	return outgoing
+}
+
+func (g *Gadget) broadcast(outgoing []outgoingVote) {
+	if g.broadcaster == nil {
+		return
+	}
+
+	for _, o := range outgoing {
+		switch o.voteType {
+		case Prevote:
+			g.broadcaster.BroadcastPrevote(o.vote)
+		case Precommit:
+			g.broadcaster.BroadcastPrecommit(o.vote)
+		}
+	}
+}
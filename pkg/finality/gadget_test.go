@@ -0,0 +1,352 @@
+package finality
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+)
+
+// testInterval is the checkpoint interval of the test gadgets.
+const testInterval int64 = 2
+
+// newTestGadget returns a gadget with the provided number of validators, over
+// a chain with two checkpoints. The node votes as the first validator if
+// validating is true. Nothing is broadcast, as votes are handled directly.
+func newTestGadget(t *testing.T, validators int, validating bool) (*Gadget, []ed25519.PrivateKey, *block.BlockFactory) {
+	t.Helper()
+
+	keys := []ed25519.PrivateKey{}
+	settings := &block.FinalitySettings{CheckpointInterval: int(testInterval)}
+	for i := 0; i < validators; i++ {
+		pub, priv, _ := ed25519.GenerateKey(nil)
+		keys = append(keys, priv)
+		settings.Validators = append(settings.Validators, hex.EncodeToString(pub))
+	}
+
+	f := block.NewBlockFactory()
+	blockchain := f.NewBlockChain()
+	extendChain(t, f, blockchain, 2*int(testInterval), "entry")
+
+	var signer ed25519.PrivateKey
+	if validating {
+		signer = keys[0]
+	}
+
+	g, err := NewGadget(settings, signer, blockchain, events.NewBus(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g, keys, f
+}
+
+// extendChain pushes the provided number of blocks to the chain.
+func extendChain(t *testing.T, f *block.BlockFactory, blockchain *block.BlockChain, blocks int, entry string) {
+	t.Helper()
+
+	for i := 0; i < blocks; i++ {
+		if err := blockchain.PushBlock(f.NewBlock([]string{fmt.Sprintf("%s-%d", entry, i)}, blockchain.GetLastBlock())); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// signVote returns the vote of the validator with the provided key.
+func signVote(g *Gadget, key ed25519.PrivateKey, voteType VoteType, height int64, hash []byte) *pb.FinalityVote {
+	return &pb.FinalityVote{
+		Height:    height,
+		BlockHash: hash,
+		Validator: key.Public().(ed25519.PublicKey),
+		Signature: ed25519.Sign(key, g.signedData(voteType, height, hash)),
+	}
+}
+
+// checkpointHash returns the hash of the block at the provided height.
+func checkpointHash(t *testing.T, g *Gadget, height int64) []byte {
+	t.Helper()
+
+	b, err := g.blockchain.GetBlockByHeight(int(height))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b.Hash
+}
+
+func TestQuorum(t *testing.T) {
+	cases := []struct {
+		name       string
+		validators int
+		votes      int
+		others     int
+		finalized  bool
+	}{
+		{
+			name:       "exactly two thirds of three",
+			validators: 3,
+			votes:      2,
+		},
+		{
+			name:       "all of three",
+			validators: 3,
+			votes:      3,
+			finalized:  true,
+		},
+		{
+			name:       "half of four",
+			validators: 4,
+			votes:      2,
+		},
+		{
+			name:       "three of four",
+			validators: 4,
+			votes:      3,
+			finalized:  true,
+		},
+		{
+			name:       "exactly two thirds of six",
+			validators: 6,
+			votes:      4,
+		},
+		{
+			name:       "five of six",
+			validators: 6,
+			votes:      5,
+			finalized:  true,
+		},
+		{
+			name:       "votes split among blocks",
+			validators: 4,
+			votes:      2,
+			others:     1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g, keys, _ := newTestGadget(t, c.validators, false)
+			hash := checkpointHash(t, g, testInterval)
+
+			for i := 0; i < c.votes+c.others; i++ {
+				voted := hash
+				if i >= c.votes {
+					voted = checkpointHash(t, g, testInterval-1)
+				}
+
+				if err := g.HandleVote(Precommit, signVote(g, keys[i], Precommit, testInterval, voted)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if finalized := g.blockchain.FinalizedHeight() == testInterval; finalized != c.finalized {
+				t.Fatalf("finalized = %t, want %t", finalized, c.finalized)
+			}
+		})
+	}
+}
+
+func TestPrecommitAfterPrevotes(t *testing.T) {
+	g, keys, _ := newTestGadget(t, 4, true)
+	hash := checkpointHash(t, g, testInterval)
+
+	// the node prevotes the checkpoints on its chain.
+	g.chainUpdated()
+	if g.own[Prevote][testInterval] == nil || g.own[Prevote][2*testInterval] == nil {
+		t.Fatal("checkpoints were not prevoted")
+	}
+
+	if err := g.HandleVote(Prevote, signVote(g, keys[1], Prevote, testInterval, hash)); err != nil {
+		t.Fatal(err)
+	}
+	if g.own[Precommit][testInterval] != nil {
+		t.Fatal("precommitted with half of the prevotes")
+	}
+
+	if err := g.HandleVote(Prevote, signVote(g, keys[2], Prevote, testInterval, hash)); err != nil {
+		t.Fatal(err)
+	}
+	if g.own[Precommit][testInterval] == nil {
+		t.Fatal("not precommitted with three of four prevotes")
+	}
+	if g.own[Precommit][2*testInterval] != nil {
+		t.Fatal("precommitted a checkpoint without prevotes")
+	}
+}
+
+func TestHandleVoteRejects(t *testing.T) {
+	g, keys, _ := newTestGadget(t, 4, false)
+	hash := checkpointHash(t, g, testInterval)
+	_, outsider, _ := ed25519.GenerateKey(nil)
+
+	cases := []struct {
+		name     string
+		voteType VoteType
+		vote     *pb.FinalityVote
+	}{
+		{
+			name:     "nil",
+			voteType: Prevote,
+		},
+		{
+			name:     "unknown type",
+			voteType: VoteType("other"),
+			vote:     signVote(g, keys[0], VoteType("other"), testInterval, hash),
+		},
+		{
+			name:     "not a checkpoint",
+			voteType: Prevote,
+			vote:     signVote(g, keys[0], Prevote, testInterval-1, hash),
+		},
+		{
+			name:     "not a validator",
+			voteType: Prevote,
+			vote:     signVote(g, outsider, Prevote, testInterval, hash),
+		},
+		{
+			name:     "signed for the other type",
+			voteType: Precommit,
+			vote:     signVote(g, keys[0], Prevote, testInterval, hash),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := g.HandleVote(c.voteType, c.vote); !errors.Is(err, ErrInvalidVote) {
+				t.Fatalf("error = %v, want %v", err, ErrInvalidVote)
+			}
+		})
+	}
+}
+
+func TestEquivocation(t *testing.T) {
+	g, keys, _ := newTestGadget(t, 4, false)
+	hash := checkpointHash(t, g, testInterval)
+	other := checkpointHash(t, g, testInterval-1)
+
+	for _, voteType := range []VoteType{Prevote, Precommit} {
+		t.Run(string(voteType), func(t *testing.T) {
+			vote := signVote(g, keys[1], voteType, testInterval, hash)
+			if err := g.HandleVote(voteType, vote); err != nil {
+				t.Fatal(err)
+			}
+
+			// the same vote received again, e.g. relayed by another peer.
+			if err := g.HandleVote(voteType, vote); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			err := g.HandleVote(voteType, signVote(g, keys[1], voteType, testInterval, other))
+			if !errors.Is(err, ErrInvalidVote) {
+				t.Fatalf("error = %v, want %v", err, ErrInvalidVote)
+			}
+
+			stored := g.votes[voteType][testInterval][hex.EncodeToString(keys[1].Public().(ed25519.PublicKey))]
+			if stored != vote {
+				t.Fatal("the first vote was replaced")
+			}
+		})
+	}
+}
+
+func TestLocksHold(t *testing.T) {
+	g, keys, f := newTestGadget(t, 4, true)
+	original := g.blockchain.GetChain()
+	hash := checkpointHash(t, g, testInterval)
+
+	// lock the first checkpoint by precommitting it.
+	g.chainUpdated()
+	for _, key := range keys[1:3] {
+		if err := g.HandleVote(Prevote, signVote(g, key, Prevote, testInterval, hash)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.own[Precommit][testInterval] == nil {
+		t.Fatal("checkpoint was not precommitted")
+	}
+	if !g.locksHold(original, 0) {
+		t.Fatal("lock does not hold on the chain with the precommitted block")
+	}
+
+	// a longer fork that does not include the locked block.
+	fork := f.NewBlockChain()
+	extendChain(t, f, fork, 4*int(testInterval), "fork")
+	if err := g.blockchain.ReplaceWith(fork.GetChain()); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.locksHold(g.blockchain.GetChain(), 0) {
+		t.Fatal("lock holds on a chain without the precommitted block")
+	}
+	if g.locksHold(original[:testInterval], 0) {
+		t.Fatal("lock holds on a chain shorter than the precommitted block")
+	}
+	if !g.locksHold(g.blockchain.GetChain(), testInterval) {
+		t.Fatal("lock on a finalized height was not ignored")
+	}
+
+	g.chainUpdated()
+	if g.own[Prevote][3*testInterval] != nil {
+		t.Fatal("prevoted on a chain without the precommitted block")
+	}
+
+	// back on a chain with the locked block, longer than the fork.
+	ext := f.NewBlockChain()
+	if err := ext.ReplaceWith(original); err != nil {
+		t.Fatal(err)
+	}
+	extendChain(t, f, ext, 3*int(testInterval), "ext")
+	if err := g.blockchain.ReplaceWith(ext.GetChain()); err != nil {
+		t.Fatal(err)
+	}
+
+	g.chainUpdated()
+	if g.own[Prevote][3*testInterval] == nil {
+		t.Fatal("did not prevote again once the lock held")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	g, keys, _ := newTestGadget(t, 4, true)
+	hash := checkpointHash(t, g, testInterval)
+	next := checkpointHash(t, g, 2*testInterval)
+
+	g.chainUpdated()
+	if err := g.HandleVote(Prevote, signVote(g, keys[1], Prevote, 2*testInterval, next)); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys[1:] {
+		if err := g.HandleVote(Precommit, signVote(g, key, Precommit, testInterval, hash)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if g.blockchain.FinalizedHeight() != testInterval {
+		t.Fatalf("finalized height = %d, want %d", g.blockchain.FinalizedHeight(), testInterval)
+	}
+
+	for _, voteType := range []VoteType{Prevote, Precommit} {
+		if _, exists := g.votes[voteType][testInterval]; exists {
+			t.Fatalf("%s votes for the finalized checkpoint were not pruned", voteType)
+		}
+		if _, exists := g.own[voteType][testInterval]; exists {
+			t.Fatalf("own %s for the finalized checkpoint was not pruned", voteType)
+		}
+	}
+	if len(g.votes[Prevote][2*testInterval]) != 2 || g.own[Prevote][2*testInterval] == nil {
+		t.Fatal("votes for the next checkpoint were pruned")
+	}
+
+	// late votes for the finalized checkpoint are not stored again.
+	if err := g.HandleVote(Prevote, signVote(g, keys[3], Prevote, testInterval, hash)); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := g.votes[Prevote][testInterval]; exists {
+		t.Fatal("late vote was stored")
+	}
+}
@@ -0,0 +1,495 @@
+package lightclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/metrics"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
+	"github.com/SunSince90/go-naivecoin/pkg/wallet"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// headersPerRequest is the number of headers asked to a peer at once.
+	headersPerRequest int64 = 2000
+)
+
+var (
+	// ErrNoPeerAnswered is returned when a query could not be answered
+	// because no peer replied to it.
+	ErrNoPeerAnswered = errors.New("no peer answered the query")
+)
+
+// LightClient only syncs block headers from full peers and verifies that
+// the entries it watches are included in the chain by asking peers for
+// merkle proofs. Balances of addresses are computed the same way, from the
+// entries moving an amount to or from them.
+type LightClient struct {
+	headers *HeaderChain
+	peers   map[string]*peers.Peer
+	// watched maps the hex representation of each watched entry hash to
+	// the proof of its inclusion, which is nil until it is found.
+	watched map[string]*pb.MerkleProof
+	// balances maps each watched address to its balance, which is nil until
+	// it is computed.
+	balances        map[string]*wallet.Balance
+	genesisHash     []byte
+	initialSyncDone bool
+	lock            sync.Mutex
+}
+
+// NewLightClient creates and returns a new instance of the LightClient.
+func NewLightClient(blockFactory *block.BlockFactory) *LightClient {
+	return &LightClient{
+		headers:     NewHeaderChain(blockFactory),
+		peers:       map[string]*peers.Peer{},
+		watched:     map[string]*pb.MerkleProof{},
+		balances:    map[string]*wallet.Balance{},
+		genesisHash: blockFactory.GenesisBlock().Hash,
+		lock:        sync.Mutex{},
+	}
+}
+
+// Headers returns the header chain tracked by the light client.
+func (l *LightClient) Headers() *HeaderChain {
+	return l.headers
+}
+
+// PushBlock pushes the header of a block generated by a peer to the header
+// chain. The entries of the block are discarded.
+//
+// If the block does not follow the last header, headers are synced again
+// from all peers as we may be on a different fork.
+func (l *LightClient) PushBlock(block *pb.Block) error {
+	if block == nil {
+		return fmt.Errorf("block is nil")
+	}
+
+	if err := l.headers.PushHeader(block.Header, block.Hash); err != nil {
+		if errors.Is(err, ErrHeaderDoesNotLink) {
+			go l.syncFromAllPeers()
+		}
+
+		return err
+	}
+
+	go l.checkWatched()
+	return nil
+}
+
+// Watch starts watching the entry with the provided hash, so that its
+// inclusion is checked every time a new header is received.
+func (l *LightClient) Watch(entryHash []byte) {
+	l.lock.Lock()
+	key := hex.EncodeToString(entryHash)
+	if _, exists := l.watched[key]; !exists {
+		l.watched[key] = nil
+	}
+	l.lock.Unlock()
+
+	go l.checkWatched()
+}
+
+// GetWatched returns the proofs of the watched entries, keyed by the hex
+// representation of their hash. The proof is nil if the entry has not been
+// found on the chain yet.
+func (l *LightClient) GetWatched() map[string]*pb.MerkleProof {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	watched := make(map[string]*pb.MerkleProof, len(l.watched))
+	for key, proof := range l.watched {
+		watched[key] = proof
+	}
+
+	return watched
+}
+
+// WatchAddress starts watching the provided address, so that its balance is
+// computed again every time a new header is received.
+func (l *LightClient) WatchAddress(address string) error {
+	if err := wallet.ValidateAddress(address); err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	key := strings.ToLower(address)
+	if _, exists := l.balances[key]; !exists {
+		l.balances[key] = nil
+	}
+	l.lock.Unlock()
+
+	go l.checkWatched()
+	return nil
+}
+
+// GetWatchedBalances returns the balances of the watched addresses. The
+// balance is nil if it has not been computed yet.
+func (l *LightClient) GetWatchedBalances() map[string]*wallet.Balance {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	balances := make(map[string]*wallet.Balance, len(l.balances))
+	for address, balance := range l.balances {
+		balances[address] = balance
+	}
+
+	return balances
+}
+
+// GetBalance asks peers for the entries that move an amount to or from the
+// provided address and returns the balance computed from the ones whose
+// proof is valid against the header chain.
+//
+// Proofs only show that entries are included, not that a peer sent all of
+// them: entries from all peers are merged, so that a single honest peer is
+// enough to get the full balance.
+func (l *LightClient) GetBalance(ctx context.Context, address string) (*wallet.Balance, error) {
+	balance, err := wallet.NewBalance(address)
+	if err != nil {
+		return nil, err
+	}
+
+	answered := 0
+	for _, peer := range l.getPeers() {
+		reqCtx, canc := context.WithTimeout(ctx, 30*time.Second)
+		entries, err := peer.GetAddressEntries(reqCtx, address)
+		canc()
+		if err != nil {
+			log.Err(err).Str("peer-name", peer.Name).Msg("could not get address entries from peer")
+			continue
+		}
+		answered++
+
+		for _, proven := range entries {
+			if err := l.headers.VerifyProof(proven.GetProof(), block.HashEntry(proven.GetEntry())); err != nil {
+				log.Err(err).Str("peer-name", peer.Name).Msg("peer sent an invalid proof")
+				continue
+			}
+
+			balance.Apply(proven.Entry, bytes.Equal(proven.Proof.BlockHash, l.genesisHash))
+		}
+	}
+
+	if answered == 0 {
+		return nil, ErrNoPeerAnswered
+	}
+
+	return balance, nil
+}
+
+// GetProof asks peers for the proof that the entry with the provided hash is
+// included in the chain and returns the first one that is valid against the
+// header chain, or block.ErrEntryNotFound if no peer provided one.
+func (l *LightClient) GetProof(ctx context.Context, entryHash []byte) (*pb.MerkleProof, error) {
+	for _, peer := range l.getPeers() {
+		reqCtx, canc := context.WithTimeout(ctx, 10*time.Second)
+		proof, err := peer.GetMerkleProof(reqCtx, entryHash)
+		canc()
+		if err != nil {
+			continue
+		}
+
+		if err := l.headers.VerifyProof(proof, entryHash); err != nil {
+			log.Err(err).Str("peer-name", peer.Name).Msg("peer sent an invalid proof")
+			continue
+		}
+
+		return proof, nil
+	}
+
+	return nil, block.ErrEntryNotFound
+}
+
+// GetSyncStatus returns how far the light client is in syncing headers with
+// its peers.
+func (l *LightClient) GetSyncStatus() *peers.SyncStatus {
+	height := l.headers.GetLastHeader().Header.Index
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	status := &peers.SyncStatus{
+		InitialSyncDone: l.initialSyncDone,
+		Height:          height,
+		Peers:           len(l.peers),
+	}
+	for _, peer := range l.peers {
+		if peerHeight := peer.Height(); peerHeight > status.BestPeerHeight {
+			status.BestPeerHeight = peerHeight
+		}
+	}
+
+	return status
+}
+
+func (l *LightClient) checkWatched() {
+	pending := [][]byte{}
+	l.lock.Lock()
+	for key, proof := range l.watched {
+		if proof == nil {
+			entryHash, _ := hex.DecodeString(key)
+			pending = append(pending, entryHash)
+		}
+	}
+	l.lock.Unlock()
+
+	for _, entryHash := range pending {
+		proof, err := l.GetProof(context.Background(), entryHash)
+		if err != nil {
+			continue
+		}
+
+		l.lock.Lock()
+		l.watched[hex.EncodeToString(entryHash)] = proof
+		l.lock.Unlock()
+
+		log.Info().Str("entry-hash", hex.EncodeToString(entryHash)).Int64("index", proof.Header.Index).Msg("watched entry found on chain")
+	}
+
+	// balances change with every block, so they are always computed again.
+	l.lock.Lock()
+	addresses := make([]string, 0, len(l.balances))
+	for address := range l.balances {
+		addresses = append(addresses, address)
+	}
+	l.lock.Unlock()
+
+	for _, address := range addresses {
+		balance, err := l.GetBalance(context.Background(), address)
+		if err != nil {
+			continue
+		}
+
+		l.lock.Lock()
+		if _, exists := l.balances[address]; exists {
+			l.balances[address] = balance
+		}
+		l.lock.Unlock()
+	}
+}
+
+func (l *LightClient) getPeers() []*peers.Peer {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	list := make([]*peers.Peer, 0, len(l.peers))
+	for _, peer := range l.peers {
+		list = append(list, peer)
+	}
+
+	return list
+}
+
+// syncHeaders gets all the headers that we don't have yet from the peer.
+// In case the peer is on a different fork, its whole header chain is
+// downloaded and replaces ours if it has more chain work.
+func (l *LightClient) syncHeaders(ctx context.Context, peer *peers.Peer) error {
+	for {
+		from := int64(l.headers.Length())
+
+		reqCtx, canc := context.WithTimeout(ctx, 30*time.Second)
+		headers, err := peer.GetHeaders(reqCtx, from, headersPerRequest)
+		canc()
+		if err != nil {
+			return fmt.Errorf("could not get headers from peer: %w", err)
+		}
+
+		if len(headers) > 0 {
+			peer.SetHeight(headers[len(headers)-1].GetHeader().GetIndex())
+		}
+
+		for _, hh := range headers {
+			err := l.headers.PushHeader(hh.GetHeader(), hh.GetHash())
+			if err == nil {
+				continue
+			}
+
+			if !errors.Is(err, ErrHeaderDoesNotLink) {
+				return err
+			}
+
+			return l.syncFork(ctx, peer)
+		}
+
+		if int64(len(headers)) < headersPerRequest {
+			go l.checkWatched()
+			return nil
+		}
+	}
+}
+
+// syncFork downloads the full header chain of the peer and replaces ours
+// with it if it has more chain work.
+func (l *LightClient) syncFork(ctx context.Context, peer *peers.Peer) error {
+	all := []*pb.HashedHeader{}
+	for {
+		reqCtx, canc := context.WithTimeout(ctx, 30*time.Second)
+		headers, err := peer.GetHeaders(reqCtx, int64(len(all)), headersPerRequest)
+		canc()
+		if err != nil {
+			return fmt.Errorf("could not get headers from peer: %w", err)
+		}
+
+		all = append(all, headers...)
+		if int64(len(headers)) < headersPerRequest {
+			break
+		}
+	}
+
+	if err := l.headers.ReplaceWith(all); err != nil {
+		return err
+	}
+
+	log.Info().Str("peer-name", peer.Name).Msg("headers replaced with my peer's headers")
+
+	// proofs may now belong to blocks that are not on our chain anymore
+	l.lock.Lock()
+	for key := range l.watched {
+		l.watched[key] = nil
+	}
+	l.lock.Unlock()
+
+	go l.checkWatched()
+	return nil
+}
+
+func (l *LightClient) syncFromAllPeers() {
+	for _, peer := range l.getPeers() {
+		if err := l.syncHeaders(context.Background(), peer); err != nil {
+			log.Err(err).Str("peer-name", peer.Name).Msg("could not sync headers from peer")
+		}
+	}
+}
+
+func (l *LightClient) addPeer(ctx context.Context, peer *peers.Peer) error {
+	l.lock.Lock()
+	_, exists := l.peers[peer.Name]
+	l.lock.Unlock()
+
+	if exists {
+		return fmt.Errorf("peer already present")
+	}
+
+	if err := l.syncHeaders(ctx, peer); err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	if _, exists := l.peers[peer.Name]; exists {
+		// another event for the same name was handled while we were
+		// syncing.
+		l.lock.Unlock()
+		return fmt.Errorf("peer already present")
+	}
+	l.peers[peer.Name] = peer
+	l.initialSyncDone = true
+	metrics.PeersConnected.Set(float64(len(l.peers)))
+	l.lock.Unlock()
+
+	log.Info().Str("peer-name", peer.Name).Msg("added peer")
+	return nil
+}
+
+func (l *LightClient) removePeer(peer *peers.Peer) (*peers.Peer, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	foundPeer, exists := l.peers[peer.Name]
+	if !exists {
+		return nil, fmt.Errorf("peer was not found")
+	}
+	if !foundPeer.SameAs(peer) {
+		return nil, fmt.Errorf("peer was replaced by a new one")
+	}
+
+	delete(l.peers, peer.Name)
+	metrics.PeersConnected.Set(float64(len(l.peers)))
+	return foundPeer, nil
+}
+
+// replacePeer removes the peer with the same name of the provided one if it
+// has a different address, keeping its score and height. It returns false if
+// the provided peer is already known.
+func (l *LightClient) replacePeer(peer *peers.Peer) bool {
+	l.lock.Lock()
+	oldPeer, exists := l.peers[peer.Name]
+	if !exists {
+		l.lock.Unlock()
+		return true
+	}
+	if oldPeer.Address() == peer.Address() && oldPeer.UID == peer.UID {
+		l.lock.Unlock()
+		return false
+	}
+	delete(l.peers, peer.Name)
+	l.lock.Unlock()
+
+	if oldPeer.CancelContext != nil {
+		oldPeer.CancelContext()
+	}
+	peer.Inherit(oldPeer)
+	return true
+}
+
+// ListenPeerEvents listens on the provided channel for peer events, e.g. new
+// or deleted peers, syncs headers from new peers and subscribes to the
+// blocks they generate.
+func (l *LightClient) ListenPeerEvents(peerEvents chan *peers.PeerEvent) {
+	ctx, canc := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+	queue := peers.NewNameQueue()
+
+	for ev := range peerEvents {
+		// events about the same peer are handled in the order they are
+		// received, so that a newer incarnation of the peer always
+		// replaces an older one.
+		switch peer := ev.Peer; ev.EventType {
+
+		case peers.EventNewPeer, peers.EventUpdatedPeer:
+			queue.Push(peer.Name, func() {
+				if !l.replacePeer(peer) {
+					return
+				}
+
+				if err := l.addPeer(ctx, peer); err != nil {
+					log.Err(err).Str("peer-name", peer.Name).Msg("could not add peer")
+					return
+				}
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					peer.SubscribeBlockGeneration(ctx, l)
+				}()
+			})
+
+		case peers.EventDeadPeer:
+			queue.Push(peer.Name, func() {
+				foundPeer, err := l.removePeer(peer)
+				if err != nil {
+					log.Err(err).Str("peer-name", peer.Name).Msg("could not remove peer")
+					return
+				}
+
+				if foundPeer.CancelContext != nil {
+					foundPeer.CancelContext()
+				}
+			})
+		}
+	}
+
+	log.Info().Msg("unsubscribing from all peers...")
+	canc()
+	wg.Wait()
+	log.Info().Msg("all unsubscriptions done")
+}
@@ -0,0 +1,106 @@
+package lightclient_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/lightclient"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
+	"github.com/SunSince90/go-naivecoin/pkg/servers"
+	"github.com/SunSince90/go-naivecoin/pkg/wallet"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serve serves the peer communications of the chain in memory and returns
+// the peer that reaches it.
+func serve(t *testing.T, bc *block.BlockChain) *peers.Peer {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterPeerCommunicationServer(server, servers.NewPeerCommunicationServer(bc, nil, nil))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return &peers.Peer{
+		Name: "full",
+		IP:   "full",
+		DialOptions: []grpc.DialOption{
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+				return listener.Dial()
+			}),
+		},
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	sender, _ := wallet.NewWallet("sender")
+	recipient, _ := wallet.NewWallet("recipient")
+	clock := block.NewFakeClock(time.Unix(1600000000, 0))
+	f := block.NewBlockFactory(
+		block.WithGenesis(&block.GenesisSettings{
+			ChainID:     "test",
+			Timestamp:   clock.Now().Unix(),
+			Allocations: []block.Allocation{{Address: sender.Address(), Amount: 100}},
+		}),
+		block.WithClock(clock),
+	)
+	bc := f.NewBlockChain()
+
+	transfer, _ := sender.NewTransfer(recipient.Address(), 30)
+	entry, _ := transfer.Entry()
+	forged := *transfer
+	forged.Amount = 1000
+	forgedEntry, _ := forged.Entry()
+	// the same transfer included twice must only be counted once.
+	for _, entries := range [][]string{{entry, "other"}, {entry, forgedEntry}} {
+		clock.Advance(10 * time.Second)
+		if err := bc.PushBlock(f.NewBlock(entries, bc.GetLastBlock())); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := lightclient.NewLightClient(f)
+	peerEvents := make(chan *peers.PeerEvent, 1)
+	defer close(peerEvents)
+	go client.ListenPeerEvents(peerEvents)
+	peerEvents <- &peers.PeerEvent{EventType: peers.EventNewPeer, Peer: serve(t, bc)}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for client.GetSyncStatus().Peers == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("light client did not sync from the peer")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cases := []struct {
+		address  string
+		received int64
+		sent     int64
+	}{
+		{address: sender.Address(), received: 100, sent: 30},
+		{address: recipient.Address(), received: 30, sent: 0},
+	}
+
+	for _, c := range cases {
+		balance, err := client.GetBalance(context.Background(), c.address)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if balance.Received.Int64() != c.received || balance.Sent.Int64() != c.sent ||
+			balance.Balance.Int64() != c.received-c.sent {
+			t.Fatalf("balance of %s = %+v, want received %d and sent %d", c.address, balance, c.received, c.sent)
+		}
+	}
+
+	if _, err := client.GetBalance(context.Background(), "not an address"); err == nil {
+		t.Fatal("balance of an invalid address was computed")
+	}
+}
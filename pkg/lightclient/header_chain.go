@@ -0,0 +1,231 @@
+package lightclient
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+)
+
+var (
+	// ErrHeaderDoesNotLink is returned when a header does not follow the last
+	// header of the chain, e.g. because the peer is on a different fork.
+	ErrHeaderDoesNotLink = errors.New("header does not link to the last header")
+)
+
+// HeaderChain keeps track of block headers only, without their entries.
+//
+// It is used by light clients to verify that entries are included in the
+// chain without having to trust the node that sent the proof: the header
+// chain is built and validated independently.
+type HeaderChain struct {
+	headers              []*pb.BlockHeader
+	hashes               [][]byte
+	heights              map[string]int
+	cumulativeDifficulty *big.Int
+	// authorities is the validator set after the last header, and
+	// inTurnHeaders the number of headers sealed in turn, with proof of
+	// authority.
+	authorities   *block.Authorities
+	inTurnHeaders int
+	blockFactory  *block.BlockFactory
+	lock          sync.Mutex
+}
+
+// NewHeaderChain creates and returns a new HeaderChain that starts from the
+// genesis block. The block factory must be configured with the same
+// consensus as the full nodes.
+func NewHeaderChain(blockFactory *block.BlockFactory) *HeaderChain {
+	genesis := blockFactory.GenesisBlock()
+
+	return &HeaderChain{
+		headers:              []*pb.BlockHeader{genesis.Header},
+		hashes:               [][]byte{genesis.Hash},
+		heights:              map[string]int{hex.EncodeToString(genesis.Hash): 0},
+		cumulativeDifficulty: big.NewInt(0),
+		authorities:          blockFactory.GenesisAuthorities(),
+		blockFactory:         blockFactory,
+		lock:                 sync.Mutex{},
+	}
+}
+
+// PushHeader validates the provided header and -- if successful -- pushes
+// it to the header chain.
+func (h *HeaderChain) PushHeader(header *pb.BlockHeader, hash []byte) error {
+	if header == nil {
+		return fmt.Errorf("header is nil")
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.pushHeader(header, hash, false)
+}
+
+// pushHeader pushes the header to the chain. The caller must hold the lock.
+// The signature of committed headers is not verified: they must be linked to
+// a header matching a checkpoint.
+func (h *HeaderChain) pushHeader(header *pb.BlockHeader, hash []byte, committed bool) error {
+	lastHeader := h.headers[len(h.headers)-1]
+	if header.Index != lastHeader.Index+1 ||
+		!bytes.Equal(header.PreviousBlockHash, h.hashes[len(h.hashes)-1]) {
+		return ErrHeaderDoesNotLink
+	}
+
+	validate := h.blockFactory.ValidateHeader
+	if committed {
+		validate = h.blockFactory.ValidateCommittedHeader
+	}
+	if err := validate(header, hash); err != nil {
+		return err
+	}
+
+	if h.authorities != nil {
+		authorities, err := h.authorities.Apply(header)
+		if err != nil {
+			return err
+		}
+
+		if h.authorities.InTurn(header) {
+			h.inTurnHeaders++
+		}
+		h.authorities = authorities
+	}
+
+	h.headers = append(h.headers, header)
+	h.hashes = append(h.hashes, hash)
+	h.heights[hex.EncodeToString(hash)] = int(header.Index)
+
+	// without proof of work the difficulty is always 0, so the chain work
+	// is the same as its length.
+	exp := big.NewInt(0).Exp(big.NewInt(2), big.NewInt(header.Difficulty), nil)
+	h.cumulativeDifficulty = big.NewInt(0).Add(h.cumulativeDifficulty, exp)
+
+	return nil
+}
+
+// ReplaceWith validates the provided headers, which must start from the
+// genesis block, and replaces the current ones if they have more chain work.
+func (h *HeaderChain) ReplaceWith(headers []*pb.HashedHeader) error {
+	if len(headers) == 0 {
+		return fmt.Errorf("no headers provided")
+	}
+
+	genesis := h.blockFactory.GenesisBlock()
+	if !bytes.Equal(headers[0].GetHash(), genesis.Hash) {
+		return fmt.Errorf("genesis block is wrong")
+	}
+
+	hashes := make([][]byte, len(headers))
+	for i, hh := range headers {
+		hashes[i] = hh.GetHash()
+	}
+	// headers are linked one by one while pushing them, so the anchor
+	// commits to the ones before it.
+	anchor := h.blockFactory.Checkpoints().Anchor(hashes)
+
+	candidate := NewHeaderChain(h.blockFactory)
+	for i, hh := range headers[1:] {
+		if hh.GetHeader() == nil {
+			return fmt.Errorf("header is nil")
+		}
+
+		if err := candidate.pushHeader(hh.Header, hh.Hash, int64(i+1) <= anchor); err != nil {
+			return err
+		}
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	// headers that include all the checkpoints are always preferred.
+	checkpoints := h.blockFactory.Checkpoints()
+	reached := checkpoints.Reached(int64(len(candidate.headers) - 1))
+	switch current := checkpoints.Reached(int64(len(h.headers) - 1)); {
+	case current && !reached:
+		return fmt.Errorf("peer's headers do not include all the checkpoints")
+	case !current && reached:
+		// replace them below
+	case h.authorities != nil:
+		// with proof of authority, the headers sealed in turn matter more
+		// than the length.
+		if h.inTurnHeaders > candidate.inTurnHeaders ||
+			(h.inTurnHeaders == candidate.inTurnHeaders && len(h.headers) >= len(candidate.headers)) {
+			return fmt.Errorf("peer's headers do not have more blocks sealed in turn than mine")
+		}
+	case h.cumulativeDifficulty.Cmp(candidate.cumulativeDifficulty) >= 0:
+		return fmt.Errorf("peer's headers do not have more chain work than mine")
+	}
+
+	h.headers = candidate.headers
+	h.hashes = candidate.hashes
+	h.heights = candidate.heights
+	h.cumulativeDifficulty = candidate.cumulativeDifficulty
+	h.authorities = candidate.authorities
+	h.inTurnHeaders = candidate.inTurnHeaders
+
+	return nil
+}
+
+// Length returns the current length of the header chain.
+func (h *HeaderChain) Length() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return len(h.headers)
+}
+
+// GetLastHeader returns the current last header on the chain, along with
+// its hash.
+func (h *HeaderChain) GetLastHeader() *pb.HashedHeader {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return &pb.HashedHeader{
+		Header: h.headers[len(h.headers)-1],
+		Hash:   h.hashes[len(h.hashes)-1],
+	}
+}
+
+// GetCumulativeDifficulty returns the chain work of the header chain.
+func (h *HeaderChain) GetCumulativeDifficulty() *big.Int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return big.NewInt(0).Set(h.cumulativeDifficulty)
+}
+
+// VerifyProof checks that the provided proof is valid against the headers
+// tracked by the header chain and that it proves the inclusion of the entry
+// with the provided hash. It returns an error if not.
+//
+// The header included in the proof is never trusted: the merkle root is
+// always taken from the header chain.
+func (h *HeaderChain) VerifyProof(proof *pb.MerkleProof, entryHash []byte) error {
+	if proof == nil {
+		return fmt.Errorf("proof is nil")
+	}
+
+	if !bytes.Equal(proof.EntryHash, entryHash) {
+		return fmt.Errorf("proof is for a different entry")
+	}
+
+	h.lock.Lock()
+	height, exists := h.heights[hex.EncodeToString(proof.BlockHash)]
+	var header *pb.BlockHeader
+	if exists {
+		header = h.headers[height]
+	}
+	h.lock.Unlock()
+
+	if !exists {
+		return fmt.Errorf("block is not in the header chain")
+	}
+
+	return block.VerifyMerkleBranch(proof.EntryHash, proof.EntryIndex, proof.EntryCount, proof.Branch, header.MerkleRoot)
+}
@@ -0,0 +1,132 @@
+package lightclient
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestVerifyProof(t *testing.T) {
+	clock := block.NewFakeClock(time.Unix(1600000000, 0))
+	f := block.NewBlockFactory(
+		block.WithGenesis(&block.GenesisSettings{ChainID: "test", Timestamp: clock.Now().Unix()}),
+		block.WithClock(clock),
+	)
+	bc := f.NewBlockChain()
+	headers := NewHeaderChain(f)
+
+	clock.Advance(10 * time.Second)
+	b := f.NewBlock([]string{"watched", "other", "another"}, bc.GetLastBlock())
+	if err := bc.PushBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := headers.PushHeader(b.Header, b.Hash); err != nil {
+		t.Fatal(err)
+	}
+
+	watched := block.HashEntry("watched")
+	other, err := bc.GetMerkleProof(block.HashEntry("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := bc.GetMerkleProof(watched)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := headers.VerifyProof(proof, watched); err != nil {
+		t.Fatalf("valid proof was refused: %s", err)
+	}
+
+	// a valid proof for another entry of a known block must not prove that
+	// the watched entry is included.
+	if err := headers.VerifyProof(other, watched); err == nil {
+		t.Fatal("proof for a different entry was accepted")
+	}
+
+	if err := headers.VerifyProof(nil, watched); err == nil {
+		t.Fatal("nil proof was accepted")
+	}
+}
+
+func TestHeaderChainCheckpoints(t *testing.T) {
+	_, signer, _ := ed25519.GenerateKey(nil)
+	clock := block.NewFakeClock(time.Unix(1600000000, 0))
+	newFactory := func(checkpoints block.Checkpoints) *block.BlockFactory {
+		poa, err := block.NewProofOfAuthority(&block.ProofOfAuthoritySettings{
+			Validators: []string{hex.EncodeToString(signer.Public().(ed25519.PublicKey))},
+		}, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return block.NewBlockFactory(
+			block.WithGenesis(&block.GenesisSettings{ChainID: "test", Timestamp: 1600000000}),
+			block.WithClock(clock),
+			block.WithProofOfAuthority(poa),
+			block.WithCheckpoints(checkpoints),
+		)
+	}
+
+	f := newFactory(nil)
+	genesis := f.GenesisBlock()
+	forged := []*pb.HashedHeader{{Header: genesis.Header, Hash: genesis.Hash}}
+	prev := genesis
+	for i := 0; i < 3; i++ {
+		clock.Advance(10 * time.Second)
+		b := f.NewBlock([]string{"entry"}, prev)
+		prev = b
+
+		// the signature is not part of the hash, so the forged headers
+		// still match the checkpoints.
+		header := proto.Clone(b.Header).(*pb.BlockHeader)
+		header.Signature = make([]byte, ed25519.SignatureSize)
+		forged = append(forged, &pb.HashedHeader{Header: header, Hash: b.Hash})
+	}
+	checkpoints := block.Checkpoints{3: forged[3].Hash}
+
+	if err := NewHeaderChain(newFactory(checkpoints)).ReplaceWith(forged); err != nil {
+		t.Fatalf("headers committed to by a checkpoint were refused: %s", err)
+	}
+
+	if err := NewHeaderChain(newFactory(checkpoints)).ReplaceWith(forged[:3]); err == nil {
+		t.Fatal("forged headers short of the checkpoint were accepted")
+	}
+
+	if err := NewHeaderChain(newFactory(checkpoints)).PushHeader(forged[1].Header, forged[1].Hash); err == nil {
+		t.Fatal("forged header was pushed")
+	}
+}
+
+func TestHeaderChainDifficulty(t *testing.T) {
+	clock := block.NewFakeClock(time.Unix(1600000000, 0))
+	newFactory := func(difficulty int) *block.BlockFactory {
+		return block.NewBlockFactory(
+			block.WithGenesis(&block.GenesisSettings{ChainID: "test", Timestamp: 1600000000}),
+			block.WithClock(clock),
+			block.WithProofOfWork(&block.ProofOfWorkSettings{InitialDifficulty: difficulty, FixedDifficulty: true}),
+		)
+	}
+	f := newFactory(2)
+	headers := NewHeaderChain(f)
+
+	// headers without work must not add chain work for free.
+	clock.Advance(10 * time.Second)
+	free := newFactory(0).NewBlock([]string{"entry"}, f.GenesisBlock())
+	if err := headers.PushHeader(free.Header, free.Hash); err == nil {
+		t.Fatal("header without work was accepted")
+	}
+
+	b := f.NewBlock([]string{"entry"}, f.GenesisBlock())
+	if err := headers.PushHeader(b.Header, b.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if got := headers.GetCumulativeDifficulty().Int64(); got != 4 {
+		t.Fatalf("cumulative difficulty = %d, want 4", got)
+	}
+}
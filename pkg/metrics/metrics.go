@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace string = "naivecoin"
+)
+
+var (
+	// Registry contains all the metrics of the node. It is separate from the
+	// default one, so that only metrics relevant to the node are exposed.
+	Registry = prometheus.NewRegistry()
+
+	// ChainHeight is the index of the last block on the chain.
+	ChainHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "chain_height",
+		Help:      "Index of the last block on the chain.",
+	})
+	// FinalizedHeight is the index of the last finalized block on the chain.
+	FinalizedHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "finalized_height",
+		Help:      "Index of the last finalized block on the chain.",
+	})
+	// CumulativeDifficulty is the sum of the work of all blocks on the chain.
+	CumulativeDifficulty = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "chain_cumulative_difficulty",
+		Help:      "Sum of the work of all blocks on the chain.",
+	})
+	// CurrentDifficulty is the difficulty the next block will be mined with.
+	CurrentDifficulty = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "chain_current_difficulty",
+		Help:      "Difficulty the next block will be mined with.",
+	})
+	// ChainReplacements counts how many times the chain was replaced with a
+	// peer's one.
+	ChainReplacements = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chain_replacements_total",
+		Help:      "Number of times the chain was replaced with a peer's one.",
+	})
+	// MiningDuration observes how long it takes to mine a block.
+	MiningDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mining_duration_seconds",
+		Help:      "Time spent mining a block.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+	})
+	// Hashrate is the number of hashes per second calculated while mining
+	// the last block.
+	Hashrate = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mining_hashrate",
+		Help:      "Hashes per second calculated while mining the last block.",
+	})
+	// PeersConnected is the number of peers currently added.
+	PeersConnected = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "peers_connected",
+		Help:      "Number of peers currently added.",
+	})
+	// BlocksReceived counts the blocks received from peers.
+	BlocksReceived = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blocks_received_total",
+		Help:      "Number of blocks received from peers.",
+	})
+	// BlocksRejected counts the blocks that were not pushed to the chain,
+	// by reason.
+	BlocksRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blocks_rejected_total",
+		Help:      "Number of blocks that were not pushed to the chain.",
+	}, []string{"reason"})
+	// SubscriberQueueDepth is the number of blocks waiting to be sent to
+	// subscribed peers.
+	SubscriberQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "subscriber_queue_depth",
+		Help:      "Number of blocks waiting to be sent to subscribed peers.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		ChainHeight,
+		FinalizedHeight,
+		CumulativeDifficulty,
+		CurrentDifficulty,
+		ChainReplacements,
+		MiningDuration,
+		Hashrate,
+		PeersConnected,
+		BlocksReceived,
+		BlocksRejected,
+		SubscriberQueueDepth,
+	)
+}
+
+// BlockMetrics reports the measurements of a blockchain and of the blocks
+// mined by its factory to the collectors of the Registry. Only the chain of
+// the node should use it, as the collectors are shared by the whole process.
+type BlockMetrics struct{}
+
+// BlockRejected counts a block that was not pushed to the chain.
+func (BlockMetrics) BlockRejected(reason string) {
+	BlocksRejected.WithLabelValues(reason).Inc()
+}
+
+// ChainReplaced counts a replacement of the chain.
+func (BlockMetrics) ChainReplaced() {
+	ChainReplacements.Inc()
+}
+
+// ChainUpdated sets the height and the cumulative difficulty of the chain.
+func (BlockMetrics) ChainUpdated(height int64, cumulativeDifficulty *big.Int) {
+	ChainHeight.Set(float64(height))
+	value, _ := big.NewFloat(0).SetInt(cumulativeDifficulty).Float64()
+	CumulativeDifficulty.Set(value)
+}
+
+// DifficultyUpdated sets the difficulty the next block will be mined with.
+func (BlockMetrics) DifficultyUpdated(difficulty int) {
+	CurrentDifficulty.Set(float64(difficulty))
+}
+
+// BlockFinalized sets the height of the last finalized block.
+func (BlockMetrics) BlockFinalized(height int64) {
+	FinalizedHeight.Set(float64(height))
+}
+
+// BlockMined observes the time spent mining a block and sets the hashrate.
+func (BlockMetrics) BlockMined(duration time.Duration, hashes int64) {
+	MiningDuration.Observe(duration.Seconds())
+	if duration > 0 {
+		Hashrate.Set(float64(hashes) / duration.Seconds())
+	}
+}
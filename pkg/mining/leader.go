@@ -0,0 +1,156 @@
+package mining
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// forwardTimeout is how long a follower waits for the leader to mine a
+	// forwarded entry.
+	forwardTimeout = 5 * time.Minute
+	// maxMineAttempts is how many times an entry is submitted when it could
+	// not be mined because leadership was changing.
+	maxMineAttempts int = 5
+	// retryDelay is how long to wait before submitting an entry again.
+	retryDelay = 2 * time.Second
+)
+
+// LeaderResolver finds the node that was elected to mine blocks.
+type LeaderResolver interface {
+	// Leader returns the peer that holds the leadership, or nil if it is
+	// this node.
+	Leader(ctx context.Context) (*peers.Peer, error)
+}
+
+// ChainSyncer downloads the blocks that peers have and the node doesn't.
+type ChainSyncer interface {
+	CatchUp(ctx context.Context) error
+}
+
+// LeaderMiner only mines blocks on the node elected as leader: other nodes
+// forward the entries they receive to it over gRPC. This way nodes of the same
+// cluster never mine competing blocks.
+type LeaderMiner struct {
+	worker   *Worker
+	resolver LeaderResolver
+	syncer   ChainSyncer
+}
+
+// NewLeaderMiner creates and returns a new instance of the LeaderMiner. The
+// worker only runs while Start is running, which must only happen while the
+// node is the leader.
+func NewLeaderMiner(worker *Worker, resolver LeaderResolver, syncer ChainSyncer) *LeaderMiner {
+	return &LeaderMiner{
+		worker:   worker,
+		resolver: resolver,
+		syncer:   syncer,
+	}
+}
+
+// Start runs the mining worker until the context is cancelled, i.e. until
+// leadership is lost. It first downloads the blocks mined by the previous
+// leader that the node did not receive yet, so that new blocks follow them.
+func (l *LeaderMiner) Start(ctx context.Context) error {
+	log.Info().Msg("elected as miner, catching up with peers...")
+	if err := l.syncer.CatchUp(ctx); err != nil {
+		log.Err(err).Msg("could not catch up with peers, mining anyways")
+	}
+
+	log.Info().Msg("mining worker started")
+	err := l.worker.Run(ctx)
+	log.Info().Msg("mining worker stopped")
+	return err
+}
+
+// Mine mines the entry with the local worker if the node is the leader, or
+// forwards it to the leader otherwise. Entries that could not be mined
+// because leadership was changing are submitted again.
+func (l *LeaderMiner) Mine(ctx context.Context, entry string) (*pb.Block, error) {
+	for attempt := 1; ; attempt++ {
+		newBlock, err := l.mine(ctx, entry)
+		if err == nil || attempt == maxMineAttempts ||
+			!(errors.Is(err, ErrNotMining) || errors.Is(err, ErrMiningAborted)) {
+			return newBlock, err
+		}
+
+		log.Info().Err(err).Int("attempt", attempt).Msg("could not mine entry, trying again...")
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (l *LeaderMiner) mine(ctx context.Context, entry string) (*pb.Block, error) {
+	if l.worker.Running() {
+		return l.worker.Mine(ctx, entry)
+	}
+
+	leader, err := l.resolver.Leader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not find leader: %s", ErrNotMining, err)
+	}
+	if leader == nil {
+		// we were just elected, but the worker did not start yet.
+		return nil, ErrNotMining
+	}
+
+	fwdCtx, canc := context.WithTimeout(ctx, forwardTimeout)
+	defer canc()
+
+	newBlock, err := leader.SubmitEntry(fwdCtx, entry)
+	if err != nil {
+		st := status.Convert(err)
+		switch st.Code() {
+		case codes.InvalidArgument:
+			return nil, leaderError(ErrBlockRejected, st)
+		case codes.FailedPrecondition:
+			// the peer is not the leader anymore.
+			return nil, leaderError(ErrNotMining, st)
+		case codes.Aborted:
+			return nil, leaderError(ErrMiningAborted, st)
+		default:
+			return nil, fmt.Errorf("could not forward entry to leader %s: %w", leader.Name, err)
+		}
+	}
+
+	return newBlock, nil
+}
+
+// leaderError returns the error that the leader returned with the status,
+// wrapping the provided one.
+func leaderError(err error, st *status.Status) error {
+	if msg := st.Message(); !strings.HasPrefix(msg, err.Error()) {
+		return fmt.Errorf("%w: %s", err, msg)
+	}
+
+	return fmt.Errorf("%w%s", err, strings.TrimPrefix(st.Message(), err.Error()))
+}
+
+// SubmissionStatus returns the gRPC status of the error returned by a Worker,
+// so that followers can tell why the entries they forwarded were not mined.
+func SubmissionStatus(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrBlockRejected):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrNotMining):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, ErrMiningAborted):
+		return status.Error(codes.Aborted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
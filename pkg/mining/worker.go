@@ -0,0 +1,165 @@
+// Package mining runs the mining of the entries submitted to a node, either
+// on the node itself or on the one elected to mine for the whole cluster.
+package mining
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+)
+
+var (
+	// ErrNotMining is returned when the entry could not be mined because
+	// the worker is not running, e.g. because the node is not the leader.
+	// The entry can be submitted again.
+	ErrNotMining = errors.New("the mining worker is not running")
+	// ErrMiningAborted is returned when the worker was stopped while
+	// mining the entry, before the block was pushed to the chain. The entry
+	// can be submitted again.
+	ErrMiningAborted = errors.New("mining was aborted")
+	// ErrBlockRejected is returned when the mined block could not be pushed
+	// to the chain.
+	ErrBlockRejected = errors.New("block was rejected")
+)
+
+// Miner mines blocks containing the entries submitted to the node.
+type Miner interface {
+	// Mine mines a block containing the provided entry, pushes it to the
+	// chain and returns it.
+	Mine(ctx context.Context, entry string) (*pb.Block, error)
+}
+
+// submission is an entry waiting to be mined.
+type submission struct {
+	entry  string
+	result chan *result
+}
+
+type result struct {
+	block *pb.Block
+	err   error
+}
+
+// Worker mines the submitted entries one at a time, so that blocks mined by
+// the node never compete with each other.
+type Worker struct {
+	blockchain *block.BlockChain
+	factory    *block.BlockFactory
+	events     *events.Bus
+
+	submissions chan *submission
+	// stopped is closed when the current run of the worker ends. It is nil
+	// if the worker is not running.
+	stopped chan struct{}
+	lock    sync.Mutex
+}
+
+// NewWorker creates and returns a new instance of the Worker. It does not mine
+// anything until Run is called.
+func NewWorker(blockchain *block.BlockChain, factory *block.BlockFactory, bus *events.Bus) *Worker {
+	return &Worker{
+		blockchain:  blockchain,
+		factory:     factory,
+		events:      bus,
+		submissions: make(chan *submission),
+		lock:        sync.Mutex{},
+	}
+}
+
+// Run mines the submitted entries until the context is cancelled. A block that
+// is being mined when that happens is discarded, and its submitter gets
+// ErrMiningAborted.
+func (w *Worker) Run(ctx context.Context) error {
+	w.lock.Lock()
+	if w.stopped != nil {
+		w.lock.Unlock()
+		return fmt.Errorf("worker is already running")
+	}
+	stopped := make(chan struct{})
+	w.stopped = stopped
+	w.lock.Unlock()
+
+	defer func() {
+		w.lock.Lock()
+		w.stopped = nil
+		w.lock.Unlock()
+		close(stopped)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sub := <-w.submissions:
+			newBlock, err := w.mine(ctx, sub.entry)
+			sub.result <- &result{block: newBlock, err: err}
+		}
+	}
+}
+
+// Running returns true if the worker is mining the submitted entries.
+func (w *Worker) Running() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.stopped != nil
+}
+
+// Mine submits the entry to the worker and waits until its block is mined.
+func (w *Worker) Mine(ctx context.Context, entry string) (*pb.Block, error) {
+	w.lock.Lock()
+	stopped := w.stopped
+	w.lock.Unlock()
+
+	if stopped == nil {
+		return nil, ErrNotMining
+	}
+
+	// submissions is not buffered, so an entry is either taken by the
+	// running worker, which then always sends a result, or not taken at
+	// all.
+	sub := &submission{entry: entry, result: make(chan *result, 1)}
+	select {
+	case w.submissions <- sub:
+	case <-stopped:
+		return nil, ErrNotMining
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case res := <-sub.result:
+		return res.block, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (w *Worker) mine(ctx context.Context, entry string) (*pb.Block, error) {
+	newBlock, err := w.factory.MineBlock(ctx, []string{entry}, w.blockchain.GetLastBlock())
+	if err != nil {
+		return nil, ErrMiningAborted
+	}
+
+	// The context is cancelled as soon as the worker must stop, e.g. when
+	// leadership is lost: leaders step down well before their lease
+	// expires, so no other node can be mining yet if it is still valid.
+	if ctx.Err() != nil {
+		return nil, ErrMiningAborted
+	}
+
+	if err := w.blockchain.PushBlock(newBlock); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBlockRejected, err)
+	}
+
+	if w.events != nil {
+		w.events.Publish(events.EventBlockMined, newBlock)
+	}
+
+	return newBlock, nil
+}
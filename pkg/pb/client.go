@@ -20,6 +20,13 @@ type PeerCommunicationClient interface {
 	GetLatestBlock(ctx context.Context, in *GetLatestBlockParams, opts ...grpc.CallOption) (*Block, error)
 	GetFullBlockChain(ctx context.Context, in *GetFullBlockChainParams, opts ...grpc.CallOption) (*BlockChain, error)
 	SubscribeNewBlocks(ctx context.Context, in *SubscribeNewBlocksParams, opts ...grpc.CallOption) (PeerCommunication_SubscribeNewBlocksClient, error)
+	GetMerkleProof(ctx context.Context, in *GetMerkleProofParams, opts ...grpc.CallOption) (*MerkleProof, error)
+	GetHeaders(ctx context.Context, in *GetHeadersParams, opts ...grpc.CallOption) (*Headers, error)
+	GetBlocks(ctx context.Context, in *GetBlocksParams, opts ...grpc.CallOption) (*BlockChain, error)
+	SubmitEntry(ctx context.Context, in *SubmitEntryParams, opts ...grpc.CallOption) (*Block, error)
+	Prevote(ctx context.Context, in *FinalityVote, opts ...grpc.CallOption) (*FinalityVoteAck, error)
+	Precommit(ctx context.Context, in *FinalityVote, opts ...grpc.CallOption) (*FinalityVoteAck, error)
+	GetAddressEntries(ctx context.Context, in *GetAddressEntriesParams, opts ...grpc.CallOption) (*ProvenEntries, error)
 }
 
 type peerCommunicationClient struct {
@@ -80,6 +87,69 @@ func (x *peerCommunicationSubscribeNewBlocksClient) Recv() (*Block, error) {
 	return m, nil
 }
 
+func (c *peerCommunicationClient) GetMerkleProof(ctx context.Context, in *GetMerkleProofParams, opts ...grpc.CallOption) (*MerkleProof, error) {
+	out := new(MerkleProof)
+	err := c.cc.Invoke(ctx, "/networking.PeerCommunication/GetMerkleProof", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peerCommunicationClient) GetHeaders(ctx context.Context, in *GetHeadersParams, opts ...grpc.CallOption) (*Headers, error) {
+	out := new(Headers)
+	err := c.cc.Invoke(ctx, "/networking.PeerCommunication/GetHeaders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peerCommunicationClient) GetBlocks(ctx context.Context, in *GetBlocksParams, opts ...grpc.CallOption) (*BlockChain, error) {
+	out := new(BlockChain)
+	err := c.cc.Invoke(ctx, "/networking.PeerCommunication/GetBlocks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peerCommunicationClient) SubmitEntry(ctx context.Context, in *SubmitEntryParams, opts ...grpc.CallOption) (*Block, error) {
+	out := new(Block)
+	err := c.cc.Invoke(ctx, "/networking.PeerCommunication/SubmitEntry", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peerCommunicationClient) Prevote(ctx context.Context, in *FinalityVote, opts ...grpc.CallOption) (*FinalityVoteAck, error) {
+	out := new(FinalityVoteAck)
+	err := c.cc.Invoke(ctx, "/networking.PeerCommunication/Prevote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peerCommunicationClient) Precommit(ctx context.Context, in *FinalityVote, opts ...grpc.CallOption) (*FinalityVoteAck, error) {
+	out := new(FinalityVoteAck)
+	err := c.cc.Invoke(ctx, "/networking.PeerCommunication/Precommit", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *peerCommunicationClient) GetAddressEntries(ctx context.Context, in *GetAddressEntriesParams, opts ...grpc.CallOption) (*ProvenEntries, error) {
+	out := new(ProvenEntries)
+	err := c.cc.Invoke(ctx, "/networking.PeerCommunication/GetAddressEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PeerCommunicationServer is the server API for PeerCommunication service.
 // All implementations must embed UnimplementedPeerCommunicationServer
 // for forward compatibility
@@ -87,6 +157,13 @@ type PeerCommunicationServer interface {
 	GetLatestBlock(context.Context, *GetLatestBlockParams) (*Block, error)
 	GetFullBlockChain(context.Context, *GetFullBlockChainParams) (*BlockChain, error)
 	SubscribeNewBlocks(*SubscribeNewBlocksParams, PeerCommunication_SubscribeNewBlocksServer) error
+	GetMerkleProof(context.Context, *GetMerkleProofParams) (*MerkleProof, error)
+	GetHeaders(context.Context, *GetHeadersParams) (*Headers, error)
+	GetBlocks(context.Context, *GetBlocksParams) (*BlockChain, error)
+	SubmitEntry(context.Context, *SubmitEntryParams) (*Block, error)
+	Prevote(context.Context, *FinalityVote) (*FinalityVoteAck, error)
+	Precommit(context.Context, *FinalityVote) (*FinalityVoteAck, error)
+	GetAddressEntries(context.Context, *GetAddressEntriesParams) (*ProvenEntries, error)
 	mustEmbedUnimplementedPeerCommunicationServer()
 }
 
@@ -103,6 +180,27 @@ func (UnimplementedPeerCommunicationServer) GetFullBlockChain(context.Context, *
 func (UnimplementedPeerCommunicationServer) SubscribeNewBlocks(*SubscribeNewBlocksParams, PeerCommunication_SubscribeNewBlocksServer) error {
 	return status.Errorf(codes.Unimplemented, "method SubscribeNewBlocks not implemented")
 }
+func (UnimplementedPeerCommunicationServer) GetMerkleProof(context.Context, *GetMerkleProofParams) (*MerkleProof, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMerkleProof not implemented")
+}
+func (UnimplementedPeerCommunicationServer) GetHeaders(context.Context, *GetHeadersParams) (*Headers, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHeaders not implemented")
+}
+func (UnimplementedPeerCommunicationServer) GetBlocks(context.Context, *GetBlocksParams) (*BlockChain, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlocks not implemented")
+}
+func (UnimplementedPeerCommunicationServer) SubmitEntry(context.Context, *SubmitEntryParams) (*Block, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitEntry not implemented")
+}
+func (UnimplementedPeerCommunicationServer) Prevote(context.Context, *FinalityVote) (*FinalityVoteAck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Prevote not implemented")
+}
+func (UnimplementedPeerCommunicationServer) Precommit(context.Context, *FinalityVote) (*FinalityVoteAck, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Precommit not implemented")
+}
+func (UnimplementedPeerCommunicationServer) GetAddressEntries(context.Context, *GetAddressEntriesParams) (*ProvenEntries, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAddressEntries not implemented")
+}
 func (UnimplementedPeerCommunicationServer) mustEmbedUnimplementedPeerCommunicationServer() {}
 
 // UnsafePeerCommunicationServer may be embedded to opt out of forward compatibility for this service.
@@ -173,6 +271,132 @@ func (x *peerCommunicationSubscribeNewBlocksServer) Send(m *Block) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _PeerCommunication_GetMerkleProof_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMerkleProofParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeerCommunicationServer).GetMerkleProof(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/networking.PeerCommunication/GetMerkleProof",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeerCommunicationServer).GetMerkleProof(ctx, req.(*GetMerkleProofParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeerCommunication_GetHeaders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHeadersParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeerCommunicationServer).GetHeaders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/networking.PeerCommunication/GetHeaders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeerCommunicationServer).GetHeaders(ctx, req.(*GetHeadersParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeerCommunication_GetBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBlocksParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeerCommunicationServer).GetBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/networking.PeerCommunication/GetBlocks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeerCommunicationServer).GetBlocks(ctx, req.(*GetBlocksParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeerCommunication_SubmitEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitEntryParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeerCommunicationServer).SubmitEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/networking.PeerCommunication/SubmitEntry",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeerCommunicationServer).SubmitEntry(ctx, req.(*SubmitEntryParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeerCommunication_Prevote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinalityVote)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeerCommunicationServer).Prevote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/networking.PeerCommunication/Prevote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeerCommunicationServer).Prevote(ctx, req.(*FinalityVote))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeerCommunication_Precommit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinalityVote)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeerCommunicationServer).Precommit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/networking.PeerCommunication/Precommit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeerCommunicationServer).Precommit(ctx, req.(*FinalityVote))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PeerCommunication_GetAddressEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAddressEntriesParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PeerCommunicationServer).GetAddressEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/networking.PeerCommunication/GetAddressEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PeerCommunicationServer).GetAddressEntries(ctx, req.(*GetAddressEntriesParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _PeerCommunication_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "networking.PeerCommunication",
 	HandlerType: (*PeerCommunicationServer)(nil),
@@ -185,6 +409,34 @@ var _PeerCommunication_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetFullBlockChain",
 			Handler:    _PeerCommunication_GetFullBlockChain_Handler,
 		},
+		{
+			MethodName: "GetMerkleProof",
+			Handler:    _PeerCommunication_GetMerkleProof_Handler,
+		},
+		{
+			MethodName: "GetHeaders",
+			Handler:    _PeerCommunication_GetHeaders_Handler,
+		},
+		{
+			MethodName: "GetBlocks",
+			Handler:    _PeerCommunication_GetBlocks_Handler,
+		},
+		{
+			MethodName: "SubmitEntry",
+			Handler:    _PeerCommunication_SubmitEntry_Handler,
+		},
+		{
+			MethodName: "Prevote",
+			Handler:    _PeerCommunication_Prevote_Handler,
+		},
+		{
+			MethodName: "Precommit",
+			Handler:    _PeerCommunication_Precommit_Handler,
+		},
+		{
+			MethodName: "GetAddressEntries",
+			Handler:    _PeerCommunication_GetAddressEntries_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
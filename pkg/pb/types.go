@@ -20,7 +20,7 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-type Block struct {
+type BlockHeader struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
@@ -29,29 +29,715 @@ type Block struct {
 	Index             int64  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
 	Timestamp         int64  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	PreviousBlockHash []byte `protobuf:"bytes,3,opt,name=previousBlockHash,proto3" json:"previousBlockHash,omitempty"`
-	Data              string `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
-	Hash              []byte `protobuf:"bytes,5,opt,name=hash,proto3" json:"hash,omitempty"`
+	MerkleRoot        []byte `protobuf:"bytes,4,opt,name=merkleRoot,proto3" json:"merkleRoot,omitempty"`
+	Difficulty        int64  `protobuf:"varint,5,opt,name=difficulty,proto3" json:"difficulty,omitempty"`
 	Nonce             int64  `protobuf:"varint,6,opt,name=nonce,proto3" json:"nonce,omitempty"`
-	Difficulty        int64  `protobuf:"varint,7,opt,name=difficulty,proto3" json:"difficulty,omitempty"`
+	// The following fields are only used with proof of authority.
+	// validator is the public key of the validator that sealed the block.
+	Validator []byte `protobuf:"bytes,7,opt,name=validator,proto3" json:"validator,omitempty"`
+	// signature is the signature of the block hash by the validator. It is
+	// not part of the hash.
+	Signature []byte `protobuf:"bytes,8,opt,name=signature,proto3" json:"signature,omitempty"`
+	// voteCandidate is the public key of the validator that the sealer votes
+	// to add to or remove from the validator set, if any.
+	VoteCandidate []byte `protobuf:"bytes,9,opt,name=voteCandidate,proto3" json:"voteCandidate,omitempty"`
+	// voteAuthorize tells whether the vote is to add the candidate.
+	VoteAuthorize bool `protobuf:"varint,10,opt,name=voteAuthorize,proto3" json:"voteAuthorize,omitempty"`
+}
+
+func (x *BlockHeader) Reset() {
+	*x = BlockHeader{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockHeader) ProtoMessage() {}
+
+func (x *BlockHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockHeader.ProtoReflect.Descriptor instead.
+func (*BlockHeader) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BlockHeader) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *BlockHeader) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *BlockHeader) GetPreviousBlockHash() []byte {
+	if x != nil {
+		return x.PreviousBlockHash
+	}
+	return nil
+}
+
+func (x *BlockHeader) GetMerkleRoot() []byte {
+	if x != nil {
+		return x.MerkleRoot
+	}
+	return nil
+}
+
+func (x *BlockHeader) GetDifficulty() int64 {
+	if x != nil {
+		return x.Difficulty
+	}
+	return 0
+}
+
+func (x *BlockHeader) GetNonce() int64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *BlockHeader) GetValidator() []byte {
+	if x != nil {
+		return x.Validator
+	}
+	return nil
+}
+
+func (x *BlockHeader) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *BlockHeader) GetVoteCandidate() []byte {
+	if x != nil {
+		return x.VoteCandidate
+	}
+	return nil
+}
+
+func (x *BlockHeader) GetVoteAuthorize() bool {
+	if x != nil {
+		return x.VoteAuthorize
+	}
+	return false
+}
+
+type Block struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Header  *BlockHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Entries []string     `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"`
+	Hash    []byte       `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
 }
 
 func (x *Block) Reset() {
 	*x = Block{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_networking_proto_msgTypes[0]
+		mi := &file_networking_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Block) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Block) ProtoMessage() {}
+
+func (x *Block) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Block.ProtoReflect.Descriptor instead.
+func (*Block) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Block) GetHeader() *BlockHeader {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+func (x *Block) GetEntries() []string {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *Block) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+type BlockChain struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Blocks []*Block `protobuf:"bytes,1,rep,name=blocks,proto3" json:"blocks,omitempty"`
+}
+
+func (x *BlockChain) Reset() {
+	*x = BlockChain{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockChain) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockChain) ProtoMessage() {}
+
+func (x *BlockChain) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockChain.ProtoReflect.Descriptor instead.
+func (*BlockChain) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BlockChain) GetBlocks() []*Block {
+	if x != nil {
+		return x.Blocks
+	}
+	return nil
+}
+
+type HashedHeader struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Header *BlockHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Hash   []byte       `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (x *HashedHeader) Reset() {
+	*x = HashedHeader{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HashedHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HashedHeader) ProtoMessage() {}
+
+func (x *HashedHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HashedHeader.ProtoReflect.Descriptor instead.
+func (*HashedHeader) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *HashedHeader) GetHeader() *BlockHeader {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+func (x *HashedHeader) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+type Headers struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Headers []*HashedHeader `protobuf:"bytes,1,rep,name=headers,proto3" json:"headers,omitempty"`
+}
+
+func (x *Headers) Reset() {
+	*x = Headers{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Headers) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Headers) ProtoMessage() {}
+
+func (x *Headers) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Headers.ProtoReflect.Descriptor instead.
+func (*Headers) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Headers) GetHeaders() []*HashedHeader {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+type MerkleProof struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Header     *BlockHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	BlockHash  []byte       `protobuf:"bytes,2,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
+	EntryHash  []byte       `protobuf:"bytes,3,opt,name=entryHash,proto3" json:"entryHash,omitempty"`
+	EntryIndex int64        `protobuf:"varint,4,opt,name=entryIndex,proto3" json:"entryIndex,omitempty"`
+	Branch     [][]byte     `protobuf:"bytes,5,rep,name=branch,proto3" json:"branch,omitempty"`
+	// entryCount is the number of entries of the block, which is committed
+	// to by the merkle root.
+	EntryCount int64 `protobuf:"varint,6,opt,name=entryCount,proto3" json:"entryCount,omitempty"`
+}
+
+func (x *MerkleProof) Reset() {
+	*x = MerkleProof{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MerkleProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleProof) ProtoMessage() {}
+
+func (x *MerkleProof) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleProof.ProtoReflect.Descriptor instead.
+func (*MerkleProof) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *MerkleProof) GetHeader() *BlockHeader {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+func (x *MerkleProof) GetBlockHash() []byte {
+	if x != nil {
+		return x.BlockHash
+	}
+	return nil
+}
+
+func (x *MerkleProof) GetEntryHash() []byte {
+	if x != nil {
+		return x.EntryHash
+	}
+	return nil
+}
+
+func (x *MerkleProof) GetEntryIndex() int64 {
+	if x != nil {
+		return x.EntryIndex
+	}
+	return 0
+}
+
+func (x *MerkleProof) GetBranch() [][]byte {
+	if x != nil {
+		return x.Branch
+	}
+	return nil
+}
+
+func (x *MerkleProof) GetEntryCount() int64 {
+	if x != nil {
+		return x.EntryCount
+	}
+	return 0
+}
+
+// ProvenEntry is an entry along with the proof of its inclusion.
+type ProvenEntry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Entry string       `protobuf:"bytes,1,opt,name=entry,proto3" json:"entry,omitempty"`
+	Proof *MerkleProof `protobuf:"bytes,2,opt,name=proof,proto3" json:"proof,omitempty"`
+}
+
+func (x *ProvenEntry) Reset() {
+	*x = ProvenEntry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProvenEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProvenEntry) ProtoMessage() {}
+
+func (x *ProvenEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProvenEntry.ProtoReflect.Descriptor instead.
+func (*ProvenEntry) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ProvenEntry) GetEntry() string {
+	if x != nil {
+		return x.Entry
+	}
+	return ""
+}
+
+func (x *ProvenEntry) GetProof() *MerkleProof {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+type ProvenEntries struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Entries []*ProvenEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+}
+
+func (x *ProvenEntries) Reset() {
+	*x = ProvenEntries{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProvenEntries) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProvenEntries) ProtoMessage() {}
+
+func (x *ProvenEntries) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProvenEntries.ProtoReflect.Descriptor instead.
+func (*ProvenEntries) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ProvenEntries) GetEntries() []*ProvenEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type GetLatestBlockParams struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetLatestBlockParams) Reset() {
+	*x = GetLatestBlockParams{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetLatestBlockParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLatestBlockParams) ProtoMessage() {}
+
+func (x *GetLatestBlockParams) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLatestBlockParams.ProtoReflect.Descriptor instead.
+func (*GetLatestBlockParams) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{8}
+}
+
+type GetFullBlockChainParams struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetFullBlockChainParams) Reset() {
+	*x = GetFullBlockChainParams{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetFullBlockChainParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFullBlockChainParams) ProtoMessage() {}
+
+func (x *GetFullBlockChainParams) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFullBlockChainParams.ProtoReflect.Descriptor instead.
+func (*GetFullBlockChainParams) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{9}
+}
+
+type SubscribeNewBlocksParams struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SubscribeNewBlocksParams) Reset() {
+	*x = SubscribeNewBlocksParams{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubscribeNewBlocksParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeNewBlocksParams) ProtoMessage() {}
+
+func (x *SubscribeNewBlocksParams) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeNewBlocksParams.ProtoReflect.Descriptor instead.
+func (*SubscribeNewBlocksParams) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{10}
+}
+
+type GetMerkleProofParams struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	EntryHash []byte `protobuf:"bytes,1,opt,name=entryHash,proto3" json:"entryHash,omitempty"`
+}
+
+func (x *GetMerkleProofParams) Reset() {
+	*x = GetMerkleProofParams{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetMerkleProofParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMerkleProofParams) ProtoMessage() {}
+
+func (x *GetMerkleProofParams) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMerkleProofParams.ProtoReflect.Descriptor instead.
+func (*GetMerkleProofParams) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetMerkleProofParams) GetEntryHash() []byte {
+	if x != nil {
+		return x.EntryHash
+	}
+	return nil
+}
+
+type GetHeadersParams struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FromIndex int64 `protobuf:"varint,1,opt,name=fromIndex,proto3" json:"fromIndex,omitempty"`
+	Limit     int64 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+}
+
+func (x *GetHeadersParams) Reset() {
+	*x = GetHeadersParams{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Block) String() string {
+func (x *GetHeadersParams) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Block) ProtoMessage() {}
+func (*GetHeadersParams) ProtoMessage() {}
 
-func (x *Block) ProtoReflect() protoreflect.Message {
-	mi := &file_networking_proto_msgTypes[0]
+func (x *GetHeadersParams) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -62,85 +748,105 @@ func (x *Block) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Block.ProtoReflect.Descriptor instead.
-func (*Block) Descriptor() ([]byte, []int) {
-	return file_networking_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use GetHeadersParams.ProtoReflect.Descriptor instead.
+func (*GetHeadersParams) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *Block) GetIndex() int64 {
+func (x *GetHeadersParams) GetFromIndex() int64 {
 	if x != nil {
-		return x.Index
+		return x.FromIndex
 	}
 	return 0
 }
 
-func (x *Block) GetTimestamp() int64 {
+func (x *GetHeadersParams) GetLimit() int64 {
 	if x != nil {
-		return x.Timestamp
+		return x.Limit
 	}
 	return 0
 }
 
-func (x *Block) GetPreviousBlockHash() []byte {
-	if x != nil {
-		return x.PreviousBlockHash
-	}
-	return nil
+type GetBlocksParams struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FromIndex int64 `protobuf:"varint,1,opt,name=fromIndex,proto3" json:"fromIndex,omitempty"`
+	Limit     int64 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
 }
 
-func (x *Block) GetData() string {
-	if x != nil {
-		return x.Data
+func (x *GetBlocksParams) Reset() {
+	*x = GetBlocksParams{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_networking_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
 	}
-	return ""
 }
 
-func (x *Block) GetHash() []byte {
-	if x != nil {
-		return x.Hash
+func (x *GetBlocksParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBlocksParams) ProtoMessage() {}
+
+func (x *GetBlocksParams) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBlocksParams.ProtoReflect.Descriptor instead.
+func (*GetBlocksParams) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *Block) GetNonce() int64 {
+func (x *GetBlocksParams) GetFromIndex() int64 {
 	if x != nil {
-		return x.Nonce
+		return x.FromIndex
 	}
 	return 0
 }
 
-func (x *Block) GetDifficulty() int64 {
+func (x *GetBlocksParams) GetLimit() int64 {
 	if x != nil {
-		return x.Difficulty
+		return x.Limit
 	}
 	return 0
 }
 
-type BlockChain struct {
+type GetAddressEntriesParams struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Blocks []*Block `protobuf:"bytes,1,rep,name=blocks,proto3" json:"blocks,omitempty"`
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
 }
 
-func (x *BlockChain) Reset() {
-	*x = BlockChain{}
+func (x *GetAddressEntriesParams) Reset() {
+	*x = GetAddressEntriesParams{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_networking_proto_msgTypes[1]
+		mi := &file_networking_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *BlockChain) String() string {
+func (x *GetAddressEntriesParams) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BlockChain) ProtoMessage() {}
+func (*GetAddressEntriesParams) ProtoMessage() {}
 
-func (x *BlockChain) ProtoReflect() protoreflect.Message {
-	mi := &file_networking_proto_msgTypes[1]
+func (x *GetAddressEntriesParams) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -151,41 +857,43 @@ func (x *BlockChain) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BlockChain.ProtoReflect.Descriptor instead.
-func (*BlockChain) Descriptor() ([]byte, []int) {
-	return file_networking_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use GetAddressEntriesParams.ProtoReflect.Descriptor instead.
+func (*GetAddressEntriesParams) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *BlockChain) GetBlocks() []*Block {
+func (x *GetAddressEntriesParams) GetAddress() string {
 	if x != nil {
-		return x.Blocks
+		return x.Address
 	}
-	return nil
+	return ""
 }
 
-type GetLatestBlockParams struct {
+type SubmitEntryParams struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	Entry string `protobuf:"bytes,1,opt,name=entry,proto3" json:"entry,omitempty"`
 }
 
-func (x *GetLatestBlockParams) Reset() {
-	*x = GetLatestBlockParams{}
+func (x *SubmitEntryParams) Reset() {
+	*x = SubmitEntryParams{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_networking_proto_msgTypes[2]
+		mi := &file_networking_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *GetLatestBlockParams) String() string {
+func (x *SubmitEntryParams) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetLatestBlockParams) ProtoMessage() {}
+func (*SubmitEntryParams) ProtoMessage() {}
 
-func (x *GetLatestBlockParams) ProtoReflect() protoreflect.Message {
-	mi := &file_networking_proto_msgTypes[2]
+func (x *SubmitEntryParams) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -196,34 +904,48 @@ func (x *GetLatestBlockParams) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetLatestBlockParams.ProtoReflect.Descriptor instead.
-func (*GetLatestBlockParams) Descriptor() ([]byte, []int) {
-	return file_networking_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use SubmitEntryParams.ProtoReflect.Descriptor instead.
+func (*SubmitEntryParams) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{15}
 }
 
-type GetFullBlockChainParams struct {
+func (x *SubmitEntryParams) GetEntry() string {
+	if x != nil {
+		return x.Entry
+	}
+	return ""
+}
+
+// FinalityVote is the vote of a validator of the finality gadget for the
+// checkpoint block at the provided height.
+type FinalityVote struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	Height    int64  `protobuf:"varint,1,opt,name=height,proto3" json:"height,omitempty"`
+	BlockHash []byte `protobuf:"bytes,2,opt,name=blockHash,proto3" json:"blockHash,omitempty"`
+	Validator []byte `protobuf:"bytes,3,opt,name=validator,proto3" json:"validator,omitempty"`
+	Signature []byte `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
 }
 
-func (x *GetFullBlockChainParams) Reset() {
-	*x = GetFullBlockChainParams{}
+func (x *FinalityVote) Reset() {
+	*x = FinalityVote{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_networking_proto_msgTypes[3]
+		mi := &file_networking_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *GetFullBlockChainParams) String() string {
+func (x *FinalityVote) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetFullBlockChainParams) ProtoMessage() {}
+func (*FinalityVote) ProtoMessage() {}
 
-func (x *GetFullBlockChainParams) ProtoReflect() protoreflect.Message {
-	mi := &file_networking_proto_msgTypes[3]
+func (x *FinalityVote) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -234,34 +956,62 @@ func (x *GetFullBlockChainParams) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetFullBlockChainParams.ProtoReflect.Descriptor instead.
-func (*GetFullBlockChainParams) Descriptor() ([]byte, []int) {
-	return file_networking_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use FinalityVote.ProtoReflect.Descriptor instead.
+func (*FinalityVote) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{16}
 }
 
-type SubscribeNewBlocksParams struct {
+func (x *FinalityVote) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *FinalityVote) GetBlockHash() []byte {
+	if x != nil {
+		return x.BlockHash
+	}
+	return nil
+}
+
+func (x *FinalityVote) GetValidator() []byte {
+	if x != nil {
+		return x.Validator
+	}
+	return nil
+}
+
+func (x *FinalityVote) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type FinalityVoteAck struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 }
 
-func (x *SubscribeNewBlocksParams) Reset() {
-	*x = SubscribeNewBlocksParams{}
+func (x *FinalityVoteAck) Reset() {
+	*x = FinalityVoteAck{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_networking_proto_msgTypes[4]
+		mi := &file_networking_proto_msgTypes[17]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *SubscribeNewBlocksParams) String() string {
+func (x *FinalityVoteAck) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SubscribeNewBlocksParams) ProtoMessage() {}
+func (*FinalityVoteAck) ProtoMessage() {}
 
-func (x *SubscribeNewBlocksParams) ProtoReflect() protoreflect.Message {
-	mi := &file_networking_proto_msgTypes[4]
+func (x *FinalityVoteAck) ProtoReflect() protoreflect.Message {
+	mi := &file_networking_proto_msgTypes[17]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -272,57 +1022,164 @@ func (x *SubscribeNewBlocksParams) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SubscribeNewBlocksParams.ProtoReflect.Descriptor instead.
-func (*SubscribeNewBlocksParams) Descriptor() ([]byte, []int) {
-	return file_networking_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use FinalityVoteAck.ProtoReflect.Descriptor instead.
+func (*FinalityVoteAck) Descriptor() ([]byte, []int) {
+	return file_networking_proto_rawDescGZIP(), []int{17}
 }
 
 var File_networking_proto protoreflect.FileDescriptor
 
 var file_networking_proto_rawDesc = []byte{
 	0x0a, 0x10, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x12, 0x0a, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x22, 0xc7,
-	0x01, 0x0a, 0x05, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65,
-	0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x1c,
-	0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x2c, 0x0a, 0x11,
-	0x70, 0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73,
-	0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x11, 0x70, 0x72, 0x65, 0x76, 0x69, 0x6f, 0x75,
-	0x73, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61,
-	0x74, 0x61, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x12,
-	0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x68, 0x61,
-	0x73, 0x68, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28,
-	0x03, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x64, 0x69, 0x66, 0x66,
-	0x69, 0x63, 0x75, 0x6c, 0x74, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x64, 0x69,
-	0x66, 0x66, 0x69, 0x63, 0x75, 0x6c, 0x74, 0x79, 0x22, 0x37, 0x0a, 0x0a, 0x42, 0x6c, 0x6f, 0x63,
-	0x6b, 0x43, 0x68, 0x61, 0x69, 0x6e, 0x12, 0x29, 0x0a, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73,
-	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b,
-	0x69, 0x6e, 0x67, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
-	0x73, 0x22, 0x16, 0x0a, 0x14, 0x47, 0x65, 0x74, 0x4c, 0x61, 0x74, 0x65, 0x73, 0x74, 0x42, 0x6c,
-	0x6f, 0x63, 0x6b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x22, 0x19, 0x0a, 0x17, 0x47, 0x65, 0x74,
-	0x46, 0x75, 0x6c, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x69, 0x6e, 0x50, 0x61,
-	0x72, 0x61, 0x6d, 0x73, 0x22, 0x1a, 0x0a, 0x18, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62,
-	0x65, 0x4e, 0x65, 0x77, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73,
-	0x32, 0x83, 0x02, 0x0a, 0x11, 0x50, 0x65, 0x65, 0x72, 0x43, 0x6f, 0x6d, 0x6d, 0x75, 0x6e, 0x69,
-	0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x47, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x4c, 0x61, 0x74,
-	0x65, 0x73, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x20, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f,
-	0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x47, 0x65, 0x74, 0x4c, 0x61, 0x74, 0x65, 0x73, 0x74, 0x42,
-	0x6c, 0x6f, 0x63, 0x6b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x1a, 0x11, 0x2e, 0x6e, 0x65, 0x74,
-	0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x22, 0x00, 0x12,
-	0x52, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x46, 0x75, 0x6c, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43,
-	0x68, 0x61, 0x69, 0x6e, 0x12, 0x23, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e,
-	0x67, 0x2e, 0x47, 0x65, 0x74, 0x46, 0x75, 0x6c, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43, 0x68,
-	0x61, 0x69, 0x6e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x1a, 0x16, 0x2e, 0x6e, 0x65, 0x74, 0x77,
-	0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x69,
-	0x6e, 0x22, 0x00, 0x12, 0x51, 0x0a, 0x12, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65,
-	0x4e, 0x65, 0x77, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12, 0x24, 0x2e, 0x6e, 0x65, 0x74, 0x77,
-	0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65,
-	0x4e, 0x65, 0x77, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x1a,
-	0x11, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x6c, 0x6f,
-	0x63, 0x6b, 0x22, 0x00, 0x30, 0x01, 0x42, 0x2f, 0x5a, 0x2d, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
-	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x53, 0x75, 0x6e, 0x53, 0x69, 0x6e, 0x63, 0x65, 0x39, 0x30, 0x2f,
-	0x67, 0x6f, 0x2d, 0x6e, 0x61, 0x69, 0x76, 0x65, 0x63, 0x6f, 0x69, 0x6e, 0x2f, 0x6e, 0x65, 0x74,
-	0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x74, 0x6f, 0x12, 0x0a, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x22, 0xcd,
+	0x02, 0x0a, 0x0b, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x14,
+	0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x69,
+	0x6e, 0x64, 0x65, 0x78, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d,
+	0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x12, 0x2c, 0x0a, 0x11, 0x70, 0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x11, 0x70,
+	0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68,
+	0x12, 0x1e, 0x0a, 0x0a, 0x6d, 0x65, 0x72, 0x6b, 0x6c, 0x65, 0x52, 0x6f, 0x6f, 0x74, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a, 0x6d, 0x65, 0x72, 0x6b, 0x6c, 0x65, 0x52, 0x6f, 0x6f, 0x74,
+	0x12, 0x1e, 0x0a, 0x0a, 0x64, 0x69, 0x66, 0x66, 0x69, 0x63, 0x75, 0x6c, 0x74, 0x79, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x64, 0x69, 0x66, 0x66, 0x69, 0x63, 0x75, 0x6c, 0x74, 0x79,
+	0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61,
+	0x74, 0x6f, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x76, 0x61, 0x6c, 0x69, 0x64,
+	0x61, 0x74, 0x6f, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72,
+	0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75,
+	0x72, 0x65, 0x12, 0x24, 0x0a, 0x0d, 0x76, 0x6f, 0x74, 0x65, 0x43, 0x61, 0x6e, 0x64, 0x69, 0x64,
+	0x61, 0x74, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0d, 0x76, 0x6f, 0x74, 0x65, 0x43,
+	0x61, 0x6e, 0x64, 0x69, 0x64, 0x61, 0x74, 0x65, 0x12, 0x24, 0x0a, 0x0d, 0x76, 0x6f, 0x74, 0x65,
+	0x41, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x0d, 0x76, 0x6f, 0x74, 0x65, 0x41, 0x75, 0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x22, 0x66,
+	0x0a, 0x05, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x2f, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65,
+	0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72,
+	0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x52, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e, 0x74, 0x72,
+	0x69, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69,
+	0x65, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x22, 0x37, 0x0a, 0x0a, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43,
+	0x68, 0x61, 0x69, 0x6e, 0x12, 0x29, 0x0a, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e,
+	0x67, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x52, 0x06, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x22,
+	0x53, 0x0a, 0x0c, 0x48, 0x61, 0x73, 0x68, 0x65, 0x64, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12,
+	0x2f, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x17, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72,
+	0x12, 0x12, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04,
+	0x68, 0x61, 0x73, 0x68, 0x22, 0x3d, 0x0a, 0x07, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x12,
+	0x32, 0x0a, 0x07, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x18, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x48, 0x61,
+	0x73, 0x68, 0x65, 0x64, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x07, 0x68, 0x65, 0x61, 0x64,
+	0x65, 0x72, 0x73, 0x22, 0xd2, 0x01, 0x0a, 0x0b, 0x4d, 0x65, 0x72, 0x6b, 0x6c, 0x65, 0x50, 0x72,
+	0x6f, 0x6f, 0x66, 0x12, 0x2f, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67,
+	0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x06, 0x68, 0x65,
+	0x61, 0x64, 0x65, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73,
+	0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61,
+	0x73, 0x68, 0x12, 0x1c, 0x0a, 0x09, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x48, 0x61, 0x73, 0x68, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x48, 0x61, 0x73, 0x68,
+	0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x49, 0x6e, 0x64, 0x65, 0x78,
+	0x12, 0x16, 0x0a, 0x06, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0c,
+	0x52, 0x06, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x6e, 0x74, 0x72,
+	0x79, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x65, 0x6e,
+	0x74, 0x72, 0x79, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x52, 0x0a, 0x0b, 0x50, 0x72, 0x6f, 0x76,
+	0x65, 0x6e, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x6e, 0x74, 0x72, 0x79,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x2d, 0x0a,
+	0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x6e,
+	0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x4d, 0x65, 0x72, 0x6b, 0x6c, 0x65,
+	0x50, 0x72, 0x6f, 0x6f, 0x66, 0x52, 0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x22, 0x42, 0x0a, 0x0d,
+	0x50, 0x72, 0x6f, 0x76, 0x65, 0x6e, 0x45, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x31, 0x0a,
+	0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17,
+	0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x50, 0x72, 0x6f, 0x76,
+	0x65, 0x6e, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73,
+	0x22, 0x16, 0x0a, 0x14, 0x47, 0x65, 0x74, 0x4c, 0x61, 0x74, 0x65, 0x73, 0x74, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x22, 0x19, 0x0a, 0x17, 0x47, 0x65, 0x74, 0x46,
+	0x75, 0x6c, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x69, 0x6e, 0x50, 0x61, 0x72,
+	0x61, 0x6d, 0x73, 0x22, 0x1a, 0x0a, 0x18, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65,
+	0x4e, 0x65, 0x77, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x22,
+	0x34, 0x0a, 0x14, 0x47, 0x65, 0x74, 0x4d, 0x65, 0x72, 0x6b, 0x6c, 0x65, 0x50, 0x72, 0x6f, 0x6f,
+	0x66, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x65, 0x6e, 0x74, 0x72, 0x79,
+	0x48, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x65, 0x6e, 0x74, 0x72,
+	0x79, 0x48, 0x61, 0x73, 0x68, 0x22, 0x46, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x48, 0x65, 0x61, 0x64,
+	0x65, 0x72, 0x73, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x66, 0x72, 0x6f,
+	0x6d, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x66, 0x72,
+	0x6f, 0x6d, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x22, 0x45, 0x0a,
+	0x0f, 0x47, 0x65, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73,
+	0x12, 0x1c, 0x0a, 0x09, 0x66, 0x72, 0x6f, 0x6d, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x09, 0x66, 0x72, 0x6f, 0x6d, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x14,
+	0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6c,
+	0x69, 0x6d, 0x69, 0x74, 0x22, 0x33, 0x0a, 0x17, 0x47, 0x65, 0x74, 0x41, 0x64, 0x64, 0x72, 0x65,
+	0x73, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x12,
+	0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0x29, 0x0a, 0x11, 0x53, 0x75, 0x62,
+	0x6d, 0x69, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x12, 0x14,
+	0x0a, 0x05, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65,
+	0x6e, 0x74, 0x72, 0x79, 0x22, 0x80, 0x01, 0x0a, 0x0c, 0x46, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x74,
+	0x79, 0x56, 0x6f, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1c, 0x0a,
+	0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x12, 0x1c, 0x0a, 0x09, 0x76,
+	0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09,
+	0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67,
+	0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69,
+	0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x22, 0x11, 0x0a, 0x0f, 0x46, 0x69, 0x6e, 0x61, 0x6c,
+	0x69, 0x74, 0x79, 0x56, 0x6f, 0x74, 0x65, 0x41, 0x63, 0x6b, 0x32, 0xfd, 0x05, 0x0a, 0x11, 0x50,
+	0x65, 0x65, 0x72, 0x43, 0x6f, 0x6d, 0x6d, 0x75, 0x6e, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x47, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x4c, 0x61, 0x74, 0x65, 0x73, 0x74, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x12, 0x20, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e,
+	0x47, 0x65, 0x74, 0x4c, 0x61, 0x74, 0x65, 0x73, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x50, 0x61,
+	0x72, 0x61, 0x6d, 0x73, 0x1a, 0x11, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e,
+	0x67, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x22, 0x00, 0x12, 0x52, 0x0a, 0x11, 0x47, 0x65, 0x74,
+	0x46, 0x75, 0x6c, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x69, 0x6e, 0x12, 0x23,
+	0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x47, 0x65, 0x74, 0x46,
+	0x75, 0x6c, 0x6c, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x69, 0x6e, 0x50, 0x61, 0x72,
+	0x61, 0x6d, 0x73, 0x1a, 0x16, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67,
+	0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x69, 0x6e, 0x22, 0x00, 0x12, 0x51, 0x0a,
+	0x12, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x4e, 0x65, 0x77, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x73, 0x12, 0x24, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67,
+	0x2e, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x4e, 0x65, 0x77, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x73, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x1a, 0x11, 0x2e, 0x6e, 0x65, 0x74, 0x77,
+	0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x22, 0x00, 0x30, 0x01,
+	0x12, 0x4d, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x4d, 0x65, 0x72, 0x6b, 0x6c, 0x65, 0x50, 0x72, 0x6f,
+	0x6f, 0x66, 0x12, 0x20, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e,
+	0x47, 0x65, 0x74, 0x4d, 0x65, 0x72, 0x6b, 0x6c, 0x65, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x50, 0x61,
+	0x72, 0x61, 0x6d, 0x73, 0x1a, 0x17, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e,
+	0x67, 0x2e, 0x4d, 0x65, 0x72, 0x6b, 0x6c, 0x65, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x22, 0x00, 0x12,
+	0x41, 0x0a, 0x0a, 0x47, 0x65, 0x74, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73, 0x12, 0x1c, 0x2e,
+	0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x47, 0x65, 0x74, 0x48, 0x65,
+	0x61, 0x64, 0x65, 0x72, 0x73, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x1a, 0x13, 0x2e, 0x6e, 0x65,
+	0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73,
+	0x22, 0x00, 0x12, 0x42, 0x0a, 0x09, 0x47, 0x65, 0x74, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x12,
+	0x1b, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x47, 0x65, 0x74,
+	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x1a, 0x16, 0x2e, 0x6e,
+	0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43,
+	0x68, 0x61, 0x69, 0x6e, 0x22, 0x00, 0x12, 0x41, 0x0a, 0x0b, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x1d, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69,
+	0x6e, 0x67, 0x2e, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x50, 0x61,
+	0x72, 0x61, 0x6d, 0x73, 0x1a, 0x11, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e,
+	0x67, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x22, 0x00, 0x12, 0x42, 0x0a, 0x07, 0x50, 0x72, 0x65,
+	0x76, 0x6f, 0x74, 0x65, 0x12, 0x18, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e,
+	0x67, 0x2e, 0x46, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x56, 0x6f, 0x74, 0x65, 0x1a, 0x1b,
+	0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x46, 0x69, 0x6e, 0x61,
+	0x6c, 0x69, 0x74, 0x79, 0x56, 0x6f, 0x74, 0x65, 0x41, 0x63, 0x6b, 0x22, 0x00, 0x12, 0x44, 0x0a,
+	0x09, 0x50, 0x72, 0x65, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x12, 0x18, 0x2e, 0x6e, 0x65, 0x74,
+	0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x46, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x74, 0x79,
+	0x56, 0x6f, 0x74, 0x65, 0x1a, 0x1b, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e,
+	0x67, 0x2e, 0x46, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x56, 0x6f, 0x74, 0x65, 0x41, 0x63,
+	0x6b, 0x22, 0x00, 0x12, 0x55, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73,
+	0x73, 0x45, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x23, 0x2e, 0x6e, 0x65, 0x74, 0x77, 0x6f,
+	0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x47, 0x65, 0x74, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x45, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x1a, 0x19, 0x2e,
+	0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x50, 0x72, 0x6f, 0x76, 0x65,
+	0x6e, 0x45, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x22, 0x00, 0x42, 0x2f, 0x5a, 0x2d, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x53, 0x75, 0x6e, 0x53, 0x69, 0x6e, 0x63,
+	0x65, 0x39, 0x30, 0x2f, 0x67, 0x6f, 0x2d, 0x6e, 0x61, 0x69, 0x76, 0x65, 0x63, 0x6f, 0x69, 0x6e,
+	0x2f, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x69, 0x6e, 0x67, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -337,27 +1194,60 @@ func file_networking_proto_rawDescGZIP() []byte {
 	return file_networking_proto_rawDescData
 }
 
-var file_networking_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_networking_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
 var file_networking_proto_goTypes = []interface{}{
-	(*Block)(nil),                    // 0: networking.Block
-	(*BlockChain)(nil),               // 1: networking.BlockChain
-	(*GetLatestBlockParams)(nil),     // 2: networking.GetLatestBlockParams
-	(*GetFullBlockChainParams)(nil),  // 3: networking.GetFullBlockChainParams
-	(*SubscribeNewBlocksParams)(nil), // 4: networking.SubscribeNewBlocksParams
+	(*BlockHeader)(nil),              // 0: networking.BlockHeader
+	(*Block)(nil),                    // 1: networking.Block
+	(*BlockChain)(nil),               // 2: networking.BlockChain
+	(*HashedHeader)(nil),             // 3: networking.HashedHeader
+	(*Headers)(nil),                  // 4: networking.Headers
+	(*MerkleProof)(nil),              // 5: networking.MerkleProof
+	(*ProvenEntry)(nil),              // 6: networking.ProvenEntry
+	(*ProvenEntries)(nil),            // 7: networking.ProvenEntries
+	(*GetLatestBlockParams)(nil),     // 8: networking.GetLatestBlockParams
+	(*GetFullBlockChainParams)(nil),  // 9: networking.GetFullBlockChainParams
+	(*SubscribeNewBlocksParams)(nil), // 10: networking.SubscribeNewBlocksParams
+	(*GetMerkleProofParams)(nil),     // 11: networking.GetMerkleProofParams
+	(*GetHeadersParams)(nil),         // 12: networking.GetHeadersParams
+	(*GetBlocksParams)(nil),          // 13: networking.GetBlocksParams
+	(*GetAddressEntriesParams)(nil),  // 14: networking.GetAddressEntriesParams
+	(*SubmitEntryParams)(nil),        // 15: networking.SubmitEntryParams
+	(*FinalityVote)(nil),             // 16: networking.FinalityVote
+	(*FinalityVoteAck)(nil),          // 17: networking.FinalityVoteAck
 }
 var file_networking_proto_depIdxs = []int32{
-	0, // 0: networking.BlockChain.blocks:type_name -> networking.Block
-	2, // 1: networking.PeerCommunication.GetLatestBlock:input_type -> networking.GetLatestBlockParams
-	3, // 2: networking.PeerCommunication.GetFullBlockChain:input_type -> networking.GetFullBlockChainParams
-	4, // 3: networking.PeerCommunication.SubscribeNewBlocks:input_type -> networking.SubscribeNewBlocksParams
-	0, // 4: networking.PeerCommunication.GetLatestBlock:output_type -> networking.Block
-	1, // 5: networking.PeerCommunication.GetFullBlockChain:output_type -> networking.BlockChain
-	0, // 6: networking.PeerCommunication.SubscribeNewBlocks:output_type -> networking.Block
-	4, // [4:7] is the sub-list for method output_type
-	1, // [1:4] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: networking.Block.header:type_name -> networking.BlockHeader
+	1,  // 1: networking.BlockChain.blocks:type_name -> networking.Block
+	0,  // 2: networking.HashedHeader.header:type_name -> networking.BlockHeader
+	3,  // 3: networking.Headers.headers:type_name -> networking.HashedHeader
+	0,  // 4: networking.MerkleProof.header:type_name -> networking.BlockHeader
+	5,  // 5: networking.ProvenEntry.proof:type_name -> networking.MerkleProof
+	6,  // 6: networking.ProvenEntries.entries:type_name -> networking.ProvenEntry
+	8,  // 7: networking.PeerCommunication.GetLatestBlock:input_type -> networking.GetLatestBlockParams
+	9,  // 8: networking.PeerCommunication.GetFullBlockChain:input_type -> networking.GetFullBlockChainParams
+	10, // 9: networking.PeerCommunication.SubscribeNewBlocks:input_type -> networking.SubscribeNewBlocksParams
+	11, // 10: networking.PeerCommunication.GetMerkleProof:input_type -> networking.GetMerkleProofParams
+	12, // 11: networking.PeerCommunication.GetHeaders:input_type -> networking.GetHeadersParams
+	13, // 12: networking.PeerCommunication.GetBlocks:input_type -> networking.GetBlocksParams
+	15, // 13: networking.PeerCommunication.SubmitEntry:input_type -> networking.SubmitEntryParams
+	16, // 14: networking.PeerCommunication.Prevote:input_type -> networking.FinalityVote
+	16, // 15: networking.PeerCommunication.Precommit:input_type -> networking.FinalityVote
+	14, // 16: networking.PeerCommunication.GetAddressEntries:input_type -> networking.GetAddressEntriesParams
+	1,  // 17: networking.PeerCommunication.GetLatestBlock:output_type -> networking.Block
+	2,  // 18: networking.PeerCommunication.GetFullBlockChain:output_type -> networking.BlockChain
+	1,  // 19: networking.PeerCommunication.SubscribeNewBlocks:output_type -> networking.Block
+	5,  // 20: networking.PeerCommunication.GetMerkleProof:output_type -> networking.MerkleProof
+	4,  // 21: networking.PeerCommunication.GetHeaders:output_type -> networking.Headers
+	2,  // 22: networking.PeerCommunication.GetBlocks:output_type -> networking.BlockChain
+	1,  // 23: networking.PeerCommunication.SubmitEntry:output_type -> networking.Block
+	17, // 24: networking.PeerCommunication.Prevote:output_type -> networking.FinalityVoteAck
+	17, // 25: networking.PeerCommunication.Precommit:output_type -> networking.FinalityVoteAck
+	7,  // 26: networking.PeerCommunication.GetAddressEntries:output_type -> networking.ProvenEntries
+	17, // [17:27] is the sub-list for method output_type
+	7,  // [7:17] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_networking_proto_init() }
@@ -367,7 +1257,7 @@ func file_networking_proto_init() {
 	}
 	if !protoimpl.UnsafeEnabled {
 		file_networking_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Block); i {
+			switch v := v.(*BlockHeader); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -379,7 +1269,7 @@ func file_networking_proto_init() {
 			}
 		}
 		file_networking_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BlockChain); i {
+			switch v := v.(*Block); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -391,7 +1281,7 @@ func file_networking_proto_init() {
 			}
 		}
 		file_networking_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetLatestBlockParams); i {
+			switch v := v.(*BlockChain); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -403,7 +1293,7 @@ func file_networking_proto_init() {
 			}
 		}
 		file_networking_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetFullBlockChainParams); i {
+			switch v := v.(*HashedHeader); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -415,6 +1305,78 @@ func file_networking_proto_init() {
 			}
 		}
 		file_networking_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Headers); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_networking_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MerkleProof); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_networking_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProvenEntry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_networking_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ProvenEntries); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_networking_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetLatestBlockParams); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_networking_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetFullBlockChainParams); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_networking_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*SubscribeNewBlocksParams); i {
 			case 0:
 				return &v.state
@@ -426,6 +1388,90 @@ func file_networking_proto_init() {
 				return nil
 			}
 		}
+		file_networking_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetMerkleProofParams); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_networking_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetHeadersParams); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_networking_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetBlocksParams); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_networking_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetAddressEntriesParams); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_networking_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubmitEntryParams); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_networking_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FinalityVote); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_networking_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FinalityVoteAck); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -433,7 +1479,7 @@ func file_networking_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_networking_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   18,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
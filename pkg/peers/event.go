@@ -9,13 +9,10 @@ const (
 	EventNewPeer PeerEventType = "NEW_PEER"
 	// EventDeadPeer represents an event about a dead/dying peer.
 	EventDeadPeer PeerEventType = "DEAD_PEER"
-
-	// We don't really need an UPDATED_PEER as in this example project we are
-	// running stateless loads on Kubernetes, thus they never change address
-	// or name: when they die K8s will schedule a new pod with a new name and
-	// new address.
-	// This needs to be corrected in case the project is made to be
-	// stateful.
+	// EventUpdatedPeer represents an event about a known peer that changed
+	// address, i.e. a pod of a StatefulSet that was scheduled again: it
+	// keeps its name, but it gets a new IP and a new UID.
+	EventUpdatedPeer PeerEventType = "UPDATED_PEER"
 )
 
 // PeerEvent is a structure that is delivered to the channel.
@@ -4,25 +4,48 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/metrics"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// catchUpPageSize is the number of blocks requested at once when
+	// catching up with a peer.
+	catchUpPageSize int64 = 500
+	// voteTimeout is how long to wait for a peer to receive a vote.
+	voteTimeout = 10 * time.Second
+)
+
 // PeersManager manages peers and peer events.
 type PeersManager struct {
-	peers      map[string]*Peer
-	blockchain *block.BlockChain
-	lock       sync.Mutex
+	peers           map[string]*Peer
+	banned          map[string]bool
+	blockchain      *block.BlockChain
+	initialSyncDone bool
+	lock            sync.Mutex
+	// queue handles the events and the changes about the same peer in
+	// order.
+	queue *NameQueue
+
+	// ctx and wg are the ones of ListenPeerEvents, so that peers added
+	// manually are unsubscribed along with the other ones.
+	ctx context.Context
+	wg  *sync.WaitGroup
 }
 
 // NewPeersManager creates and returns a new instance of the PeersManager.
 func NewPeersManager(blockchain *block.BlockChain) *PeersManager {
 	return &PeersManager{
 		peers:      map[string]*Peer{},
+		banned:     map[string]bool{},
 		lock:       sync.Mutex{},
+		queue:      NewNameQueue(),
 		blockchain: blockchain,
 	}
 }
@@ -30,12 +53,17 @@ func NewPeersManager(blockchain *block.BlockChain) *PeersManager {
 func (m *PeersManager) addPeer(addCtx context.Context, peer *Peer) error {
 	m.lock.Lock()
 	_, exists := m.peers[peer.Name]
+	banned := m.banned[peer.Name]
 	m.lock.Unlock()
 
 	if exists {
 		return fmt.Errorf("peer already present")
 	}
+	if banned {
+		return fmt.Errorf("peer is banned")
+	}
 
+	peer.setState(PeerStateSyncing)
 	ctx, canc := context.WithTimeout(addCtx, 30*time.Second)
 	peerLastBlock, err := peer.GetLastBlock(ctx)
 	if err != nil {
@@ -43,24 +71,33 @@ func (m *PeersManager) addPeer(addCtx context.Context, peer *Peer) error {
 		return fmt.Errorf("could not last block from peer")
 	}
 	canc()
+	peer.SetHeight(peerLastBlock.GetHeader().GetIndex())
 
 	myLastBlock := m.blockchain.GetLastBlock()
 
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	switch diff := myLastBlock.Index - peerLastBlock.Index; {
+	switch diff := myLastBlock.Header.Index - peerLastBlock.GetHeader().GetIndex(); {
 	case diff == 0:
 		// we have the same index
 		if !bytes.Equal(myLastBlock.Hash, peerLastBlock.Hash) {
 			return fmt.Errorf("peer's last block hash is invalid")
 		}
 
-		if !bytes.Equal(myLastBlock.PreviousBlockHash, peerLastBlock.PreviousBlockHash) {
+		if !bytes.Equal(myLastBlock.Header.PreviousBlockHash, peerLastBlock.GetHeader().GetPreviousBlockHash()) {
 			return fmt.Errorf("peer's previous block hash is invalid")
 		}
 	case diff < 0:
-		// the peer's last block is higher than mine
+		// the peer's last block is higher than mine: try to only get the
+		// blocks that I miss first, and the whole chain if they don't follow
+		// mine.
+		err := m.catchUp(addCtx, peer, peerLastBlock.GetHeader().GetIndex())
+		if err == nil {
+			break
+		}
+		log.Info().Err(err).Str("peer-name", peer.Name).Msg("could not catch up with peer, getting its full blockchain")
+
 		ctx, canc := context.WithTimeout(addCtx, 30*time.Second)
 		peerBlockChain, err := peer.GetFullBlockChain(ctx)
 		if err != nil {
@@ -76,35 +113,310 @@ func (m *PeersManager) addPeer(addCtx context.Context, peer *Peer) error {
 		// the peer's last block is lower than mine. I don't need to sync.
 	}
 
+	if _, exists := m.peers[peer.Name]; exists {
+		// another event for the same name was handled while we were
+		// syncing.
+		return fmt.Errorf("peer already present")
+	}
+
 	m.peers[peer.Name] = peer
+	m.initialSyncDone = true
+	metrics.PeersConnected.Set(float64(len(m.peers)))
 
 	log.Info().Str("peer-name", peer.Name).Msg("added peer")
 
 	return nil
 }
 
-func (m *PeersManager) removePeer(name string) (*Peer, error) {
-	peer, exists := func() (*Peer, bool) {
-		m.lock.Lock()
-		defer m.lock.Unlock()
+// catchUp pushes the blocks that the peer has after the last one of my chain,
+// until the provided height is reached. This is much faster than getting the
+// full blockchain when the chains are few blocks apart, e.g. after
+// bootstrapping from a file.
+func (m *PeersManager) catchUp(addCtx context.Context, peer *Peer, peerHeight int64) error {
+	for {
+		myHeight := m.blockchain.GetLastBlock().Header.Index
+		if myHeight >= peerHeight {
+			return nil
+		}
+
+		ctx, canc := context.WithTimeout(addCtx, 30*time.Second)
+		blocks, err := peer.GetBlocks(ctx, myHeight+1, catchUpPageSize)
+		canc()
+		if err != nil {
+			return fmt.Errorf("could not get blocks from peer: %w", err)
+		}
+		if len(blocks) == 0 {
+			return fmt.Errorf("peer has no blocks after height %d", myHeight)
+		}
 
-		peer, exists := m.peers[name]
-		if !exists {
-			return nil, false
+		for _, b := range blocks {
+			if err := m.blockchain.PushBlock(b); err != nil {
+				return fmt.Errorf("could not push block %d: %w", b.GetHeader().GetIndex(), err)
+			}
 		}
+	}
+}
 
-		delete(m.peers, name)
-		return peer, true
-	}()
+// CatchUp downloads the blocks that we miss from the peer with the highest
+// chain, e.g. the ones mined by the previous leader right before a node
+// starts mining.
+func (m *PeersManager) CatchUp(ctx context.Context) error {
+	m.lock.Lock()
+	peers := make([]*Peer, 0, len(m.peers))
+	for _, peer := range m.peers {
+		peers = append(peers, peer)
+	}
+	m.lock.Unlock()
+
+	var best *Peer
+	for _, peer := range peers {
+		reqCtx, canc := context.WithTimeout(ctx, 10*time.Second)
+		peerLastBlock, err := peer.GetLastBlock(reqCtx)
+		canc()
+		if err != nil {
+			log.Info().Err(err).Str("peer-name", peer.Name).Msg("could not get last block from peer")
+			continue
+		}
+
+		peer.SetHeight(peerLastBlock.GetHeader().GetIndex())
+		if best == nil || peer.Height() > best.Height() {
+			best = peer
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	return m.catchUp(ctx, best, best.Height())
+}
+
+// removePeer removes the peer from the known ones, unless the one we know is
+// a different incarnation of it, i.e. a pod that got the same name of a dead
+// one and whose events were handled first.
+func (m *PeersManager) removePeer(peer *Peer) (*Peer, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	foundPeer, exists := m.peers[peer.Name]
 	if !exists {
 		return nil, fmt.Errorf("peer was not found")
 	}
+	if !foundPeer.SameAs(peer) {
+		return nil, fmt.Errorf("peer was replaced by a new one")
+	}
+
+	delete(m.peers, peer.Name)
+	metrics.PeersConnected.Set(float64(len(m.peers)))
+	return foundPeer, nil
+}
+
+// connectPeer adds the peer and subscribes to the blocks it generates in a
+// separate goroutine. It must be called by work that is itself in the wait
+// group, so that the subscription is added before the group is waited for.
+func (m *PeersManager) connectPeer(ctx context.Context, wg *sync.WaitGroup, peer *Peer) error {
+	if err := m.addPeer(ctx, peer); err != nil {
+		return err
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		peer.SubscribeBlockGeneration(ctx, m.blockchain)
+	}()
+
+	return nil
+}
+
+// inOrder runs the function after the events about the peer with the
+// provided name that are still being handled, and returns its error.
+func (m *PeersManager) inOrder(name string, fn func() error) error {
+	errChan := make(chan error, 1)
+	m.queue.Push(name, func() {
+		errChan <- fn()
+	})
+
+	return <-errChan
+}
+
+// disconnectPeer removes the peer and unsubscribes from its blocks.
+func (m *PeersManager) disconnectPeer(peer *Peer) error {
+	foundPeer, err := m.removePeer(peer)
+	if err != nil {
+		return err
+	}
+
+	if foundPeer.CancelContext != nil {
+		foundPeer.CancelContext()
+	}
+
+	return nil
+}
+
+// updatePeer replaces a known peer that changed address: it unsubscribes from
+// the old address, dials the new one and keeps the score and the height of
+// the peer. The peer is added as a new one if it is not known.
+func (m *PeersManager) updatePeer(ctx context.Context, wg *sync.WaitGroup, peer *Peer) error {
+	m.lock.Lock()
+	oldPeer, exists := m.peers[peer.Name]
+	if exists {
+		if oldPeer.Address() == peer.Address() && oldPeer.UID == peer.UID {
+			m.lock.Unlock()
+			return nil
+		}
+
+		delete(m.peers, peer.Name)
+	}
+	m.lock.Unlock()
+
+	if exists {
+		if oldPeer.CancelContext != nil {
+			oldPeer.CancelContext()
+		}
+		peer.Inherit(oldPeer)
+		log.Info().Str("peer-name", peer.Name).Str("old-address", oldPeer.Address()).Str("new-address", peer.Address()).Msg("peer changed address")
+	}
+
+	// This syncs again with the peer, in case it has a different chain at
+	// the new address, but our chain is already synced so only the missing
+	// blocks are downloaded.
+	return m.connectPeer(ctx, wg, peer)
+}
+
+// AddPeer syncs the chain with the provided peer and subscribes to the blocks
+// it generates, as if it was discovered. It can only be used while
+// ListenPeerEvents is running.
+func (m *PeersManager) AddPeer(peer *Peer) error {
+	// The context is checked and the work is added to the wait group under
+	// the lock, so that ListenPeerEvents can't start waiting in between.
+	m.lock.Lock()
+	ctx, wg := m.ctx, m.wg
+	if ctx == nil || ctx.Err() != nil {
+		m.lock.Unlock()
+		return fmt.Errorf("peers manager is not listening for peers")
+	}
+	wg.Add(1)
+	m.lock.Unlock()
+
+	return m.inOrder(peer.Name, func() error {
+		defer wg.Done()
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		return m.connectPeer(ctx, wg, peer)
+	})
+}
+
+// RemovePeer removes the peer with the provided name and unsubscribes from
+// the blocks it generates.
+func (m *PeersManager) RemovePeer(name string) error {
+	return m.inOrder(name, func() error {
+		return m.disconnectPeer(&Peer{Name: name})
+	})
+}
+
+// BanPeer removes the peer with the provided name, if present, and prevents
+// it from being added again until UnbanPeer is called.
+func (m *PeersManager) BanPeer(name string) {
+	m.lock.Lock()
+	m.banned[name] = true
+	m.lock.Unlock()
+
+	err := m.inOrder(name, func() error {
+		return m.disconnectPeer(&Peer{Name: name})
+	})
+	if err == nil {
+		log.Info().Str("peer-name", name).Msg("removed banned peer")
+	}
+}
+
+// UnbanPeer allows the peer with the provided name to be added again.
+func (m *PeersManager) UnbanPeer(name string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.banned, name)
+}
+
+// GetBannedPeers returns the names of the banned peers.
+func (m *PeersManager) GetBannedPeers() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	names := make([]string, 0, len(m.banned))
+	for name := range m.banned {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// GetPeers returns information about all the peers, sorted by name.
+func (m *PeersManager) GetPeers() []*PeerInfo {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	infos := make([]*PeerInfo, 0, len(m.peers))
+	for _, peer := range m.peers {
+		infos = append(infos, peer.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	return infos
+}
+
+// ResyncFromPeer downloads the full chain from the peer with the provided
+// name and replaces ours with it, if valid and with more work.
+func (m *PeersManager) ResyncFromPeer(ctx context.Context, name string) error {
+	m.lock.Lock()
+	peer, exists := m.peers[name]
+	m.lock.Unlock()
+
+	if !exists {
+		return fmt.Errorf("peer was not found")
+	}
+
+	peerBlockChain, err := peer.GetFullBlockChain(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get full blockchain from peer: %w", err)
+	}
 
-	return peer, nil
+	if len(peerBlockChain) > 0 {
+		peer.SetHeight(peerBlockChain[len(peerBlockChain)-1].GetHeader().GetIndex())
+	}
+
+	return m.blockchain.ReplaceWith(peerBlockChain)
+}
+
+// GetSyncStatus returns how far the node is in syncing the chain with its
+// peers.
+func (m *PeersManager) GetSyncStatus() *SyncStatus {
+	height := m.blockchain.GetLastBlock().Header.Index
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	status := &SyncStatus{
+		InitialSyncDone: m.initialSyncDone,
+		Height:          height,
+		Peers:           len(m.peers),
+	}
+	for _, peer := range m.peers {
+		if peerHeight := peer.Height(); peerHeight > status.BestPeerHeight {
+			status.BestPeerHeight = peerHeight
+		}
+	}
+
+	return status
 }
 
-// ListenPeerEvents listens on the provided channel for peer events, e.g. new
-// or deleted peers.
+// ListenPeerEvents listens on the provided channel for peer events, e.g. new,
+// updated or deleted peers.
 func (m *PeersManager) ListenPeerEvents(peerEvents chan *PeerEvent) {
 	// This context will be passed to each addPeer and used as a main
 	// context: when the user wants to stop the program they will also close
@@ -112,39 +424,91 @@ func (m *PeersManager) ListenPeerEvents(peerEvents chan *PeerEvent) {
 	ctx, canc := context.WithCancel(context.Background())
 	wg := sync.WaitGroup{}
 
+	m.lock.Lock()
+	m.ctx, m.wg = ctx, &wg
+	m.lock.Unlock()
+
 	for ev := range peerEvents {
 		switch ev.EventType {
 
-		case EventNewPeer:
-			go func(peer *Peer) {
-				if err := m.addPeer(ctx, peer); err != nil {
-					log.Err(err).Str("peer-name", peer.Name).Msg("could not add peer")
+		// events about the same peer are handled in the order they are
+		// received, so that a newer incarnation of the peer always
+		// replaces an older one.
+		case EventNewPeer, EventUpdatedPeer:
+			// A new peer may have the name of one we still know, if the
+			// event about the death of the previous one has not been
+			// handled yet: it is then updated rather than rejected.
+			peer := ev.Peer
+			wg.Add(1)
+			m.queue.Push(peer.Name, func() {
+				defer wg.Done()
+
+				if ctx.Err() != nil {
 					return
 				}
-
-				wg.Add(1)
-				peer.SubscribeBlockGeneration(ctx, m.blockchain)
-				wg.Done()
-			}(ev.Peer)
+				if err := m.updatePeer(ctx, &wg, peer); err != nil {
+					log.Err(err).Str("peer-name", peer.Name).Msg("could not add peer")
+				}
+			})
 
 		case EventDeadPeer:
-			go func(peer *Peer) {
-				foundPeer, err := m.removePeer(peer.Name)
-				if err != nil {
-					log.Err(err).Str("peer-name", peer.Name).Msg("could not remove peer")
+			peer := ev.Peer
+			wg.Add(1)
+			m.queue.Push(peer.Name, func() {
+				defer wg.Done()
+
+				if ctx.Err() != nil {
 					return
 				}
-
-				foundPeer.CancelContext()
-			}(ev.Peer)
+				if err := m.disconnectPeer(peer); err != nil {
+					log.Err(err).Str("peer-name", peer.Name).Msg("could not remove peer")
+				}
+			})
 		}
 	}
 
 	// each goroutine subscribed to block generation events from other peers
 	// receives a new context derived from the one we created above:
 	// by cancelling the "main" one above, we cancel the other ones too.
+	// The events still queued are skipped, but they are waited for along
+	// with the subscriptions, as they were added to the wait group before
+	// being scheduled.
 	log.Info().Msg("unsubscribing from all peers...")
+	m.lock.Lock()
 	canc()
+	m.lock.Unlock()
 	wg.Wait()
 	log.Info().Msg("all unsubscriptions done")
 }
+
+// BroadcastPrevote sends the prevote to all peers, without waiting for them
+// to receive it.
+func (m *PeersManager) BroadcastPrevote(vote *pb.FinalityVote) {
+	m.broadcastVote(vote, (*Peer).SendPrevote)
+}
+
+// BroadcastPrecommit sends the precommit to all peers, without waiting for
+// them to receive it.
+func (m *PeersManager) BroadcastPrecommit(vote *pb.FinalityVote) {
+	m.broadcastVote(vote, (*Peer).SendPrecommit)
+}
+
+func (m *PeersManager) broadcastVote(vote *pb.FinalityVote, send func(*Peer, context.Context, *pb.FinalityVote) error) {
+	m.lock.Lock()
+	peers := make([]*Peer, 0, len(m.peers))
+	for _, peer := range m.peers {
+		peers = append(peers, peer)
+	}
+	m.lock.Unlock()
+
+	for _, peer := range peers {
+		go func(peer *Peer) {
+			ctx, canc := context.WithTimeout(context.Background(), voteTimeout)
+			defer canc()
+
+			if err := send(peer, ctx, vote); err != nil {
+				log.Debug().Err(err).Str("peer-name", peer.Name).Int64("height", vote.Height).Msg("could not send vote to peer")
+			}
+		}(peer)
+	}
+}
@@ -0,0 +1,61 @@
+package peers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"google.golang.org/grpc"
+)
+
+func TestListenPeerEventsStop(t *testing.T) {
+	m := NewPeersManager(block.NewBlockFactory().NewBlockChain())
+	var dials int32
+	peer := &Peer{
+		Name: "peer",
+		DialOptions: []grpc.DialOption{
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+				atomic.AddInt32(&dials, 1)
+				return nil, errors.New("unreachable")
+			}),
+		},
+	}
+
+	peerEvents := make(chan *PeerEvent)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		m.ListenPeerEvents(peerEvents)
+	}()
+
+	// the event is queued behind this function, so it is only handled after
+	// the manager is told to stop.
+	release := make(chan struct{})
+	m.queue.Push(peer.Name, func() { <-release })
+	peerEvents <- &PeerEvent{EventType: EventNewPeer, Peer: peer}
+	close(peerEvents)
+
+	select {
+	case <-stopped:
+		t.Fatal("stopped before handling the queued event")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not stop after handling the queued event")
+	}
+
+	if n := atomic.LoadInt32(&dials); n != 0 {
+		t.Fatalf("peer was dialed %d times after stopping", n)
+	}
+	if err := m.AddPeer(peer); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+}
@@ -2,30 +2,172 @@ package peers
 
 import (
 	"context"
-	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/metrics"
 	"github.com/SunSince90/go-naivecoin/pkg/pb"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
 )
 
+// BlockPusher is implemented by anything that can store the blocks received
+// from peers, e.g. a full blockchain or a light client.
+type BlockPusher interface {
+	PushBlock(block *pb.Block) error
+}
+
+// PeerState represents the state of the connection to a peer.
+type PeerState string
+
+const (
+	// DefaultPort is the port where peers serve peer communications, unless
+	// another one is discovered.
+	DefaultPort int32 = 8082
+)
+
+const (
+	// PeerStateSyncing means that we are syncing the chain with the peer.
+	PeerStateSyncing PeerState = "SYNCING"
+	// PeerStateSubscribed means that we are receiving the blocks generated
+	// by the peer.
+	PeerStateSubscribed PeerState = "SUBSCRIBED"
+	// PeerStateDisconnected means that we are not receiving blocks from the
+	// peer anymore.
+	PeerStateDisconnected PeerState = "DISCONNECTED"
+)
+
+// PeerInfo contains information about a peer and its connection.
+type PeerInfo struct {
+	Name      string    `json:"name"`
+	IP        string    `json:"ip"`
+	State     PeerState `json:"state"`
+	Height    int64     `json:"height"`
+	LatencyMs float64   `json:"latencyMs"`
+	Score     int64     `json:"score"`
+}
+
 // Peer is a representation of other nodes.
 type Peer struct {
 	// Name of the peer.
 	Name string
 	// IP of the peer
 	IP string
+	// Port where the peer serves peer communications. DefaultPort is used
+	// if it is 0.
+	Port int32
+	// UID of the pod running the peer. Pods of a StatefulSet keep their
+	// name when they are scheduled again, so this tells different
+	// incarnations of the same peer apart. It may be empty, e.g. for peers
+	// added manually.
+	UID string
+	// DialOptions are added to the options used to connect to the peer,
+	// e.g. to reach peers running in the same process through in-memory
+	// listeners.
+	DialOptions []grpc.DialOption
 
 	// Address
 	sub pb.PeerCommunication_SubscribeNewBlocksClient
 	// TODO: check this
 	CancelContext context.CancelFunc
+	// height is the index of the last block we know the peer has.
+	// It must be accessed atomically.
+	height int64
+	// score increases for each valid block received from the peer and
+	// decreases for each invalid one. It must be accessed atomically.
+	score int64
+
+	state     PeerState
+	latency   time.Duration
+	stateLock sync.Mutex
+}
+
+// Info returns information about the peer and its connection.
+func (p *Peer) Info() *PeerInfo {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	return &PeerInfo{
+		Name:      p.Name,
+		IP:        p.IP,
+		State:     p.state,
+		Height:    p.Height(),
+		LatencyMs: float64(p.latency) / float64(time.Millisecond),
+		Score:     atomic.LoadInt64(&p.score),
+	}
+}
+
+func (p *Peer) setState(state PeerState) {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	p.state = state
+}
+
+func (p *Peer) setLatency(latency time.Duration) {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	p.latency = latency
+}
+
+// Address returns the address where the peer serves peer communications.
+func (p *Peer) Address() string {
+	port := p.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+
+	return net.JoinHostPort(p.IP, strconv.Itoa(int(port)))
+}
+
+func (p *Peer) dial(ctx context.Context) (*grpc.ClientConn, error) {
+	opts := []grpc.DialOption{grpc.WithInsecure(), grpc.WithBlock()}
+	opts = append(opts, p.DialOptions...)
+
+	return grpc.DialContext(ctx, p.Address(), opts...)
+}
+
+// SameAs returns true if other is the same incarnation of the peer, i.e. it
+// has the same name and, if both UIDs are known, the same UID.
+func (p *Peer) SameAs(other *Peer) bool {
+	if p.Name != other.Name {
+		return false
+	}
+
+	return p.UID == "" || other.UID == "" || p.UID == other.UID
+}
+
+// Inherit copies the score and the height of old, which is a previous
+// incarnation of the peer, so that they are not lost when the peer changes
+// address.
+func (p *Peer) Inherit(old *Peer) {
+	atomic.StoreInt64(&p.score, atomic.LoadInt64(&old.score))
+	p.SetHeight(old.Height())
+}
+
+// Height returns the index of the last block we know the peer has.
+func (p *Peer) Height() int64 {
+	return atomic.LoadInt64(&p.height)
+}
+
+// SetHeight updates the index of the last block we know the peer has, if
+// higher than the current one.
+func (p *Peer) SetHeight(height int64) {
+	for {
+		current := atomic.LoadInt64(&p.height)
+		if height <= current || atomic.CompareAndSwapInt64(&p.height, current, height) {
+			return
+		}
+	}
 }
 
 // GetLastBlock returns the last block that the peer has stored.
 func (p *Peer) GetLastBlock(ctx context.Context) (*pb.Block, error) {
-	conn, err := grpc.Dial(fmt.Sprintf("%s:%d", p.IP, 8082), grpc.WithInsecure(), grpc.WithBlock())
+	conn, err := p.dial(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -33,12 +175,19 @@ func (p *Peer) GetLastBlock(ctx context.Context) (*pb.Block, error) {
 
 	cli := pb.NewPeerCommunicationClient(conn)
 
-	return cli.GetLatestBlock(ctx, &pb.GetLatestBlockParams{})
+	start := time.Now()
+	block, err := cli.GetLatestBlock(ctx, &pb.GetLatestBlockParams{})
+	if err != nil {
+		return nil, err
+	}
+	p.setLatency(time.Since(start))
+
+	return block, nil
 }
 
 // GetFullBlockChain returns the full chain from the peer.
 func (p *Peer) GetFullBlockChain(ctx context.Context) ([]*pb.Block, error) {
-	conn, err := grpc.Dial(fmt.Sprintf("%s:%d", p.IP, 8082), grpc.WithInsecure(), grpc.WithBlock())
+	conn, err := p.dial(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -54,17 +203,140 @@ func (p *Peer) GetFullBlockChain(ctx context.Context) ([]*pb.Block, error) {
 	return bc.Blocks, nil
 }
 
+// GetBlocks returns at most limit blocks from the peer, starting from the
+// block with the provided index.
+func (p *Peer) GetBlocks(ctx context.Context, fromIndex, limit int64) ([]*pb.Block, error) {
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	cli := pb.NewPeerCommunicationClient(conn)
+
+	bc, err := cli.GetBlocks(ctx, &pb.GetBlocksParams{
+		FromIndex: fromIndex,
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bc.Blocks, nil
+}
+
+// GetHeaders returns at most limit headers from the peer, starting from the
+// block with the provided index.
+func (p *Peer) GetHeaders(ctx context.Context, fromIndex, limit int64) ([]*pb.HashedHeader, error) {
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	cli := pb.NewPeerCommunicationClient(conn)
+
+	headers, err := cli.GetHeaders(ctx, &pb.GetHeadersParams{
+		FromIndex: fromIndex,
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return headers.Headers, nil
+}
+
+// GetMerkleProof returns the proof that the entry with the provided hash is
+// included in the peer's chain.
+func (p *Peer) GetMerkleProof(ctx context.Context, entryHash []byte) (*pb.MerkleProof, error) {
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	cli := pb.NewPeerCommunicationClient(conn)
+
+	return cli.GetMerkleProof(ctx, &pb.GetMerkleProofParams{EntryHash: entryHash})
+}
+
+// GetAddressEntries returns the entries of the peer's chain that move an
+// amount to or from the provided address, along with the proofs of their
+// inclusion.
+func (p *Peer) GetAddressEntries(ctx context.Context, address string) ([]*pb.ProvenEntry, error) {
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	cli := pb.NewPeerCommunicationClient(conn)
+
+	entries, err := cli.GetAddressEntries(ctx, &pb.GetAddressEntriesParams{Address: address})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries.Entries, nil
+}
+
+// SubmitEntry asks the peer to mine a block with the provided entry and
+// returns the mined block. This is used to forward entries to the node that
+// mines them.
+func (p *Peer) SubmitEntry(ctx context.Context, entry string) (*pb.Block, error) {
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	cli := pb.NewPeerCommunicationClient(conn)
+
+	return cli.SubmitEntry(ctx, &pb.SubmitEntryParams{Entry: entry})
+}
+
+// SendPrevote sends the prevote of a validator of the finality gadget to the
+// peer.
+func (p *Peer) SendPrevote(ctx context.Context, vote *pb.FinalityVote) error {
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	cli := pb.NewPeerCommunicationClient(conn)
+
+	_, err = cli.Prevote(ctx, vote)
+	return err
+}
+
+// SendPrecommit sends the precommit of a validator of the finality gadget to
+// the peer.
+func (p *Peer) SendPrecommit(ctx context.Context, vote *pb.FinalityVote) error {
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	cli := pb.NewPeerCommunicationClient(conn)
+
+	_, err = cli.Precommit(ctx, vote)
+	return err
+}
+
 // SubscribeBlockGeneration runs a uni-direction stream connection to the peer
 // to get blocks generated by the peer.
 //
 // This needs to run in a separate goroutine.
-func (p *Peer) SubscribeBlockGeneration(ctx context.Context, blockchain *block.BlockChain) error {
+func (p *Peer) SubscribeBlockGeneration(ctx context.Context, blockchain BlockPusher) error {
 	l := log.With().
 		Str("peer-name", p.Name).
 		Str("peer-ip", p.IP).
 		Logger()
 
-	conn, err := grpc.Dial(fmt.Sprintf("%s:%d", p.IP, 8082), grpc.WithInsecure(), grpc.WithBlock())
+	conn, err := p.dial(ctx)
 	if err != nil {
 		return err
 	}
@@ -82,6 +354,8 @@ func (p *Peer) SubscribeBlockGeneration(ctx context.Context, blockchain *block.B
 	// store the cancel function, that we can later use it to unsubscribe
 	// from events
 	p.CancelContext = canc
+	p.setState(PeerStateSubscribed)
+	defer p.setState(PeerStateDisconnected)
 
 	l.Info().Msg("listening for block generation events from peer...")
 	p.sub = sub
@@ -96,10 +370,14 @@ func (p *Peer) SubscribeBlockGeneration(ctx context.Context, blockchain *block.B
 			return err
 		}
 
-		l.Info().Int64("index", block.Index).Str("data", block.Data).Msg("got block from peer")
+		metrics.BlocksReceived.Inc()
+		p.SetHeight(block.GetHeader().GetIndex())
+		l.Info().Int64("index", block.GetHeader().GetIndex()).Int("entries", len(block.Entries)).Msg("got block from peer")
 		if err := blockchain.PushBlock(block); err != nil {
+			atomic.AddInt64(&p.score, -1)
 			l.Err(err).Msg("error while adding block to blockchain")
 		} else {
+			atomic.AddInt64(&p.score, 1)
 			l.Info().Msg("added block generated by peer")
 		}
 	}
@@ -0,0 +1,55 @@
+package peers
+
+import "sync"
+
+// NameQueue runs the functions pushed for the same peer name one after the
+// other, in the order they were pushed, and the ones for different names
+// concurrently.
+//
+// Events about the same peer must be handled in order: otherwise an event
+// about a newer incarnation of the peer may be handled before the one about
+// the older one, which would then replace it.
+type NameQueue struct {
+	// pending maps each name whose functions are running to the ones that
+	// are waiting for them.
+	pending map[string][]func()
+	lock    sync.Mutex
+}
+
+// NewNameQueue creates and returns a new instance of the NameQueue.
+func NewNameQueue() *NameQueue {
+	return &NameQueue{
+		pending: map[string][]func(){},
+		lock:    sync.Mutex{},
+	}
+}
+
+// Push runs the function after the ones already pushed for the same name,
+// without waiting for it to finish.
+func (q *NameQueue) Push(name string, fn func()) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if pending, running := q.pending[name]; running {
+		q.pending[name] = append(pending, fn)
+		return
+	}
+
+	q.pending[name] = []func(){}
+	go q.run(name, fn)
+}
+
+func (q *NameQueue) run(name string, fn func()) {
+	for fn != nil {
+		fn()
+
+		q.lock.Lock()
+		if pending := q.pending[name]; len(pending) > 0 {
+			fn, q.pending[name] = pending[0], pending[1:]
+		} else {
+			fn = nil
+			delete(q.pending, name)
+		}
+		q.lock.Unlock()
+	}
+}
@@ -0,0 +1,69 @@
+package peers
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNameQueueOrder(t *testing.T) {
+	queue := NewNameQueue()
+	lock := sync.Mutex{}
+	handled := []int{}
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 100; i++ {
+		i := i
+		wg.Add(1)
+		queue.Push("peer", func() {
+			defer wg.Done()
+
+			// the first events take longer, so that they would be
+			// overtaken if they were not in order.
+			time.Sleep(time.Duration(100-i) * 10 * time.Microsecond)
+			lock.Lock()
+			handled = append(handled, i)
+			lock.Unlock()
+		})
+	}
+	wg.Wait()
+
+	for i, got := range handled {
+		if got != i {
+			t.Fatalf("event %d was handled as number %d", got, i)
+		}
+	}
+
+	// the name is forgotten right after its last event is handled.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		queue.lock.Lock()
+		pending := len(queue.pending)
+		queue.lock.Unlock()
+
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queue still has %d names after handling all events", pending)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNameQueueConcurrency(t *testing.T) {
+	queue := NewNameQueue()
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	// a slow event for a peer must not hold back the ones of other peers.
+	queue.Push("slow", func() { <-release })
+	queue.Push("fast", func() { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event of a peer waited for the one of another peer")
+	}
+	close(release)
+}
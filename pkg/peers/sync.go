@@ -0,0 +1,13 @@
+package peers
+
+// SyncStatus describes how far a node is in syncing the chain with its peers.
+type SyncStatus struct {
+	// InitialSyncDone is true once the node synced from at least one peer.
+	InitialSyncDone bool `json:"initialSyncDone"`
+	// Height is the index of the last block of the node.
+	Height int64 `json:"height"`
+	// BestPeerHeight is the highest index known among the peers.
+	BestPeerHeight int64 `json:"bestPeerHeight"`
+	// Peers is the number of peers currently added.
+	Peers int `json:"peers"`
+}
@@ -0,0 +1,180 @@
+package servers
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+// AdminServer lets operators inspect and manage the peers of the node at
+// runtime.
+//
+// All requests must be authenticated with the token provided to
+// NewAdminServer as a bearer token.
+type AdminServer struct {
+	FiberApp    *fiber.App
+	token       []byte
+	peerManager *peers.PeersManager
+	commServer  *PeerCommunicationServer
+	authority   *block.ProofOfAuthority
+}
+
+// PeersList contains the peers of the node and the banned ones.
+type PeersList struct {
+	Peers  []*peers.PeerInfo `json:"peers"`
+	Banned []string          `json:"banned"`
+}
+
+type voteRequest struct {
+	Candidate string `json:"candidate"`
+	Authorize bool   `json:"authorize"`
+}
+
+type addPeerRequest struct {
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+}
+
+// NewAdminServer returns a server for managing the peers of the node.
+// This server needs to run on a different port from the other ones, as it
+// should not be reachable from outside the cluster.
+//
+// If the node uses proof of authority, the server also manages the votes
+// that it puts in the blocks it seals.
+func NewAdminServer(token string, peerManager *peers.PeersManager, commServer *PeerCommunicationServer, authority *block.ProofOfAuthority) *AdminServer {
+	server := &AdminServer{
+		FiberApp:    fiber.New(fiber.Config{ReadTimeout: 30 * time.Second}),
+		token:       []byte(token),
+		peerManager: peerManager,
+		commServer:  commServer,
+		authority:   authority,
+	}
+
+	app := server.FiberApp
+	app.Use(server.authenticate)
+	app.Get("/peers", server.handleGetPeers)
+	app.Post("/peers", server.handleAddPeer)
+	app.Delete("/peers/:name", server.handleRemovePeer)
+	app.Post("/peers/:name/ban", server.handleBanPeer)
+	app.Delete("/peers/:name/ban", server.handleUnbanPeer)
+	app.Post("/peers/:name/resync", server.handleResync)
+	app.Get("/subscribers", server.handleGetSubscribers)
+	if authority != nil {
+		app.Get("/votes", server.handleGetVotes)
+		app.Post("/votes", server.handleVote)
+		app.Delete("/votes/:candidate", server.handleDiscardVote)
+	}
+	return server
+}
+
+func (a *AdminServer) authenticate(c *fiber.Ctx) error {
+	token := strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), a.token) != 1 {
+		log.Warn().Str("ip", c.IP()).Str("path", c.Path()).Msg("unauthorized admin request")
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	return c.Next()
+}
+
+func (a *AdminServer) handleGetPeers(c *fiber.Ctx) error {
+	return c.JSON(&PeersList{
+		Peers:  a.peerManager.GetPeers(),
+		Banned: a.peerManager.GetBannedPeers(),
+	})
+}
+
+func (a *AdminServer) handleAddPeer(c *fiber.Ctx) error {
+	var req addPeerRequest
+	if err := c.BodyParser(&req); err != nil || req.Name == "" || req.IP == "" {
+		c.Send([]byte("name and ip are required"))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	if err := a.peerManager.AddPeer(&peers.Peer{Name: req.Name, IP: req.IP}); err != nil {
+		c.Send([]byte(err.Error()))
+		return c.SendStatus(fiber.StatusConflict)
+	}
+
+	log.Info().Str("peer-name", req.Name).Str("peer-ip", req.IP).Msg("peer added by admin")
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+func (a *AdminServer) handleRemovePeer(c *fiber.Ctx) error {
+	name := c.Params("name")
+	if err := a.peerManager.RemovePeer(name); err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	log.Info().Str("peer-name", name).Msg("peer removed by admin")
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (a *AdminServer) handleBanPeer(c *fiber.Ctx) error {
+	name := c.Params("name")
+	a.peerManager.BanPeer(name)
+
+	log.Info().Str("peer-name", name).Msg("peer banned by admin")
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (a *AdminServer) handleUnbanPeer(c *fiber.Ctx) error {
+	name := c.Params("name")
+	a.peerManager.UnbanPeer(name)
+
+	log.Info().Str("peer-name", name).Msg("peer unbanned by admin")
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (a *AdminServer) handleResync(c *fiber.Ctx) error {
+	name := c.Params("name")
+
+	ctx, canc := context.WithTimeout(context.Background(), 30*time.Second)
+	defer canc()
+
+	if err := a.peerManager.ResyncFromPeer(ctx, name); err != nil {
+		c.Send([]byte(err.Error()))
+		return c.SendStatus(fiber.StatusConflict)
+	}
+
+	log.Info().Str("peer-name", name).Msg("resynced from peer by admin")
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (a *AdminServer) handleGetSubscribers(c *fiber.Ctx) error {
+	return c.JSON(a.commServer.GetSubscriberStats())
+}
+
+func (a *AdminServer) handleGetVotes(c *fiber.Ctx) error {
+	return c.JSON(a.authority.Proposals())
+}
+
+func (a *AdminServer) handleVote(c *fiber.Ctx) error {
+	var req voteRequest
+	if err := c.BodyParser(&req); err != nil || req.Candidate == "" {
+		c.Send([]byte("candidate is required"))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	if err := a.authority.Propose(req.Candidate, req.Authorize); err != nil {
+		c.Send([]byte(err.Error()))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	log.Info().Str("candidate", req.Candidate).Bool("authorize", req.Authorize).Msg("validator vote added by admin")
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (a *AdminServer) handleDiscardVote(c *fiber.Ctx) error {
+	candidate := c.Params("candidate")
+	a.authority.Discard(candidate)
+
+	log.Info().Str("candidate", candidate).Msg("validator vote discarded by admin")
+	return c.SendStatus(fiber.StatusNoContent)
+}
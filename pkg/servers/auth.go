@@ -0,0 +1,252 @@
+package servers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// clientLocal is the key of the fiber local containing the name of the
+	// client that made the request.
+	clientLocal string = "client"
+	// anonymousClient is the name given to clients when authentication is
+	// disabled.
+	anonymousClient string = "anonymous"
+)
+
+// AuthSettings contains the credentials accepted on write endpoints.
+// If no credentials are set, authentication is disabled.
+type AuthSettings struct {
+	// APIKeys maps the name of each client to its API key.
+	APIKeys map[string]string `yaml:"apiKeys"`
+	// JWTSecret is the secret used to verify JWTs signed with HS256. The
+	// subject of the token is used as the name of the client.
+	JWTSecret string `yaml:"jwtSecret"`
+}
+
+// WriteSettings defines how the endpoints that modify the chain are
+// protected.
+type WriteSettings struct {
+	// Auth contains the accepted credentials.
+	Auth AuthSettings
+	// RateLimit is the maximum number of requests a client can make in
+	// RateLimitWindow. Zero disables rate limiting.
+	RateLimit int
+	// RateLimitWindow is the window in which requests are counted.
+	RateLimitWindow time.Duration
+	// MaxPayloadSize is the maximum size in bytes of the body of requests
+	// to write endpoints, i.e. the POST ones, and of messages sent to the
+	// RPC WebSocket. Larger bodies are refused before being read. It should
+	// be positive: otherwise the default limit of the server, of 4MB, is
+	// used, as for the other endpoints.
+	MaxPayloadSize int
+	// DisableMining makes the node refuse the entries it receives, as it
+	// does not mine blocks. Blocks mined by others are still accepted.
+	DisableMining bool
+}
+
+// rateWindow counts the requests made by a client in the current window.
+type rateWindow struct {
+	start    time.Time
+	requests int
+}
+
+// writeGuard authenticates and rate limits requests made to write endpoints,
+// and limits the size of their body.
+type writeGuard struct {
+	settings WriteSettings
+	windows  map[string]*rateWindow
+	lock     sync.Mutex
+}
+
+func newWriteGuard(settings WriteSettings) *writeGuard {
+	return &writeGuard{
+		settings: settings,
+		windows:  map[string]*rateWindow{},
+	}
+}
+
+// handlers returns the middlewares that must precede write endpoints.
+func (w *writeGuard) handlers() []fiber.Handler {
+	return []fiber.Handler{
+		w.authenticate,
+		w.limitRate,
+	}
+}
+
+// requestConfig limits the size of the body of requests to write endpoints,
+// i.e. POST /blocks and POST /rpc, as RPC requests can modify the chain.
+// It is called once the headers of a request are read, before its body.
+func (w *writeGuard) requestConfig(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+	if !header.IsPost() {
+		return fasthttp.RequestConfig{}
+	}
+
+	return fasthttp.RequestConfig{MaxRequestBodySize: w.settings.MaxPayloadSize}
+}
+
+func (w *writeGuard) authenticate(c *fiber.Ctx) error {
+	client, err := w.identify(c)
+	if err != nil {
+		log.Warn().Err(err).Str("ip", c.IP()).Str("path", c.Path()).Msg("unauthorized request")
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	c.Locals(clientLocal, client)
+	return c.Next()
+}
+
+// identify returns the name of the client that made the request, or
+// anonymousClient if authentication is disabled.
+func (w *writeGuard) identify(c *fiber.Ctx) (string, error) {
+	auth := w.settings.Auth
+	if len(auth.APIKeys) == 0 && auth.JWTSecret == "" {
+		return anonymousClient, nil
+	}
+
+	return w.getAuthenticatedClient(c)
+}
+
+func (w *writeGuard) getAuthenticatedClient(c *fiber.Ctx) (string, error) {
+	token := c.Get("X-API-Key")
+	if bearer := c.Get(fiber.HeaderAuthorization); token == "" && strings.HasPrefix(bearer, "Bearer ") {
+		token = strings.TrimPrefix(bearer, "Bearer ")
+	}
+	if token == "" {
+		return "", fmt.Errorf("no credentials provided")
+	}
+
+	if strings.Count(token, ".") == 2 && w.settings.Auth.JWTSecret != "" {
+		return verifyJWT(token, []byte(w.settings.Auth.JWTSecret))
+	}
+
+	for name, key := range w.settings.Auth.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid api key")
+}
+
+func (w *writeGuard) limitRate(c *fiber.Ctx) error {
+	client := getClient(c)
+	if allowed, retryAfter := w.allow(client); !allowed {
+		log.Warn().Str("client", client).Str("ip", c.IP()).Str("path", c.Path()).Msg("rate limit reached")
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())+1))
+		return c.SendStatus(fiber.StatusTooManyRequests)
+	}
+
+	return c.Next()
+}
+
+// allow counts a request from the provided client and tells whether it is
+// within the rate limit. If not, it also returns how long the client should
+// wait before retrying.
+func (w *writeGuard) allow(client string) (bool, time.Duration) {
+	if w.settings.RateLimit <= 0 {
+		return true, 0
+	}
+
+	now := time.Now()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for key, window := range w.windows {
+		// forget clients that have been quiet for a while
+		if now.Sub(window.start) >= w.settings.RateLimitWindow {
+			delete(w.windows, key)
+		}
+	}
+
+	window, exists := w.windows[client]
+	if !exists {
+		window = &rateWindow{start: now}
+		w.windows[client] = window
+	}
+	window.requests++
+
+	if window.requests > w.settings.RateLimit {
+		return false, window.start.Add(w.settings.RateLimitWindow).Sub(now)
+	}
+
+	return true, 0
+}
+
+// getClient returns the name of the client that made the request, or its
+// IP if it was not authenticated.
+func getClient(c *fiber.Ctx) string {
+	if client, ok := c.Locals(clientLocal).(string); ok && client != anonymousClient {
+		return client
+	}
+
+	return c.IP()
+}
+
+// verifyJWT verifies a JWT signed with HS256 and returns its subject.
+func verifyJWT(token string, secret []byte) (string, error) {
+	parts := strings.Split(token, ".")
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return "", fmt.Errorf("invalid token header: %w", err)
+	}
+	if header.Alg != "HS256" {
+		return "", fmt.Errorf("unsupported signing algorithm %q", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("invalid token signature: %w", err)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", fmt.Errorf("invalid token signature")
+	}
+
+	var claims struct {
+		Subject   string `json:"sub"`
+		ExpiresAt int64  `json:"exp"`
+		NotBefore int64  `json:"nbf"`
+	}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return "", fmt.Errorf("invalid token claims: %w", err)
+	}
+
+	now := time.Now().Unix()
+	switch {
+	case claims.Subject == "":
+		return "", fmt.Errorf("token has no subject")
+	case claims.ExpiresAt != 0 && now >= claims.ExpiresAt:
+		return "", fmt.Errorf("token is expired")
+	case claims.NotBefore != 0 && now < claims.NotBefore:
+		return "", fmt.Errorf("token is not valid yet")
+	}
+
+	return claims.Subject, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
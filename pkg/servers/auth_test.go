@@ -0,0 +1,320 @@
+package servers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	testAPIKey    string = "test-key"
+	testJWTSecret string = "test-secret"
+)
+
+// testMiner pushes blocks with the entries right away.
+type testMiner struct {
+	factory    *block.BlockFactory
+	blockchain *block.BlockChain
+}
+
+func (m *testMiner) Mine(_ context.Context, entry string) (*pb.Block, error) {
+	newBlock := m.factory.NewBlock([]string{entry}, m.blockchain.GetLastBlock())
+	if err := m.blockchain.PushBlock(newBlock); err != nil {
+		return nil, err
+	}
+
+	return newBlock, nil
+}
+
+// serve serves the app on a random port of the loopback interface until the
+// test ends, and returns its URL.
+func serve(t *testing.T, app *fiber.App) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the server waits for open connections when shutting down, so they
+	// must not be kept alive by the client.
+	app.Server().DisableKeepalive = true
+	go app.Listener(listener)
+	t.Cleanup(func() { app.Shutdown() })
+
+	return "http://" + listener.Addr().String()
+}
+
+// newTestServer serves a public server with the provided settings and
+// returns its URL.
+func newTestServer(t *testing.T, settings WriteSettings) string {
+	t.Helper()
+
+	bus := events.NewBus()
+	t.Cleanup(bus.Close)
+	f := block.NewBlockFactory(block.WithEventBus(bus))
+	bc := f.NewBlockChain()
+	server := NewPublicServer(bc, bus, &testMiner{factory: f, blockchain: bc}, settings)
+
+	return serve(t, server.FiberApp)
+}
+
+// do sends the request and returns the status code and the body of the
+// response.
+func do(t *testing.T, method, url, body string, headers map[string]string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(respBody)
+}
+
+// signJWT returns a JWT signed with HS256 with the provided claims.
+func signJWT(secret string, claims map[string]interface{}) string {
+	encode := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	unsigned := encode(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encode(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthentication(t *testing.T) {
+	auth := AuthSettings{
+		APIKeys:   map[string]string{"client": testAPIKey},
+		JWTSecret: testJWTSecret,
+	}
+	expiresAt := time.Now().Add(time.Hour).Unix()
+
+	cases := []struct {
+		name       string
+		auth       AuthSettings
+		headers    map[string]string
+		wantStatus int
+	}{
+		{
+			name:       "disabled",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no credentials",
+			auth:       auth,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "api key",
+			auth:       auth,
+			headers:    map[string]string{"X-API-Key": testAPIKey},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "api key as bearer",
+			auth:       auth,
+			headers:    map[string]string{"Authorization": "Bearer " + testAPIKey},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong api key",
+			auth:       auth,
+			headers:    map[string]string{"X-API-Key": "wrong"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "jwt",
+			auth: auth,
+			headers: map[string]string{
+				"Authorization": "Bearer " + signJWT(testJWTSecret, map[string]interface{}{"sub": "client", "exp": expiresAt}),
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "jwt signed with another secret",
+			auth: auth,
+			headers: map[string]string{
+				"Authorization": "Bearer " + signJWT("other", map[string]interface{}{"sub": "client", "exp": expiresAt}),
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "expired jwt",
+			auth: auth,
+			headers: map[string]string{
+				"Authorization": "Bearer " + signJWT(testJWTSecret, map[string]interface{}{"sub": "client", "exp": time.Now().Add(-time.Minute).Unix()}),
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "jwt without subject",
+			auth: auth,
+			headers: map[string]string{
+				"Authorization": "Bearer " + signJWT(testJWTSecret, map[string]interface{}{"exp": expiresAt}),
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			url := newTestServer(t, WriteSettings{Auth: c.auth})
+
+			if status, body := do(t, http.MethodPost, url+"/blocks", "entry", c.headers); status != c.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", status, body, c.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRPCAuthentication(t *testing.T) {
+	url := newTestServer(t, WriteSettings{Auth: AuthSettings{APIKeys: map[string]string{"client": testAPIKey}}})
+	call := func(method string, headers map[string]string) *rpcResponse {
+		t.Helper()
+
+		request := fmt.Sprintf(`{"jsonrpc":"2.0","method":%q,"params":["entry"],"id":1}`, method)
+		status, body := do(t, http.MethodPost, url+"/rpc", request, headers)
+		if status != http.StatusOK {
+			t.Fatalf("status = %d (%s), want %d", status, body, http.StatusOK)
+		}
+
+		var response rpcResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			t.Fatal(err)
+		}
+
+		return &response
+	}
+
+	// reads don't need credentials.
+	if response := call("getblockhash", nil); response.Error != nil && response.Error.Code == rpcUnauthorized {
+		t.Fatal("read method required credentials")
+	}
+
+	if response := call("sendrawtransaction", nil); response.Error == nil || response.Error.Code != rpcUnauthorized {
+		t.Fatalf("error = %v, want code %d", response.Error, rpcUnauthorized)
+	}
+
+	if response := call("sendrawtransaction", map[string]string{"X-API-Key": testAPIKey}); response.Error != nil {
+		t.Fatalf("unexpected error: %s", response.Error)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	url := newTestServer(t, WriteSettings{
+		Auth:            AuthSettings{APIKeys: map[string]string{"first": "first-key", "second": "second-key"}},
+		RateLimit:       2,
+		RateLimitWindow: time.Minute,
+	})
+	post := func(key string) int {
+		t.Helper()
+
+		status, _ := do(t, http.MethodPost, url+"/blocks", "entry", map[string]string{"X-API-Key": key})
+		return status
+	}
+
+	for i := 0; i < 2; i++ {
+		if status := post("first-key"); status != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i, status, http.StatusOK)
+		}
+	}
+
+	if status := post("first-key"); status != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", status, http.StatusTooManyRequests)
+	}
+
+	// the limit applies to each client.
+	if status := post("second-key"); status != http.StatusOK {
+		t.Fatalf("status of another client = %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	const maxPayloadSize = 64
+	url := newTestServer(t, WriteSettings{MaxPayloadSize: maxPayloadSize})
+	small := `{"jsonrpc":"2.0","method":"getblockcount","id":1}`
+	large := `{"jsonrpc":"2.0","method":"getblockcount","id":"` + strings.Repeat("a", maxPayloadSize) + `"}`
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "write within the limit",
+			method:     http.MethodPost,
+			path:       "/blocks",
+			body:       strings.Repeat("a", maxPayloadSize),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "write over the limit",
+			method:     http.MethodPost,
+			path:       "/blocks",
+			body:       strings.Repeat("a", maxPayloadSize+1),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "rpc within the limit",
+			method:     http.MethodPost,
+			path:       "/rpc",
+			body:       small,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "rpc over the limit",
+			method:     http.MethodPost,
+			path:       "/rpc",
+			body:       large,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "read over the limit",
+			method:     http.MethodGet,
+			path:       "/blocks",
+			body:       strings.Repeat("a", 2*maxPayloadSize),
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if status, body := do(t, c.method, url+c.path, c.body, nil); status != c.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", status, body, c.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package servers
+
+import (
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/lightclient"
+	"github.com/SunSince90/go-naivecoin/pkg/wallet"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LightServer is the public server of nodes running in light mode: it
+// answers inclusion and balance queries by asking full peers for merkle
+// proofs and verifying them against the headers it synced.
+type LightServer struct {
+	FiberApp    *fiber.App
+	lightClient *lightclient.LightClient
+}
+
+// NewLightServer creates and returns a new instance of the LightServer.
+func NewLightServer(lightClient *lightclient.LightClient) *LightServer {
+	server := &LightServer{
+		FiberApp:    fiber.New(fiber.Config{ReadTimeout: 30 * time.Second}),
+		lightClient: lightClient,
+	}
+
+	app := server.FiberApp
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("Hello, World 👋!")
+	})
+	app.Get("/headers/tip", server.handleGetTip)
+	app.Get("/proofs/:entryHash", server.handleGetProof)
+	app.Get("/watched", server.handleGetWatched)
+	app.Put("/watched/:entryHash", server.handlePutWatched)
+	app.Get("/balances", server.handleGetBalances)
+	app.Get("/balances/:address", server.handleGetBalance)
+	app.Put("/balances/:address", server.handlePutBalance)
+	return server
+}
+
+func (l *LightServer) handleGetTip(c *fiber.Ctx) error {
+	return c.JSON(l.lightClient.Headers().GetLastHeader())
+}
+
+func (l *LightServer) handleGetProof(c *fiber.Ctx) error {
+	entryHash, err := hex.DecodeString(c.Params("entryHash"))
+	if err != nil || len(entryHash) == 0 {
+		c.Send([]byte("entry hash must be a valid hex string"))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	proof, err := l.lightClient.GetProof(c.Context(), entryHash)
+	if err != nil {
+		if errors.Is(err, block.ErrEntryNotFound) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		c.Send([]byte(err.Error()))
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.JSON(proof)
+}
+
+func (l *LightServer) handleGetWatched(c *fiber.Ctx) error {
+	return c.JSON(l.lightClient.GetWatched())
+}
+
+func (l *LightServer) handlePutWatched(c *fiber.Ctx) error {
+	entryHash, err := hex.DecodeString(c.Params("entryHash"))
+	if err != nil || len(entryHash) == 0 {
+		c.Send([]byte("entry hash must be a valid hex string"))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	l.lightClient.Watch(entryHash)
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+func (l *LightServer) handleGetBalances(c *fiber.Ctx) error {
+	return c.JSON(l.lightClient.GetWatchedBalances())
+}
+
+func (l *LightServer) handleGetBalance(c *fiber.Ctx) error {
+	address := c.Params("address")
+	if err := wallet.ValidateAddress(address); err != nil {
+		c.Send([]byte(err.Error()))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	balance, err := l.lightClient.GetBalance(c.Context(), address)
+	if err != nil {
+		c.Send([]byte(err.Error()))
+		if errors.Is(err, lightclient.ErrNoPeerAnswered) {
+			return c.SendStatus(fiber.StatusServiceUnavailable)
+		}
+
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.JSON(balance)
+}
+
+func (l *LightServer) handlePutBalance(c *fiber.Ctx) error {
+	if err := l.lightClient.WatchAddress(c.Params("address")); err != nil {
+		c.Send([]byte(err.Error()))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
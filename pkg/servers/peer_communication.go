@@ -2,12 +2,58 @@ package servers
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/finality"
+	"github.com/SunSince90/go-naivecoin/pkg/metrics"
+	"github.com/SunSince90/go-naivecoin/pkg/mining"
 	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/SunSince90/go-naivecoin/pkg/wallet"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
+const (
+	// maxHeadersPerRequest is the maximum number of headers returned by a
+	// single GetHeaders call.
+	maxHeadersPerRequest int = 2000
+	// maxBlocksPerRequest is the maximum number of blocks returned by a
+	// single GetBlocks call.
+	maxBlocksPerRequest int = 500
+	// subscriberQueueSize is how many blocks can wait to be sent to a
+	// subscriber before the new ones are dropped for it.
+	subscriberQueueSize int = 10
+)
+
+// SubscriberStats contains information about a peer subscribed to the blocks
+// mined by this node.
+type SubscriberStats struct {
+	ID            int       `json:"id"`
+	Address       string    `json:"address"`
+	Since         time.Time `json:"since"`
+	BlocksSent    int64     `json:"blocksSent"`
+	BlocksDropped int64     `json:"blocksDropped"`
+	QueueDepth    int       `json:"queueDepth"`
+}
+
+type subscriber struct {
+	address string
+	since   time.Time
+	blocks  chan *pb.Block
+	// sent and dropped must be accessed atomically.
+	sent    int64
+	dropped int64
+}
+
 // PeerCommunicationServer is used to make pods communicate with each other
 // and exchange information that should not be public but only useful for
 // internal purposes, like synchronization and receive notifications of new
@@ -16,16 +62,25 @@ import (
 // This server should be used with gRPC.
 type PeerCommunicationServer struct {
 	blockchain  *block.BlockChain
-	subscribers []chan *pb.Block
+	worker      *mining.Worker
+	finality    *finality.Gadget
+	subscribers map[int]*subscriber
+	lastSubID   int
+	lock        sync.Mutex
 	pb.UnimplementedPeerCommunicationServer
 }
 
 // NewPeerCommunicationServer creates and returns a new instance of the
-// PeerCommunicationServer.
-func NewPeerCommunicationServer(blockchain *block.BlockChain) *PeerCommunicationServer {
+// PeerCommunicationServer. Entries forwarded by peers are mined by the
+// provided worker, which can be nil if the node does not mine blocks. Votes
+// sent by peers are counted by the finality gadget, which can be nil if
+// finality is disabled.
+func NewPeerCommunicationServer(blockchain *block.BlockChain, worker *mining.Worker, gadget *finality.Gadget) *PeerCommunicationServer {
 	return &PeerCommunicationServer{
 		blockchain:  blockchain,
-		subscribers: []chan *pb.Block{},
+		worker:      worker,
+		finality:    gadget,
+		subscribers: map[int]*subscriber{},
 	}
 }
 
@@ -48,17 +103,140 @@ func (c *PeerCommunicationServer) GetFullBlockChain(ctx context.Context, _ *pb.G
 	}, nil
 }
 
+// GetHeaders returns the headers of the chain starting from the requested
+// index. This is used by light clients, which only store headers.
+func (c *PeerCommunicationServer) GetHeaders(ctx context.Context, params *pb.GetHeadersParams) (*pb.Headers, error) {
+	limit := int(params.Limit)
+	if limit <= 0 || limit > maxHeadersPerRequest {
+		limit = maxHeadersPerRequest
+	}
+
+	return &pb.Headers{
+		Headers: c.blockchain.GetHeaders(int(params.FromIndex), limit),
+	}, nil
+}
+
+// GetBlocks returns the blocks of the chain starting from the requested
+// index. This is used by peers that only need to catch up with the chain
+// instead of downloading it whole.
+func (c *PeerCommunicationServer) GetBlocks(ctx context.Context, params *pb.GetBlocksParams) (*pb.BlockChain, error) {
+	limit := int(params.Limit)
+	if limit <= 0 || limit > maxBlocksPerRequest {
+		limit = maxBlocksPerRequest
+	}
+
+	return &pb.BlockChain{
+		Blocks: c.blockchain.GetBlocks(int(params.FromIndex), limit),
+	}, nil
+}
+
+// GetMerkleProof returns the proof that the entry with the provided hash is
+// included in the chain, so that light clients don't need to download the
+// whole chain to verify it.
+func (c *PeerCommunicationServer) GetMerkleProof(ctx context.Context, params *pb.GetMerkleProofParams) (*pb.MerkleProof, error) {
+	proof, err := c.blockchain.GetMerkleProof(params.EntryHash)
+	if err != nil {
+		if errors.Is(err, block.ErrEntryNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return proof, nil
+}
+
+// GetAddressEntries returns the entries that move an amount to or from the
+// requested address, along with the proofs of their inclusion, so that light
+// clients can compute its balance.
+func (c *PeerCommunicationServer) GetAddressEntries(ctx context.Context, params *pb.GetAddressEntriesParams) (*pb.ProvenEntries, error) {
+	if err := wallet.ValidateAddress(params.Address); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &pb.ProvenEntries{
+		Entries: c.blockchain.FindEntries(func(entry string, height int64) bool {
+			return wallet.Involves(params.Address, entry, height == 0)
+		}),
+	}, nil
+}
+
+// SubmitEntry mines a block with an entry that a peer received and returns
+// it. Peers forward entries to the node elected to mine, so entries are never
+// forwarded again from here: they are refused if this node is not mining.
+func (c *PeerCommunicationServer) SubmitEntry(ctx context.Context, params *pb.SubmitEntryParams) (*pb.Block, error) {
+	if c.worker == nil {
+		return nil, status.Error(codes.FailedPrecondition, "this node does not mine blocks")
+	}
+	if params.Entry == "" {
+		return nil, status.Error(codes.InvalidArgument, "entry cannot be empty")
+	}
+
+	newBlock, err := c.worker.Mine(ctx, params.Entry)
+	if err != nil {
+		return nil, mining.SubmissionStatus(err)
+	}
+
+	log.Info().
+		Str("entry-hash", hex.EncodeToString(block.HashEntry(params.Entry))).
+		Int64("index", newBlock.Header.Index).
+		Str("block-hash", hex.EncodeToString(newBlock.Hash)).
+		Msg("forwarded entry mined")
+
+	return newBlock, nil
+}
+
+// Prevote counts the prevote of a validator of the finality gadget.
+func (c *PeerCommunicationServer) Prevote(ctx context.Context, vote *pb.FinalityVote) (*pb.FinalityVoteAck, error) {
+	return c.handleVote(finality.Prevote, vote)
+}
+
+// Precommit counts the precommit of a validator of the finality gadget.
+func (c *PeerCommunicationServer) Precommit(ctx context.Context, vote *pb.FinalityVote) (*pb.FinalityVoteAck, error) {
+	return c.handleVote(finality.Precommit, vote)
+}
+
+func (c *PeerCommunicationServer) handleVote(voteType finality.VoteType, vote *pb.FinalityVote) (*pb.FinalityVoteAck, error) {
+	if c.finality == nil {
+		return nil, status.Error(codes.FailedPrecondition, "finality is disabled on this node")
+	}
+
+	if err := c.finality.HandleVote(voteType, vote); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &pb.FinalityVoteAck{}, nil
+}
+
 // SubscribeNewBlocks *sends* data to peers that are subscribed to me.
 // It is called SubscribeNewBlocks because that's what peers will do, but here
 // we are on the serving side.
 // TODO: update this name in future?
 func (c *PeerCommunicationServer) SubscribeNewBlocks(_ *pb.SubscribeNewBlocksParams, commStream pb.PeerCommunication_SubscribeNewBlocksServer) error {
-	// This can probably be written in a better way, but for now let's keep
-	// this like this and see how we can improve this in future.
-	myChan := make(chan *pb.Block, 10)
-	c.subscribers = append(c.subscribers, myChan)
+	sub := &subscriber{
+		address: "unknown",
+		since:   time.Now(),
+		blocks:  make(chan *pb.Block, subscriberQueueSize),
+	}
+	if p, ok := peer.FromContext(commStream.Context()); ok {
+		sub.address = p.Addr.String()
+	}
+
+	c.lock.Lock()
+	c.lastSubID++
+	id := c.lastSubID
+	c.subscribers[id] = sub
+	c.lock.Unlock()
+
+	defer func() {
+		c.lock.Lock()
+		delete(c.subscribers, id)
+		c.lock.Unlock()
+		c.updateQueueDepth()
+	}()
 
-	for block := range myChan {
+	for block := range sub.blocks {
+		c.updateQueueDepth()
 		if err := commStream.SendMsg(block); err != nil {
 			if commStream.Context().Err() == context.DeadlineExceeded ||
 				commStream.Context().Err() == context.Canceled {
@@ -67,21 +245,93 @@ func (c *PeerCommunicationServer) SubscribeNewBlocks(_ *pb.SubscribeNewBlocksPar
 
 			return err
 		}
+		atomic.AddInt64(&sub.sent, 1)
 	}
 
 	return nil
 }
 
-func (c *PeerCommunicationServer) ServeSubscriptions(genBlock chan *pb.Block) {
-	for block := range genBlock {
-		for _, sub := range c.subscribers {
-			sub <- block
+// ServeSubscriptions forwards the blocks mined by this node to the peers
+// that are subscribed to me, until the bus is closed.
+//
+// Blocks are queued for each subscriber without waiting: if the queue of a
+// slow peer is full, the block is dropped only for that peer, which will get
+// it when catching up with the chain, so that it doesn't hold back the others.
+func (c *PeerCommunicationServer) ServeSubscriptions(bus *events.Bus) {
+	id, evChan := bus.Subscribe(10)
+	defer bus.Unsubscribe(id)
+
+	for ev := range evChan {
+		// blocks submitted by clients are only known to this node, so
+		// they are forwarded like the ones it mined.
+		if ev.EventType != events.EventBlockMined && ev.EventType != events.EventBlockSubmitted {
+			continue
 		}
+
+		for _, sub := range c.getSubscribers() {
+			select {
+			case sub.blocks <- ev.Block:
+			default:
+				atomic.AddInt64(&sub.dropped, 1)
+				log.Warn().
+					Str("subscriber", sub.address).
+					Int64("index", ev.Block.Header.Index).
+					Msg("subscriber is too slow, dropping block")
+			}
+		}
+		c.updateQueueDepth()
 	}
 
 	log.Info().Msg("closing all subscriptions...")
-	for _, sub := range c.subscribers {
-		close(sub)
+	for _, sub := range c.getSubscribers() {
+		close(sub.blocks)
 	}
 	log.Info().Msg("all subscriptions closed")
 }
+
+// GetSubscriberStats returns information about the peers subscribed to the
+// blocks mined by this node, sorted by subscription order.
+func (c *PeerCommunicationServer) GetSubscriberStats() []*SubscriberStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	stats := make([]*SubscriberStats, 0, len(c.subscribers))
+	for id, sub := range c.subscribers {
+		stats = append(stats, &SubscriberStats{
+			ID:            id,
+			Address:       sub.address,
+			Since:         sub.since,
+			BlocksSent:    atomic.LoadInt64(&sub.sent),
+			BlocksDropped: atomic.LoadInt64(&sub.dropped),
+			QueueDepth:    len(sub.blocks),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].ID < stats[j].ID
+	})
+
+	return stats
+}
+
+func (c *PeerCommunicationServer) getSubscribers() []*subscriber {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	subs := make([]*subscriber, 0, len(c.subscribers))
+	for _, sub := range c.subscribers {
+		subs = append(subs, sub)
+	}
+
+	return subs
+}
+
+// updateQueueDepth updates the metric about blocks waiting to be sent to
+// subscribers.
+func (c *PeerCommunicationServer) updateQueueDepth() {
+	depth := 0
+	for _, sub := range c.getSubscribers() {
+		depth += len(sub.blocks)
+	}
+
+	metrics.SubscriberQueueDepth.Set(float64(depth))
+}
@@ -0,0 +1,101 @@
+package servers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+)
+
+// testStream is a subscription stream that delivers the blocks on a channel.
+// If release is not nil, sending blocks until it is closed, like a slow peer
+// that stops reading.
+type testStream struct {
+	received chan *pb.Block
+	release  chan struct{}
+	pb.PeerCommunication_SubscribeNewBlocksServer
+}
+
+func (s *testStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *testStream) SendMsg(m interface{}) error {
+	if s.release != nil {
+		<-s.release
+	}
+	s.received <- m.(*pb.Block)
+	return nil
+}
+
+func TestServeSubscriptionsWithSlowSubscriber(t *testing.T) {
+	const blocks = subscriberQueueSize + 5
+	server := NewPeerCommunicationServer(nil, nil, nil)
+	bus := events.NewBus()
+
+	slow := &testStream{received: make(chan *pb.Block, 2*blocks), release: make(chan struct{})}
+	fast := &testStream{received: make(chan *pb.Block, 2*blocks)}
+	streamsDone := make(chan struct{}, 2)
+	for i, stream := range []*testStream{slow, fast} {
+		go func(stream *testStream) {
+			server.SubscribeNewBlocks(&pb.SubscribeNewBlocksParams{}, stream)
+			streamsDone <- struct{}{}
+		}(stream)
+
+		// subscribe them in order, so that the stats are too
+		for len(server.GetSubscriberStats()) <= i {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	serveDone := make(chan struct{})
+	go func() {
+		server.ServeSubscriptions(bus)
+		close(serveDone)
+	}()
+
+	// waitFor publishes the block until the fast subscriber receives it,
+	// as the first ones can be published before ServeSubscriptions is
+	// subscribed to the bus.
+	waitFor := func(index int64) {
+		t.Helper()
+
+		block := &pb.Block{Header: &pb.BlockHeader{Index: index}}
+		bus.Publish(events.EventBlockMined, block)
+		for timeout := time.After(5 * time.Second); ; {
+			select {
+			case b := <-fast.received:
+				if b.Header.Index == index {
+					return
+				}
+			case <-time.After(50 * time.Millisecond):
+				if index == 0 {
+					bus.Publish(events.EventBlockMined, block)
+				}
+			case <-timeout:
+				t.Fatalf("block %d was not received", index)
+			}
+		}
+	}
+
+	waitFor(0)
+	for i := int64(1); i <= int64(blocks); i++ {
+		waitFor(i)
+	}
+
+	stats := server.GetSubscriberStats()
+	if stats[0].BlocksDropped == 0 {
+		t.Fatal("no blocks were dropped for the slow subscriber")
+	}
+	if stats[1].BlocksDropped != 0 {
+		t.Fatalf("%d blocks were dropped for the fast subscriber", stats[1].BlocksDropped)
+	}
+
+	close(slow.release)
+	bus.Close()
+	<-serveDone
+	<-streamsDone
+	<-streamsDone
+}
@@ -1,21 +1,57 @@
 package servers
 
 import (
+	"fmt"
 	"time"
 
-	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/metrics"
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
 	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
 )
 
+// syncStatusProvider is implemented by both the peers manager of full nodes
+// and the light client.
+type syncStatusProvider interface {
+	GetSyncStatus() *peers.SyncStatus
+}
+
+// ReadinessSettings defines when a node should be considered ready.
+type ReadinessSettings struct {
+	// MinPeers is the minimum number of peers the node must be connected to.
+	MinPeers int
+	// MaxBlocksBehind is how many blocks the node can be behind the best
+	// known peer.
+	MaxBlocksBehind int64
+	// InitialSyncGracePeriod is how long to wait for a first peer to sync
+	// from before considering the initial sync done anyways, e.g. because
+	// this is the first node of the network.
+	InitialSyncGracePeriod time.Duration
+}
+
+// SyncDetails is the sync status of the node along with whether it is ready.
+type SyncDetails struct {
+	*peers.SyncStatus
+	// Ready tells whether the node is ready to receive traffic.
+	Ready bool `json:"ready"`
+	// Reason explains why the node is not ready.
+	Reason string `json:"reason,omitempty"`
+}
+
 // ProbesServer implements probes for Kubernetes.
 //
 // A probe server is useful because it will prevent Kubernetes from marking a
 // pod as ready until some condition is verified, e.g. inits.
 // In our case, we will let Kubernetes know that a pod is ready only once it
-// created the genesis block.
+// finished syncing the chain with its peers.
+//
+// It also exposes the metrics of the node for Prometheus on /metrics.
 type ProbesServer struct {
 	FiberApp   *fiber.App
-	blockchain *block.BlockChain
+	syncStatus syncStatusProvider
+	settings   ReadinessSettings
+	startedAt  time.Time
 }
 
 func (p *ProbesServer) healthz(c *fiber.Ctx) error {
@@ -23,19 +59,45 @@ func (p *ProbesServer) healthz(c *fiber.Ctx) error {
 }
 
 func (p *ProbesServer) readyz(c *fiber.Ctx) error {
-	if p.blockchain.Length() > 0 {
+	details := p.getSyncDetails()
+	if details.Ready {
 		return c.SendStatus(fiber.StatusOK)
 	}
 
-	return c.SendStatus(fiber.ErrInternalServerError.Code)
+	c.Send([]byte(details.Reason))
+	return c.SendStatus(fiber.StatusServiceUnavailable)
+}
+
+func (p *ProbesServer) syncz(c *fiber.Ctx) error {
+	return c.JSON(p.getSyncDetails())
+}
+
+func (p *ProbesServer) getSyncDetails() *SyncDetails {
+	status := p.syncStatus.GetSyncStatus()
+	details := &SyncDetails{SyncStatus: status}
+
+	switch {
+	case !status.InitialSyncDone && time.Since(p.startedAt) < p.settings.InitialSyncGracePeriod:
+		details.Reason = "initial sync is not finished"
+	case status.Peers < p.settings.MinPeers:
+		details.Reason = fmt.Sprintf("connected to %d peers, need at least %d", status.Peers, p.settings.MinPeers)
+	case status.BestPeerHeight-status.Height > p.settings.MaxBlocksBehind:
+		details.Reason = fmt.Sprintf("%d blocks behind the best peer", status.BestPeerHeight-status.Height)
+	default:
+		details.Ready = true
+	}
+
+	return details
 }
 
 // NewProbesServer returns a server that that implements probes for Kubernetes.
 // This server needs to run on a different port from the one from
 // NewPublicServer().
-func NewProbesServer(blockchain *block.BlockChain) *ProbesServer {
+func NewProbesServer(syncStatus syncStatusProvider, settings ReadinessSettings) *ProbesServer {
 	p := &ProbesServer{
-		blockchain: blockchain,
+		syncStatus: syncStatus,
+		settings:   settings,
+		startedAt:  time.Now(),
 	}
 
 	app := fiber.New(fiber.Config{ReadTimeout: 5 * time.Second})
@@ -44,6 +106,13 @@ func NewProbesServer(blockchain *block.BlockChain) *ProbesServer {
 	})
 	app.Get("/healthz", p.healthz)
 	app.Get("/readyz", p.readyz)
+	app.Get("/syncz", p.syncz)
+
+	metricsHandler := fasthttpadaptor.NewFastHTTPHandler(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+	app.Get("/metrics", func(c *fiber.Ctx) error {
+		metricsHandler(c.Context())
+		return nil
+	})
 
 	p.FiberApp = app
 	return p
@@ -1,33 +1,74 @@
 package servers
 
 import (
+	"context"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/mining"
 	"github.com/SunSince90/go-naivecoin/pkg/pb"
 	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
 )
 
+const (
+	// defaultPageLimit is the number of blocks returned in a page when the
+	// limit is not specified.
+	defaultPageLimit int = 100
+	// maxPageLimit is the maximum number of blocks returned in a page.
+	maxPageLimit int = 1000
+	// submissionTimeout is how long mining a submitted entry can take
+	// before the request fails.
+	submissionTimeout time.Duration = 5 * time.Minute
+)
+
+// openAPISpec is the OpenAPI document describing the routes of the
+// PublicServer. Keep it up to date when changing them.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// BlocksPage is a page of blocks returned when paginating the chain.
+type BlocksPage struct {
+	// Blocks contained in this page.
+	Blocks []*pb.Block `json:"blocks"`
+	// Next is the height to request the next page from. It is empty if this
+	// is the last page.
+	Next *int `json:"next,omitempty"`
+}
+
 // PublicServer exposes some information about the pod and that should be equal
 // to all pods, e.g. the blocks or blockchain.
 type PublicServer struct {
-	FiberApp     *fiber.App
-	genBlock     chan *pb.Block
-	blockchain   *block.BlockChain
-	blockFactory *block.BlockFactory
+	FiberApp   *fiber.App
+	events     *events.Bus
+	blockchain *block.BlockChain
+	miner      mining.Miner
+	writeGuard *writeGuard
 }
 
 // NewPublicServer creates and returns a new instance of the PublicServer.
-func NewPublicServer(blockchain *block.BlockChain, genBlock chan *pb.Block, blockFactory *block.BlockFactory) *PublicServer {
+// Entries submitted to the node are mined by the provided miner. Endpoints that
+// modify the chain are protected according to the provided settings.
+func NewPublicServer(blockchain *block.BlockChain, bus *events.Bus, miner mining.Miner, settings WriteSettings) *PublicServer {
 	server := &PublicServer{
-		FiberApp:     fiber.New(fiber.Config{ReadTimeout: 30 * time.Second}),
-		genBlock:     genBlock,
-		blockchain:   blockchain,
-		blockFactory: blockFactory,
+		FiberApp:   fiber.New(fiber.Config{ReadTimeout: 30 * time.Second}),
+		events:     bus,
+		blockchain: blockchain,
+		miner:      miner,
+		writeGuard: newWriteGuard(settings),
 	}
 
 	// set up the fiber server
 	app := server.FiberApp
+	// bodies are read before any handler runs, so the size of the ones sent
+	// to write endpoints is limited as soon as their headers are read.
+	app.Server().HeaderReceived = server.writeGuard.requestConfig
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.SendString("Hello, World 👋!")
 	})
@@ -40,28 +81,146 @@ func NewPublicServer(blockchain *block.BlockChain, genBlock chan *pb.Block, bloc
 		return c.Next()
 	})
 	app.Get("/blocks", server.handleGetBlocks)
-	app.Post("/blocks", server.handlePostBlocks)
+	app.Post("/blocks", append(server.writeGuard.handlers(), server.handlePostBlocks)...)
+	app.Get("/blocks/hash/:hash", server.handleGetBlockByHash)
+	app.Get("/blocks/:height", server.handleGetBlockByHeight)
+	app.Get("/chain/tip", server.handleGetChainTip)
+	app.Get("/chain/stats", server.handleGetChainStats)
+	app.Get("/proofs/:entryHash", server.handleGetProof)
+	app.Get("/events/blocks", server.handleBlockEventsSSE)
+	app.Get("/events/blocks/ws", server.handleBlockEventsWebSocket)
+	app.Post("/rpc", server.handleRPC)
+	app.Get("/rpc/ws", server.handleRPCWebSocket)
+	app.Get("/openapi.json", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(openAPISpec)
+	})
 	// Probably more paths will come...
 	return server
 }
 
 func (n *PublicServer) handleGetBlocks(c *fiber.Ctx) error {
-	return c.JSON(n.blockchain.GetChain())
+	if c.Query("from") == "" && c.Query("limit") == "" {
+		return c.JSON(n.blockchain.GetChain())
+	}
+
+	from, err := strconv.Atoi(c.Query("from", "0"))
+	if err != nil || from < 0 {
+		c.Send([]byte("from must be a non negative integer"))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit <= 0 {
+		c.Send([]byte("limit must be a positive integer"))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	page := &BlocksPage{
+		Blocks: n.blockchain.GetBlocks(from, limit),
+	}
+	if next := from + len(page.Blocks); len(page.Blocks) == limit && next < n.blockchain.Length() {
+		page.Next = &next
+	}
+
+	return c.JSON(page)
+}
+
+func (n *PublicServer) handleGetBlockByHeight(c *fiber.Ctx) error {
+	height, err := strconv.Atoi(c.Params("height"))
+	if err != nil {
+		c.Send([]byte("height must be an integer"))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	block, err := n.blockchain.GetBlockByHeight(height)
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	return c.JSON(block)
+}
+
+func (n *PublicServer) handleGetBlockByHash(c *fiber.Ctx) error {
+	hash, err := hex.DecodeString(c.Params("hash"))
+	if err != nil || len(hash) == 0 {
+		c.Send([]byte("hash must be a valid hex string"))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	block, err := n.blockchain.GetBlockByHash(hash)
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	return c.JSON(block)
+}
+
+func (n *PublicServer) handleGetChainTip(c *fiber.Ctx) error {
+	return c.JSON(n.blockchain.GetLastBlock())
+}
+
+func (n *PublicServer) handleGetChainStats(c *fiber.Ctx) error {
+	return c.JSON(n.blockchain.GetStats())
 }
 
 func (n *PublicServer) handlePostBlocks(c *fiber.Ctx) error {
+	if n.writeGuard.settings.DisableMining {
+		c.Send([]byte("this node does not mine blocks"))
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
 	if len(c.Body()) == 0 {
 		c.Send([]byte("body cannot be empty"))
 		return c.SendStatus(fiber.ErrBadRequest.Code)
 	}
 
-	block := n.blockFactory.NewBlock(string(c.Body()), n.blockchain.GetLastBlock())
-	if err := n.blockchain.PushBlock(block); err != nil {
+	ctx, canc := context.WithTimeout(c.UserContext(), submissionTimeout)
+	defer canc()
+
+	entryHash := block.HashEntry(string(c.Body()))
+	block, err := n.miner.Mine(ctx, string(c.Body()))
+	if err != nil {
 		c.Send([]byte(err.Error()))
-		return c.SendStatus(fiber.ErrBadRequest.Code)
+		if errors.Is(err, mining.ErrBlockRejected) {
+			return c.SendStatus(fiber.ErrBadRequest.Code)
+		}
+
+		// the entry could not be mined at the moment, e.g. because a
+		// new miner is being elected.
+		return c.SendStatus(fiber.StatusServiceUnavailable)
 	}
 
-	n.genBlock <- block
+	log.Info().
+		Str("client", getClient(c)).
+		Str("ip", c.IP()).
+		Str("entry-hash", hex.EncodeToString(entryHash)).
+		Int64("index", block.Header.Index).
+		Str("block-hash", hex.EncodeToString(block.Hash)).
+		Msg("block submitted")
 
 	return c.SendStatus(fiber.StatusOK)
 }
+
+func (n *PublicServer) handleGetProof(c *fiber.Ctx) error {
+	entryHash, err := hex.DecodeString(c.Params("entryHash"))
+	if err != nil || len(entryHash) == 0 {
+		c.Send([]byte("entry hash must be a valid hex string"))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	proof, err := n.blockchain.GetMerkleProof(entryHash)
+	if err != nil {
+		if errors.Is(err, block.ErrEntryNotFound) {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+
+		c.Send([]byte(err.Error()))
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.JSON(proof)
+}
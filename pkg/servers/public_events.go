@@ -0,0 +1,177 @@
+package servers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// keepAliveInterval is how often streams are pinged to detect clients
+	// that went away while there were no events.
+	keepAliveInterval time.Duration = 15 * time.Second
+	// eventsBufferSize is the size of the channel each stream receives
+	// events from.
+	eventsBufferSize int = 100
+)
+
+var upgrader = websocket.FastHTTPUpgrader{}
+
+// blockEventsFrom returns the height to resume streaming from, or -1 if
+// the client only wants new events.
+//
+// SSE clients that reconnect send the id of the last event they received,
+// which is the height of its block.
+func blockEventsFrom(c *fiber.Ctx) (int, error) {
+	if from := c.Query("from"); from != "" {
+		height, err := strconv.Atoi(from)
+		if err != nil || height < 0 {
+			return 0, fmt.Errorf("from must be a non negative integer")
+		}
+
+		return height, nil
+	}
+
+	if lastID := c.Get("Last-Event-ID"); lastID != "" {
+		height, err := strconv.Atoi(lastID)
+		if err != nil || height < 0 {
+			return 0, fmt.Errorf("last event id must be a non negative integer")
+		}
+
+		return height + 1, nil
+	}
+
+	return -1, nil
+}
+
+// streamBlockEvents sends the accepted blocks starting from the provided
+// height, if not negative, and then all new events until the bus is closed
+// or sending fails.
+func (n *PublicServer) streamBlockEvents(from int, send func(*events.BlockEvent) error, ping func() error, done <-chan struct{}) {
+	// subscribe before replaying, so that no block is lost in between
+	id, evChan := n.events.Subscribe(eventsBufferSize)
+	defer n.events.Unsubscribe(id)
+
+	next := int64(-1)
+	if from >= 0 {
+		for {
+			blocks := n.blockchain.GetBlocks(from, maxPageLimit)
+			for _, block := range blocks {
+				if err := send(&events.BlockEvent{EventType: events.EventBlockAccepted, Block: block}); err != nil {
+					return
+				}
+			}
+
+			from += len(blocks)
+			if len(blocks) < maxPageLimit {
+				break
+			}
+		}
+
+		next = int64(from)
+	}
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ev, ok := <-evChan:
+			if !ok {
+				return
+			}
+
+			if ev.EventType == events.EventBlockAccepted && ev.Block.Header.Index < next {
+				// already sent while replaying
+				continue
+			}
+
+			if err := send(ev); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := ping(); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func (n *PublicServer) handleBlockEventsSSE(c *fiber.Ctx) error {
+	from, err := blockEventsFrom(c)
+	if err != nil {
+		c.Send([]byte(err.Error()))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		send := func(ev *events.BlockEvent) error {
+			data, err := json.Marshal(ev)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Block.Header.Index, ev.EventType, data)
+			return w.Flush()
+		}
+		ping := func() error {
+			fmt.Fprint(w, ": keep-alive\n\n")
+			return w.Flush()
+		}
+
+		n.streamBlockEvents(from, send, ping, nil)
+	})
+
+	return nil
+}
+
+func (n *PublicServer) handleBlockEventsWebSocket(c *fiber.Ctx) error {
+	from, err := blockEventsFrom(c)
+	if err != nil {
+		c.Send([]byte(err.Error()))
+		return c.SendStatus(fiber.ErrBadRequest.Code)
+	}
+
+	err = upgrader.Upgrade(c.Context(), func(conn *websocket.Conn) {
+		defer conn.Close()
+
+		// we don't expect messages from clients, but we need to read to
+		// know when they close the connection.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		send := func(ev *events.BlockEvent) error {
+			return conn.WriteJSON(ev)
+		}
+		ping := func() error {
+			return conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(5*time.Second))
+		}
+
+		n.streamBlockEvents(from, send, ping, done)
+	})
+	if err != nil {
+		log.Err(err).Msg("could not upgrade connection to websocket")
+	}
+
+	return nil
+}
@@ -0,0 +1,481 @@
+package servers
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/mining"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/SunSince90/go-naivecoin/pkg/wallet"
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// Error codes defined by the JSON-RPC 2.0 specification.
+const (
+	rpcParseError     int = -32700
+	rpcInvalidRequest int = -32600
+	rpcMethodNotFound int = -32601
+	rpcInvalidParams  int = -32602
+	rpcInternalError  int = -32603
+)
+
+// Error codes in the range reserved for implementation-defined server
+// errors.
+const (
+	rpcNotFound       int = -32000
+	rpcBlockRejected  int = -32001
+	rpcUnauthorized   int = -32002
+	rpcRateLimited    int = -32003
+	rpcMiningDisabled int = -32005
+	rpcMinerBusy      int = -32006
+)
+
+type rpcRequest struct {
+	JSONRPC string            `json:"jsonrpc"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+	// ID is nil if missing, which means that the request is a notification
+	// and must not be answered.
+	ID json.RawMessage `json:"id"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+func newRPCError(code int, format string, a ...interface{}) *rpcError {
+	return &rpcError{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// rpcCaller is who is calling RPC methods. Credentials are checked once per
+// HTTP request or WebSocket connection, but only enforced by write methods.
+type rpcCaller struct {
+	// client is the name of the client, or its IP if it did not
+	// authenticate.
+	client  string
+	ip      string
+	authErr error
+	// ctx is the context of the request, or the one of the WebSocket
+	// connection, which ends with it or when the server shuts down.
+	ctx context.Context
+}
+
+type rpcMethod func(caller *rpcCaller, params []json.RawMessage) (interface{}, error)
+
+func (n *PublicServer) rpcMethods() map[string]rpcMethod {
+	return map[string]rpcMethod{
+		"getblockcount":      n.rpcGetBlockCount,
+		"getbestblockhash":   n.rpcGetBestBlockHash,
+		"getblockhash":       n.rpcGetBlockHash,
+		"getblock":           n.rpcGetBlock,
+		"getdifficulty":      n.rpcGetDifficulty,
+		"getbalance":         n.rpcGetBalance,
+		"sendrawtransaction": n.rpcSendRawTransaction,
+		"submitblock":        n.rpcSubmitBlock,
+	}
+}
+
+func (n *PublicServer) newRPCCaller(c *fiber.Ctx) *rpcCaller {
+	caller := &rpcCaller{client: c.IP(), ip: c.IP(), ctx: c.UserContext()}
+
+	client, err := n.writeGuard.identify(c)
+	switch {
+	case err != nil:
+		caller.authErr = err
+	case client != anonymousClient:
+		caller.client = client
+	}
+
+	return caller
+}
+
+func (n *PublicServer) handleRPC(c *fiber.Ctx) error {
+	response := n.serveRPC(n.newRPCCaller(c), c.Body())
+	if response == nil {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(response)
+}
+
+func (n *PublicServer) handleRPCWebSocket(c *fiber.Ctx) error {
+	caller := n.newRPCCaller(c)
+
+	err := upgrader.Upgrade(c.Context(), func(conn *websocket.Conn) {
+		defer conn.Close()
+		// each message is a request that can modify the chain, like the
+		// body of POST /rpc.
+		if maxSize := n.writeGuard.settings.MaxPayloadSize; maxSize > 0 {
+			conn.SetReadLimit(int64(maxSize))
+		}
+
+		// the request cannot be used once the connection is upgraded, so
+		// calls end with the connection instead.
+		ctx, canc := context.WithCancel(context.Background())
+		defer canc()
+		caller.ctx = ctx
+
+		// stop calls and reading when the server is shutting down, as the
+		// read below would otherwise block forever.
+		id, evChan := n.events.Subscribe(eventsBufferSize)
+		defer n.events.Unsubscribe(id)
+		go func() {
+			for range evChan {
+			}
+			canc()
+			conn.SetReadDeadline(time.Now())
+		}()
+
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if response := n.serveRPC(caller, message); response != nil {
+				if err := conn.WriteMessage(websocket.TextMessage, response); err != nil {
+					return
+				}
+			}
+		}
+	})
+	if err != nil {
+		log.Err(err).Msg("could not upgrade connection to websocket")
+	}
+
+	return nil
+}
+
+// serveRPC handles a single or batch request and returns the encoded
+// response, or nil if there is nothing to answer, i.e. only notifications
+// were sent.
+func (n *PublicServer) serveRPC(caller *rpcCaller, payload []byte) []byte {
+	payload = bytes.TrimSpace(payload)
+
+	if len(payload) == 0 || payload[0] != '[' {
+		response := n.callRPC(caller, payload)
+		if response == nil {
+			return nil
+		}
+
+		data, _ := json.Marshal(response)
+		return data
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		data, _ := json.Marshal(rpcErrorResponse(nil, newRPCError(rpcParseError, "parse error")))
+		return data
+	}
+	if len(batch) == 0 {
+		data, _ := json.Marshal(rpcErrorResponse(nil, newRPCError(rpcInvalidRequest, "empty batch")))
+		return data
+	}
+
+	responses := []*rpcResponse{}
+	for _, request := range batch {
+		if response := n.callRPC(caller, request); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+
+	data, _ := json.Marshal(responses)
+	return data
+}
+
+// callRPC handles a single request and returns its response, or nil if it
+// is a notification.
+func (n *PublicServer) callRPC(caller *rpcCaller, payload []byte) *rpcResponse {
+	var request rpcRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) || len(payload) == 0 {
+			return rpcErrorResponse(nil, newRPCError(rpcParseError, "parse error"))
+		}
+
+		return rpcErrorResponse(nil, newRPCError(rpcInvalidRequest, "invalid request: %s", err))
+	}
+
+	if request.JSONRPC != "2.0" || request.Method == "" {
+		return rpcErrorResponse(request.ID, newRPCError(rpcInvalidRequest, "invalid request"))
+	}
+
+	var response *rpcResponse
+	method, exists := n.rpcMethods()[request.Method]
+	if !exists {
+		response = rpcErrorResponse(request.ID, newRPCError(rpcMethodNotFound, "method %q not found", request.Method))
+	} else {
+		result, err := method(caller, request.Params)
+		response = rpcResultResponse(request.ID, result, err)
+	}
+
+	if request.ID == nil {
+		return nil
+	}
+
+	return response
+}
+
+func rpcResultResponse(id json.RawMessage, result interface{}, err error) *rpcResponse {
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = newRPCError(rpcInternalError, err.Error())
+		}
+
+		return rpcErrorResponse(id, rpcErr)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return rpcErrorResponse(id, newRPCError(rpcInternalError, err.Error()))
+	}
+
+	return &rpcResponse{JSONRPC: "2.0", Result: data, ID: rpcID(id)}
+}
+
+func rpcErrorResponse(id json.RawMessage, err *rpcError) *rpcResponse {
+	return &rpcResponse{JSONRPC: "2.0", Error: err, ID: rpcID(id)}
+}
+
+// rpcID returns the id to answer with, which is null when the id of the
+// request could not be read.
+func rpcID(id json.RawMessage) json.RawMessage {
+	if id == nil {
+		return json.RawMessage("null")
+	}
+
+	return id
+}
+
+// parseRPCParams decodes the positional params into the provided values.
+// Only the first required values must be provided, the other ones keep
+// their defaults when missing.
+func parseRPCParams(params []json.RawMessage, required int, values ...interface{}) error {
+	if len(params) < required || len(params) > len(values) {
+		return newRPCError(rpcInvalidParams, "expected between %d and %d params, got %d", required, len(values), len(params))
+	}
+
+	for i, param := range params {
+		if err := json.Unmarshal(param, values[i]); err != nil {
+			return newRPCError(rpcInvalidParams, "invalid param %d: %s", i, err)
+		}
+	}
+
+	return nil
+}
+
+// authorizeRPCWrite checks that the caller can modify the chain. The size of
+// the payload is already limited when reading the request.
+func (n *PublicServer) authorizeRPCWrite(caller *rpcCaller) error {
+	if caller.authErr != nil {
+		log.Warn().Err(caller.authErr).Str("ip", caller.ip).Msg("unauthorized rpc request")
+		return newRPCError(rpcUnauthorized, "unauthorized")
+	}
+
+	if allowed, _ := n.writeGuard.allow(caller.client); !allowed {
+		log.Warn().Str("client", caller.client).Str("ip", caller.ip).Msg("rate limit reached")
+		return newRPCError(rpcRateLimited, "rate limit reached")
+	}
+
+	return nil
+}
+
+func (n *PublicServer) rpcGetBlockCount(_ *rpcCaller, params []json.RawMessage) (interface{}, error) {
+	if err := parseRPCParams(params, 0); err != nil {
+		return nil, err
+	}
+
+	return n.blockchain.GetLastBlock().Header.Index, nil
+}
+
+func (n *PublicServer) rpcGetBestBlockHash(_ *rpcCaller, params []json.RawMessage) (interface{}, error) {
+	if err := parseRPCParams(params, 0); err != nil {
+		return nil, err
+	}
+
+	return hex.EncodeToString(n.blockchain.GetLastBlock().Hash), nil
+}
+
+func (n *PublicServer) rpcGetBlockHash(_ *rpcCaller, params []json.RawMessage) (interface{}, error) {
+	var height int
+	if err := parseRPCParams(params, 1, &height); err != nil {
+		return nil, err
+	}
+
+	b, err := n.blockchain.GetBlockByHeight(height)
+	if err != nil {
+		return nil, newRPCError(rpcNotFound, "block height out of range")
+	}
+
+	return hex.EncodeToString(b.Hash), nil
+}
+
+// rpcGetBlock returns the block with the provided hash: with verbosity 0 it
+// is encoded as hex protobuf, otherwise as JSON.
+func (n *PublicServer) rpcGetBlock(_ *rpcCaller, params []json.RawMessage) (interface{}, error) {
+	var hexHash string
+	verbosity := 1
+	if err := parseRPCParams(params, 1, &hexHash, &verbosity); err != nil {
+		return nil, err
+	}
+
+	hash, err := hex.DecodeString(hexHash)
+	if err != nil || len(hash) == 0 {
+		return nil, newRPCError(rpcInvalidParams, "hash must be a valid hex string")
+	}
+
+	b, err := n.blockchain.GetBlockByHash(hash)
+	if err != nil {
+		return nil, newRPCError(rpcNotFound, "block not found")
+	}
+
+	if verbosity == 0 {
+		data, err := proto.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+
+		return hex.EncodeToString(data), nil
+	}
+
+	return b, nil
+}
+
+func (n *PublicServer) rpcGetDifficulty(_ *rpcCaller, params []json.RawMessage) (interface{}, error) {
+	if err := parseRPCParams(params, 0); err != nil {
+		return nil, err
+	}
+
+	return n.blockchain.GetStats().CurrentDifficulty, nil
+}
+
+// rpcGetBalance returns the balance of the provided address, computed from
+// the genesis allocations and the signed transfers on the chain.
+func (n *PublicServer) rpcGetBalance(_ *rpcCaller, params []json.RawMessage) (interface{}, error) {
+	var address string
+	if err := parseRPCParams(params, 1, &address); err != nil {
+		return nil, err
+	}
+
+	balance, err := wallet.NewBalance(address)
+	if err != nil {
+		return nil, newRPCError(rpcInvalidParams, err.Error())
+	}
+
+	for _, proven := range n.blockchain.FindEntries(func(entry string, height int64) bool {
+		return wallet.Involves(address, entry, height == 0)
+	}) {
+		balance.Apply(proven.Entry, proven.Proof.Header.Index == 0)
+	}
+
+	return balance, nil
+}
+
+// rpcSendRawTransaction mines a block containing the provided entry, the
+// same way POST /blocks does, and returns the hex hash of the entry.
+func (n *PublicServer) rpcSendRawTransaction(caller *rpcCaller, params []json.RawMessage) (interface{}, error) {
+	var entry string
+	if err := parseRPCParams(params, 1, &entry); err != nil {
+		return nil, err
+	}
+	if entry == "" {
+		return nil, newRPCError(rpcInvalidParams, "entry cannot be empty")
+	}
+
+	if n.writeGuard.settings.DisableMining {
+		return nil, newRPCError(rpcMiningDisabled, "this node does not mine blocks")
+	}
+
+	if err := n.authorizeRPCWrite(caller); err != nil {
+		return nil, err
+	}
+
+	ctx, canc := context.WithTimeout(caller.ctx, submissionTimeout)
+	defer canc()
+
+	entryHash := block.HashEntry(entry)
+	newBlock, err := n.miner.Mine(ctx, entry)
+	if err != nil {
+		if errors.Is(err, mining.ErrBlockRejected) {
+			return nil, newRPCError(rpcBlockRejected, err.Error())
+		}
+
+		return nil, newRPCError(rpcMinerBusy, err.Error())
+	}
+
+	n.logSubmission(caller, entryHash, newBlock)
+
+	return hex.EncodeToString(entryHash), nil
+}
+
+// rpcSubmitBlock pushes a block that was mined elsewhere, encoded as hex
+// protobuf, and forwards it to peers.
+func (n *PublicServer) rpcSubmitBlock(caller *rpcCaller, params []json.RawMessage) (interface{}, error) {
+	var hexBlock string
+	if err := parseRPCParams(params, 1, &hexBlock); err != nil {
+		return nil, err
+	}
+
+	data, err := hex.DecodeString(hexBlock)
+	if err != nil || len(data) == 0 {
+		return nil, newRPCError(rpcInvalidParams, "block must be a valid hex string")
+	}
+
+	if err := n.authorizeRPCWrite(caller); err != nil {
+		return nil, err
+	}
+
+	var submitted pb.Block
+	if err := proto.Unmarshal(data, &submitted); err != nil {
+		return nil, newRPCError(rpcInvalidParams, "could not decode block: %s", err)
+	}
+
+	if err := n.blockchain.PushBlock(&submitted); err != nil {
+		return nil, newRPCError(rpcBlockRejected, err.Error())
+	}
+
+	n.events.Publish(events.EventBlockSubmitted, &submitted)
+	n.logSubmission(caller, nil, &submitted)
+
+	return nil, nil
+}
+
+func (n *PublicServer) logSubmission(caller *rpcCaller, entryHash []byte, b *pb.Block) {
+	l := log.Info().
+		Str("client", caller.client).
+		Str("ip", caller.ip)
+	if entryHash != nil {
+		l = l.Str("entry-hash", hex.EncodeToString(entryHash))
+	}
+
+	l.Int64("index", b.Header.Index).
+		Str("block-hash", hex.EncodeToString(b.Hash)).
+		Msg("block submitted")
+}
@@ -0,0 +1,42 @@
+package servers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+)
+
+// deadlineMiner fails mining unless the context has a deadline.
+type deadlineMiner struct {
+	testMiner
+}
+
+func (m *deadlineMiner) Mine(ctx context.Context, entry string) (*pb.Block, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, context.Canceled
+	}
+
+	return m.testMiner.Mine(ctx, entry)
+}
+
+func TestSubmissionTimeout(t *testing.T) {
+	bus := events.NewBus()
+	t.Cleanup(bus.Close)
+	f := block.NewBlockFactory(block.WithEventBus(bus))
+	bc := f.NewBlockChain()
+	miner := &deadlineMiner{testMiner{factory: f, blockchain: bc}}
+	url := serve(t, NewPublicServer(bc, bus, miner, WriteSettings{}).FiberApp)
+
+	if status, body := do(t, http.MethodPost, url+"/blocks", "entry", nil); status != http.StatusOK {
+		t.Fatalf("status = %d (%s), want %d", status, body, http.StatusOK)
+	}
+
+	request := `{"jsonrpc":"2.0","method":"sendrawtransaction","params":["other"],"id":1}`
+	if _, body := do(t, http.MethodPost, url+"/rpc", request, nil); bc.Length() != 3 {
+		t.Fatalf("entry sent with rpc was not mined: %s", body)
+	}
+}
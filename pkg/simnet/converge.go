@@ -0,0 +1,76 @@
+package simnet
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var (
+	// ErrNotConverged is returned when the nodes did not agree on the last
+	// block of the chain in time.
+	ErrNotConverged = errors.New("nodes did not converge")
+)
+
+// Tips returns the hash of the last block of each of the provided nodes, or
+// of all nodes if none is provided, by node name.
+func (n *Network) Tips(nodes ...*Node) map[string]string {
+	if len(nodes) == 0 {
+		nodes = n.nodes
+	}
+
+	tips := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		tips[node.name] = hex.EncodeToString(node.blockchain.GetLastBlock().Hash)
+	}
+
+	return tips
+}
+
+// Converged returns true if all the provided nodes, or all nodes if none is
+// provided, have the same last block.
+func (n *Network) Converged(nodes ...*Node) bool {
+	var tip string
+	for _, hash := range n.Tips(nodes...) {
+		if tip != "" && hash != tip {
+			return false
+		}
+		tip = hash
+	}
+
+	return true
+}
+
+// WaitForConvergence waits until all the provided nodes, or all nodes if none
+// is provided, have the same last block. It returns ErrNotConverged with the
+// last block of each node if the context is cancelled before.
+func (n *Network) WaitForConvergence(ctx context.Context, nodes ...*Node) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for !n.Converged(nodes...) {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v", ErrNotConverged, n.Tips(nodes...))
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// RequireConvergence fails the test if all the provided nodes, or all nodes
+// if none is provided, do not have the same last block within the timeout.
+func (n *Network) RequireConvergence(t testing.TB, timeout time.Duration, nodes ...*Node) {
+	t.Helper()
+
+	ctx, canc := context.WithTimeout(context.Background(), timeout)
+	defer canc()
+
+	if err := n.WaitForConvergence(ctx, nodes...); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,220 @@
+package simnet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrLinkCut is returned when a node dials a peer through a link that
+	// is cut.
+	ErrLinkCut = errors.New("link is cut")
+)
+
+type linkKey struct {
+	from string
+	to   string
+}
+
+// Link is the connection that a node uses to reach one of its peers. It
+// affects the requests sent by the node to the peer and their responses,
+// including the blocks that the peer sends to the node, but not the
+// requests sent by the peer to the node, which go through the opposite
+// link.
+type Link struct {
+	network *Network
+	from    *Node
+	to      *Node
+	// uid is the UID of the last announcement of the peer.
+	uid string
+
+	latency  time.Duration
+	dropRate float64
+	cut      bool
+	conns    map[net.Conn]bool
+	lock     sync.Mutex
+}
+
+func newLink(network *Network, from, to *Node) *Link {
+	return &Link{
+		network: network,
+		from:    from,
+		to:      to,
+		conns:   map[net.Conn]bool{},
+		lock:    sync.Mutex{},
+	}
+}
+
+// SetLatency delays each message sent through the link by the provided
+// duration.
+func (l *Link) SetLatency(latency time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.latency = latency
+}
+
+// SetDropRate drops each message sent through the link with the provided
+// probability, between 0 and 1. Dropped requests fail, while dropped blocks
+// are never received by the node.
+func (l *Link) SetDropRate(rate float64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.dropRate = rate
+}
+
+// Cut closes all connections through the link and makes new ones fail, until
+// the link is restored.
+func (l *Link) Cut() {
+	l.lock.Lock()
+	l.cut = true
+	conns := l.conns
+	l.conns = map[net.Conn]bool{}
+	l.lock.Unlock()
+
+	for conn := range conns {
+		conn.Close()
+	}
+}
+
+// Restore allows connections through the link again and returns true if it
+// was cut. The node does not sync with the peer again until the peer is
+// announced to it.
+func (l *Link) Restore() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	wasCut := l.cut
+	l.cut = false
+	return wasCut
+}
+
+func (l *Link) state() (time.Duration, float64, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.latency, l.dropRate, l.cut
+}
+
+// peer returns the peer that the node reaches through the link, as
+// reported by the discovery source.
+func (l *Link) peer() *peers.Peer {
+	return &peers.Peer{
+		Name: l.to.name,
+		IP:   l.to.ip,
+		UID:  l.uid,
+		DialOptions: []grpc.DialOption{
+			grpc.WithContextDialer(l.dial),
+			grpc.FailOnNonTempDialError(true),
+			grpc.WithUnaryInterceptor(l.interceptUnary),
+			grpc.WithStreamInterceptor(l.interceptStream),
+		},
+	}
+}
+
+func (l *Link) dial(ctx context.Context, _ string) (net.Conn, error) {
+	if _, _, cut := l.state(); cut {
+		return nil, ErrLinkCut
+	}
+
+	conn, err := l.to.listener.Dial()
+	if err != nil {
+		return nil, err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.cut {
+		// the link was cut while dialing.
+		conn.Close()
+		return nil, ErrLinkCut
+	}
+	l.conns[conn] = true
+
+	return conn, nil
+}
+
+// transmit simulates a message going through the link, by waiting for the
+// latency and failing if the message is dropped.
+func (l *Link) transmit(ctx context.Context) error {
+	latency, dropRate, cut := l.state()
+	if cut {
+		return status.Error(codes.Unavailable, ErrLinkCut.Error())
+	}
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-timer.C:
+		}
+	}
+
+	if l.network.dropped(dropRate) {
+		return status.Error(codes.Unavailable, "message was dropped")
+	}
+
+	return nil
+}
+
+func (l *Link) interceptUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if err := l.transmit(ctx); err != nil {
+		return err
+	}
+
+	if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+		return err
+	}
+
+	return l.transmit(ctx)
+}
+
+func (l *Link) interceptStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if err := l.transmit(ctx); err != nil {
+		return nil, err
+	}
+
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &linkStream{ClientStream: stream, link: l}, nil
+}
+
+// linkStream delays or drops the messages received from a stream, e.g. the
+// blocks sent by the peer.
+type linkStream struct {
+	grpc.ClientStream
+	link *Link
+}
+
+// RecvMsg receives the next message of the stream that is not dropped.
+func (s *linkStream) RecvMsg(m interface{}) error {
+	for {
+		if err := s.ClientStream.RecvMsg(m); err != nil {
+			return err
+		}
+
+		err := s.link.transmit(s.Context())
+		if status.Code(err) != codes.Unavailable {
+			return err
+		}
+		if _, _, cut := s.link.state(); cut {
+			return err
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Package simnet runs several full nodes in the same process, connected
+// through in-memory gRPC connections, so that the behavior of the network can
+// be tested with go test: peers are discovered through a fake discovery
+// source and the links between nodes can be slowed down, made lossy or cut.
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
+)
+
+const (
+	// meshTimeout is how long New waits for all nodes to subscribe to
+	// the blocks of the others.
+	meshTimeout time.Duration = 30 * time.Second
+	// pollInterval is how often the state of the nodes is checked while
+	// waiting for them.
+	pollInterval time.Duration = 20 * time.Millisecond
+)
+
+// NetworkOptions defines options for the simulated network.
+type NetworkOptions func(*Network)
+
+// WithFactoryOptions instructs the network to create the block factory of
+// each node with the options returned by fn, which is called with the index
+// of the node. All nodes must use the same genesis and consensus settings,
+// but they may have different signers.
+func WithFactoryOptions(fn func(index int) []block.FactoryOptions) NetworkOptions {
+	return func(n *Network) {
+		n.factoryOptions = fn
+	}
+}
+
+// WithSeed instructs the network to drop messages according to the provided
+// seed, so that runs with lossy links can be reproduced.
+func WithSeed(seed int64) NetworkOptions {
+	return func(n *Network) {
+		n.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// Network is a set of full nodes running in the same process, where each one
+// is connected to all the others.
+type Network struct {
+	nodes          []*Node
+	links          map[linkKey]*Link
+	factoryOptions func(index int) []block.FactoryOptions
+	// incarnation is increased every time a peer is announced, so that
+	// each announcement has a different UID.
+	incarnation int64
+	rand        *rand.Rand
+	closed      bool
+	lock        sync.Mutex
+}
+
+// New starts a network of the provided number of nodes, named node-0,
+// node-1 and so on, and announces all nodes to each other. It returns once
+// every node is subscribed to the blocks of all the others.
+func New(size int, options ...NetworkOptions) (*Network, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid number of nodes: %d", size)
+	}
+
+	n := &Network{
+		links:          map[linkKey]*Link{},
+		factoryOptions: func(int) []block.FactoryOptions { return nil },
+		rand:           rand.New(rand.NewSource(time.Now().UnixNano())),
+		lock:           sync.Mutex{},
+	}
+	for _, o := range options {
+		o(n)
+	}
+
+	for i := 0; i < size; i++ {
+		n.nodes = append(n.nodes, newNode(i, n.factoryOptions(i)))
+	}
+	for _, from := range n.nodes {
+		for _, to := range n.nodes {
+			if from != to {
+				n.links[linkKey{from.name, to.name}] = newLink(n, from, to)
+			}
+		}
+	}
+
+	for _, node := range n.nodes {
+		node.start()
+	}
+	for key := range n.links {
+		n.Announce(key.from, key.to)
+	}
+
+	ctx, canc := context.WithTimeout(context.Background(), meshTimeout)
+	defer canc()
+	if err := n.waitForMesh(ctx); err != nil {
+		n.Close()
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// Nodes returns all nodes of the network, sorted by index.
+func (n *Network) Nodes() []*Node {
+	return append([]*Node{}, n.nodes...)
+}
+
+// Node returns the node with the provided name, or nil if it does not
+// exist.
+func (n *Network) Node(name string) *Node {
+	for _, node := range n.nodes {
+		if node.name == name {
+			return node
+		}
+	}
+
+	return nil
+}
+
+// Link returns the link that the node named from uses to reach the node
+// named to, or nil if any of them does not exist.
+func (n *Network) Link(from, to string) *Link {
+	return n.links[linkKey{from, to}]
+}
+
+// Announce makes the discovery source of the node named from report the
+// node named to, as it happens when a pod is scheduled again: the node
+// unsubscribes from the peer, if it knows it, syncs its chain with it and
+// subscribes to its blocks again.
+func (n *Network) Announce(from, to string) {
+	n.emit(from, to, peers.EventNewPeer)
+}
+
+// Forget makes the discovery source of the node named from report the node
+// named to as dead, so that the node unsubscribes from its blocks.
+func (n *Network) Forget(from, to string) {
+	n.emit(from, to, peers.EventDeadPeer)
+}
+
+func (n *Network) emit(from, to string, eventType peers.PeerEventType) {
+	link := n.Link(from, to)
+	if link == nil {
+		return
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.closed {
+		return
+	}
+
+	if eventType == peers.EventNewPeer {
+		n.incarnation++
+		link.uid = fmt.Sprintf("%s-%d", to, n.incarnation)
+	}
+
+	link.from.peerEvents <- &peers.PeerEvent{
+		EventType: eventType,
+		Peer:      link.peer(),
+	}
+}
+
+// Partition cuts all links between nodes of different groups, by name.
+// Nodes that are not in any group are put together in another group.
+func (n *Network) Partition(groups ...[]string) {
+	group := map[string]int{}
+	for i, names := range groups {
+		for _, name := range names {
+			group[name] = i + 1
+		}
+	}
+
+	for key, link := range n.links {
+		if group[key.from] != group[key.to] {
+			link.Cut()
+		}
+	}
+}
+
+// Heal restores all links that were cut and announces the nodes at their
+// ends to each other again, so that they sync their chains. The latency and
+// the drop rate of the links are not changed.
+func (n *Network) Heal() {
+	for key, link := range n.links {
+		if link.Restore() {
+			n.Announce(key.from, key.to)
+		}
+	}
+}
+
+// Close stops all nodes of the network.
+func (n *Network) Close() {
+	n.lock.Lock()
+	if n.closed {
+		n.lock.Unlock()
+		return
+	}
+	n.closed = true
+	n.lock.Unlock()
+
+	for _, node := range n.nodes {
+		node.stop()
+	}
+}
+
+// waitForMesh waits until each node is subscribed to the blocks of all the
+// others and the others are serving it, so that no mined block is missed.
+func (n *Network) waitForMesh(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		meshed := true
+		for _, node := range n.nodes {
+			if node.subscriptions() < len(n.nodes)-1 || len(node.commServer.GetSubscriberStats()) < len(n.nodes)-1 {
+				meshed = false
+				break
+			}
+		}
+		if meshed {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("nodes did not connect to each other: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// dropped returns true if a message must be dropped according to the
+// provided rate.
+func (n *Network) dropped(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.rand.Float64() < rate
+}
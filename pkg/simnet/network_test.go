@@ -0,0 +1,118 @@
+package simnet_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SunSince90/go-naivecoin/pkg/simnet"
+)
+
+const convergenceTimeout time.Duration = 10 * time.Second
+
+func newNetwork(t *testing.T, size int, options ...simnet.NetworkOptions) *simnet.Network {
+	t.Helper()
+
+	network, err := simnet.New(size, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(network.Close)
+
+	return network
+}
+
+func mine(t *testing.T, node *simnet.Node, entry string) {
+	t.Helper()
+
+	if _, err := node.Mine(context.Background(), entry); err != nil {
+		t.Fatalf("%s could not mine %s: %s", node.Name(), entry, err)
+	}
+}
+
+func TestConvergence(t *testing.T) {
+	network := newNetwork(t, 4)
+	nodes := network.Nodes()
+
+	// each block is mined by a different node, on top of the ones mined by
+	// the others.
+	for i := 0; i < 8; i++ {
+		mine(t, nodes[i%len(nodes)], fmt.Sprintf("entry-%d", i))
+		network.RequireConvergence(t, convergenceTimeout)
+	}
+
+	chain := nodes[0].BlockChain().GetChain()
+	if len(chain) != 9 {
+		t.Fatalf("chain has %d blocks, want 9", len(chain))
+	}
+	for _, node := range nodes[1:] {
+		other := node.BlockChain().GetChain()
+		for i := range chain {
+			if !bytes.Equal(chain[i].Hash, other[i].Hash) {
+				t.Fatalf("%s has a different block at height %d", node.Name(), i)
+			}
+		}
+	}
+}
+
+func TestPartitionAndHeal(t *testing.T) {
+	network := newNetwork(t, 4, simnet.WithSeed(1))
+	majority := []*simnet.Node{network.Node("node-0"), network.Node("node-1")}
+	minority := []*simnet.Node{network.Node("node-2"), network.Node("node-3")}
+
+	network.Partition([]string{"node-0", "node-1"})
+	mine(t, majority[0], "majority-0")
+	network.RequireConvergence(t, convergenceTimeout, majority...)
+	mine(t, majority[1], "majority-1")
+	mine(t, minority[0], "minority-0")
+
+	// each side of the partition agrees on its own chain, but the two
+	// chains are different.
+	network.RequireConvergence(t, convergenceTimeout, majority...)
+	network.RequireConvergence(t, convergenceTimeout, minority...)
+	time.Sleep(200 * time.Millisecond)
+	if network.Converged() {
+		t.Fatal("nodes converged through a partition")
+	}
+
+	network.Heal()
+	network.RequireConvergence(t, convergenceTimeout)
+
+	// the longer chain of the majority wins.
+	last := minority[1].BlockChain().GetLastBlock()
+	if last.Header.Index != 2 || !bytes.Equal(last.Hash, majority[0].BlockChain().GetLastBlock().Hash) {
+		t.Fatalf("nodes converged on block %d instead of the last one of the majority", last.Header.Index)
+	}
+
+	// the network works as a whole again.
+	mine(t, minority[1], "healed")
+	network.RequireConvergence(t, convergenceTimeout)
+}
+
+func TestLossyLinks(t *testing.T) {
+	network := newNetwork(t, 3, simnet.WithSeed(2))
+	origin, slow, lossy := network.Node("node-0"), network.Node("node-1"), network.Node("node-2")
+
+	latency := 200 * time.Millisecond
+	network.Link(slow.Name(), origin.Name()).SetLatency(latency)
+	network.Link(lossy.Name(), origin.Name()).SetDropRate(1)
+
+	start := time.Now()
+	mine(t, origin, "entry")
+	network.RequireConvergence(t, convergenceTimeout, origin, slow)
+	if elapsed := time.Since(start); elapsed < latency {
+		t.Fatalf("block was received after %s, before the latency of the link", elapsed)
+	}
+
+	time.Sleep(2 * latency)
+	if network.Converged(origin, lossy) {
+		t.Fatal("block was received through a link that drops everything")
+	}
+
+	// the node syncs once it hears about the peer again.
+	network.Link(lossy.Name(), origin.Name()).SetDropRate(0)
+	network.Announce(lossy.Name(), origin.Name())
+	network.RequireConvergence(t, convergenceTimeout)
+}
@@ -0,0 +1,144 @@
+package simnet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+	"github.com/SunSince90/go-naivecoin/pkg/events"
+	"github.com/SunSince90/go-naivecoin/pkg/mining"
+	"github.com/SunSince90/go-naivecoin/pkg/pb"
+	"github.com/SunSince90/go-naivecoin/pkg/peers"
+	"github.com/SunSince90/go-naivecoin/pkg/servers"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	// listenerBufferSize is the size of the in-memory buffer of each
+	// connection to a node.
+	listenerBufferSize int = 1 << 20
+)
+
+// Node is a full node of the simulated network. It mines the entries
+// submitted to it and serves peer communications like a real node, but
+// through an in-memory listener.
+type Node struct {
+	name       string
+	ip         string
+	factory    *block.BlockFactory
+	blockchain *block.BlockChain
+	bus        *events.Bus
+	peers      *peers.PeersManager
+	worker     *mining.Worker
+	commServer *servers.PeerCommunicationServer
+	grpcServer *grpc.Server
+	listener   *bufconn.Listener
+	peerEvents chan *peers.PeerEvent
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newNode(index int, options []block.FactoryOptions) *Node {
+	bus := events.NewBus()
+	bf := block.NewBlockFactory(append(options, block.WithEventBus(bus))...)
+	blockchain := bf.NewBlockChain()
+	worker := mining.NewWorker(blockchain, bf, bus)
+
+	return &Node{
+		name:       fmt.Sprintf("node-%d", index),
+		ip:         fmt.Sprintf("10.0.%d.%d", index/256, index%256),
+		factory:    bf,
+		blockchain: blockchain,
+		bus:        bus,
+		peers:      peers.NewPeersManager(blockchain),
+		worker:     worker,
+		commServer: servers.NewPeerCommunicationServer(blockchain, worker, nil),
+		grpcServer: grpc.NewServer(),
+		listener:   bufconn.Listen(listenerBufferSize),
+		peerEvents: make(chan *peers.PeerEvent, 100),
+	}
+}
+
+// Name returns the name of the node.
+func (n *Node) Name() string {
+	return n.name
+}
+
+// BlockFactory returns the block factory of the node.
+func (n *Node) BlockFactory() *block.BlockFactory {
+	return n.factory
+}
+
+// BlockChain returns the chain of the node.
+func (n *Node) BlockChain() *block.BlockChain {
+	return n.blockchain
+}
+
+// PeersManager returns the peers manager of the node.
+func (n *Node) PeersManager() *peers.PeersManager {
+	return n.peers
+}
+
+// Mine mines a block containing the provided entry, pushes it to the chain of
+// the node and sends it to the peers subscribed to the node.
+func (n *Node) Mine(ctx context.Context, entry string) (*pb.Block, error) {
+	return n.worker.Mine(ctx, entry)
+}
+
+func (n *Node) start() {
+	ctx, canc := context.WithCancel(context.Background())
+	n.cancel = canc
+	l := log.With().Str("node", n.name).Logger()
+
+	pb.RegisterPeerCommunicationServer(n.grpcServer, n.commServer)
+
+	n.wg.Add(4)
+	go func() {
+		defer n.wg.Done()
+		n.peers.ListenPeerEvents(n.peerEvents)
+	}()
+
+	go func() {
+		defer n.wg.Done()
+		if err := n.grpcServer.Serve(n.listener); err != nil {
+			l.Err(err).Msg("could not serve communication server")
+		}
+	}()
+
+	go func() {
+		defer n.wg.Done()
+		n.worker.Run(ctx)
+	}()
+
+	go func() {
+		defer n.wg.Done()
+		n.commServer.ServeSubscriptions(n.bus)
+	}()
+}
+
+func (n *Node) stop() {
+	n.cancel()
+	// closing the bus ends the streams of the blocks sent to the peers,
+	// which would otherwise keep the server from stopping.
+	close(n.peerEvents)
+	n.bus.Close()
+	n.grpcServer.Stop()
+	n.wg.Wait()
+}
+
+// subscriptions returns the number of peers whose blocks the node is
+// receiving.
+func (n *Node) subscriptions() int {
+	subscribed := 0
+	for _, info := range n.peers.GetPeers() {
+		if info.State == peers.PeerStateSubscribed {
+			subscribed++
+		}
+	}
+
+	return subscribed
+}
@@ -0,0 +1,104 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/SunSince90/go-naivecoin/pkg/block"
+)
+
+// Balance is the amount received and sent by an address according to the
+// entries of the chain: genesis allocations and signed transfers.
+//
+// The chain does not check transfers against balances, so an address may
+// have sent more than it received, in which case its balance is negative.
+type Balance struct {
+	Address  string   `json:"address"`
+	Received *big.Int `json:"received"`
+	Sent     *big.Int `json:"sent"`
+	Balance  *big.Int `json:"balance"`
+	// Entries is the number of entries that were counted.
+	Entries int `json:"entries"`
+
+	// counted contains the hex representation of the hashes of the counted
+	// entries, so that an entry included more than once is counted once.
+	counted map[string]bool
+}
+
+// NewBalance returns an empty balance for the provided address.
+func NewBalance(address string) (*Balance, error) {
+	if _, err := decodeAddress(address); err != nil {
+		return nil, err
+	}
+
+	return &Balance{
+		Address:  strings.ToLower(address),
+		Received: big.NewInt(0),
+		Sent:     big.NewInt(0),
+		Balance:  big.NewInt(0),
+		counted:  map[string]bool{},
+	}, nil
+}
+
+// Involves returns true if the entry moves an amount to or from the address.
+// Allocations are only recognized in the genesis block.
+func Involves(address, entry string, genesis bool) bool {
+	from, to, _, ok := parseEntry(entry, genesis)
+	address = strings.ToLower(address)
+
+	return ok && (from == address || to == address)
+}
+
+// Apply counts the entry in the balance if it moves an amount to or from the
+// address, and returns true if it did. Allocations are only recognized in
+// the genesis block.
+func (b *Balance) Apply(entry string, genesis bool) bool {
+	from, to, amount, ok := parseEntry(entry, genesis)
+	if !ok || (from != b.Address && to != b.Address) {
+		return false
+	}
+
+	key := hex.EncodeToString(block.HashEntry(entry))
+	if b.counted[key] {
+		return false
+	}
+	b.counted[key] = true
+	b.Entries++
+
+	value := big.NewInt(0).SetUint64(amount)
+	if to == b.Address {
+		b.Received.Add(b.Received, value)
+	}
+	if from == b.Address {
+		b.Sent.Add(b.Sent, value)
+	}
+	b.Balance.Sub(b.Received, b.Sent)
+
+	return true
+}
+
+// parseEntry returns the sender, the recipient and the amount moved by the
+// entry, or false if it does not move any. Transfers with an invalid
+// signature are ignored. Allocations have no sender.
+func parseEntry(entry string, genesis bool) (string, string, uint64, bool) {
+	if genesis {
+		var allocation block.Allocation
+		if err := json.Unmarshal([]byte(entry), &allocation); err != nil || allocation.Address == "" {
+			return "", "", 0, false
+		}
+
+		return "", strings.ToLower(allocation.Address), allocation.Amount, true
+	}
+
+	var transfer Transfer
+	if err := json.Unmarshal([]byte(entry), &transfer); err != nil || transfer.From == "" || transfer.To == "" {
+		return "", "", 0, false
+	}
+	if err := transfer.Verify(); err != nil {
+		return "", "", 0, false
+	}
+
+	return strings.ToLower(transfer.From), strings.ToLower(transfer.To), transfer.Amount, true
+}
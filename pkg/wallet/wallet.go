@@ -0,0 +1,216 @@
+// Package wallet manages the key pairs used to sign transfers.
+//
+// The chain does not know about accounts or balances: a transfer is just a
+// signed entry, so anyone reading the chain can verify who sent it. Balances
+// are computed from these entries when asked for.
+package wallet
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	walletFileExtension string = ".json"
+)
+
+// Wallet is a named key pair. Its address is the hex representation of the
+// public key.
+type Wallet struct {
+	Name       string             `json:"name"`
+	PublicKey  ed25519.PublicKey  `json:"publicKey"`
+	PrivateKey ed25519.PrivateKey `json:"privateKey"`
+}
+
+// NewWallet generates a new key pair with the provided name.
+func NewWallet(name string) (*Wallet, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid wallet name %q", name)
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate key pair: %w", err)
+	}
+
+	return &Wallet{
+		Name:       name,
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+	}, nil
+}
+
+// Address returns the address of the wallet.
+func (w *Wallet) Address() string {
+	return hex.EncodeToString(w.PublicKey)
+}
+
+// Save stores the wallet in the provided directory. It fails if a wallet
+// with the same name already exists.
+func (w *Wallet) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, w.Name+walletFileExtension), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("wallet %q already exists", w.Name)
+		}
+
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(data)
+	return err
+}
+
+// Load reads the wallet with the provided name from the directory.
+func Load(dir, name string) (*Wallet, error) {
+	return LoadFile(filepath.Join(dir, name+walletFileExtension))
+}
+
+// LoadFile reads the wallet stored in the provided file, e.g. one mounted
+// from a secret.
+func LoadFile(path string) (*Wallet, error) {
+	name := strings.TrimSuffix(filepath.Base(path), walletFileExtension)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("wallet %q does not exist", name)
+		}
+
+		return nil, err
+	}
+
+	var w Wallet
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("could not parse wallet %q: %w", name, err)
+	}
+	if len(w.PrivateKey) != ed25519.PrivateKeySize || len(w.PublicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("wallet %q has invalid keys", name)
+	}
+
+	return &w, nil
+}
+
+// List returns all the wallets stored in the directory, sorted by name.
+func List(dir string) ([]*Wallet, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Wallet{}, nil
+		}
+
+		return nil, err
+	}
+
+	wallets := []*Wallet{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != walletFileExtension {
+			continue
+		}
+
+		w, err := Load(dir, strings.TrimSuffix(file.Name(), walletFileExtension))
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, w)
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		return wallets[i].Name < wallets[j].Name
+	})
+
+	return wallets, nil
+}
+
+// Transfer is a signed statement that an amount was sent from an address to
+// another one.
+type Transfer struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Amount    uint64 `json:"amount"`
+	Timestamp int64  `json:"timestamp"`
+	Signature []byte `json:"signature"`
+}
+
+// NewTransfer creates a transfer from the wallet to the provided address and
+// signs it.
+func (w *Wallet) NewTransfer(to string, amount uint64) (*Transfer, error) {
+	if _, err := decodeAddress(to); err != nil {
+		return nil, err
+	}
+	if amount == 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+
+	t := &Transfer{
+		From:      w.Address(),
+		To:        to,
+		Amount:    amount,
+		Timestamp: time.Now().Unix(),
+	}
+	t.Signature = ed25519.Sign(w.PrivateKey, t.signedData())
+
+	return t, nil
+}
+
+// Verify checks that the transfer was signed by the sender.
+func (t *Transfer) Verify() error {
+	from, err := decodeAddress(t.From)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(from, t.signedData(), t.Signature) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	return nil
+}
+
+// Entry returns the representation of the transfer that is stored on the
+// chain.
+func (t *Transfer) Entry() (string, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func (t *Transfer) signedData() []byte {
+	return []byte(fmt.Sprintf("%s:%s:%d:%d", t.From, t.To, t.Amount, t.Timestamp))
+}
+
+// ValidateAddress returns an error if the address is not the hex
+// representation of a public key.
+func ValidateAddress(address string) error {
+	_, err := decodeAddress(address)
+	return err
+}
+
+func decodeAddress(address string) (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(address)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid address %q", address)
+	}
+
+	return key, nil
+}